@@ -0,0 +1,190 @@
+// Package authz decides whether a caller may perform an action on a specific resource.
+//
+// Route permissions (middleware.RequirePermission) decide which kinds of operation a role
+// may attempt at all. The policies here add the relationship between the caller and the
+// resource: whether they own it, advise its owner or stand in for its advisor, share its
+// department or are an admin.
+package authz
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// RoleAdmin is the role whose members may act on every resource
+const RoleAdmin = "Admin"
+
+type Action string
+
+const (
+	ActionList    Action = "list"
+	ActionRead    Action = "read"
+	ActionCreate  Action = "create"
+	ActionUpdate  Action = "update"
+	ActionDelete  Action = "delete"
+	ActionSubmit  Action = "submit"
+	ActionVerify  Action = "verify" // verify or reject
+	ActionAttach  Action = "attach files to"
+	ActionComment Action = "comment on"
+	ActionManage  Action = "manage"
+)
+
+type ResourceKind string
+
+const (
+	KindAchievement ResourceKind = "achievement"
+	KindStudent     ResourceKind = "student"
+	KindFile        ResourceKind = "file"
+)
+
+// Subject is the caller an authorization decision is made for
+type Subject struct {
+	UserID     uuid.UUID
+	RoleName   string
+	StudentID  uuid.UUID // student profile of the caller, uuid.Nil if none
+	LecturerID uuid.UUID // lecturer profile of the caller, uuid.Nil if none
+	Department string    // department of the caller's lecturer profile
+	Service    bool      // an API key acting for an integration rather than a person
+
+	Delegations []Delegation // delegations in force that the caller acts on
+}
+
+// Delegation lets a lecturer act as the advisor of another lecturer's advisees
+type Delegation struct {
+	AdvisorID uuid.UUID // lecturer profile of the advisor who delegated
+	OwnerID   uuid.UUID // the only advisee covered, uuid.Nil for all of them
+}
+
+// Resource is the object of an authorization decision. Achievements, files and students
+// all belong to a student, whose advisor and study program they inherit.
+type Resource struct {
+	Kind       ResourceKind
+	OwnerID    uuid.UUID  // student profile owning the resource, uuid.Nil if unowned
+	AdvisorID  *uuid.UUID // lecturer profile advising the owner
+	Department string     // study program of the owner
+	Stage      *Stage     // approval stage a submitted achievement waits in, nil for the advisor alone
+}
+
+// Stage names who decides the approval stage an achievement waits in: the owner's
+// advisor, the members of a role or a single user
+type Stage struct {
+	Advisor bool
+	Role    string
+	UserID  uuid.UUID
+}
+
+// Rule grants access when the subject stands in a given relation to the resource
+type Rule func(subject Subject, resource Resource) bool
+
+// Admin matches members of the Admin role
+func Admin(subject Subject, _ Resource) bool {
+	return !subject.Service && subject.RoleName == RoleAdmin
+}
+
+// Integration matches API keys. They act for a system rather than a person, so ownership
+// rules cannot apply; their scopes are already checked by the route permissions.
+func Integration(subject Subject, _ Resource) bool {
+	return subject.Service
+}
+
+// Student matches any caller with a student profile
+func Student(subject Subject, _ Resource) bool {
+	return subject.StudentID != uuid.Nil
+}
+
+// Owner matches the student the resource belongs to
+func Owner(subject Subject, resource Resource) bool {
+	return subject.StudentID != uuid.Nil && subject.StudentID == resource.OwnerID
+}
+
+// AdvisorOfOwner matches the academic advisor of the student the resource belongs to
+func AdvisorOfOwner(subject Subject, resource Resource) bool {
+	return subject.LecturerID != uuid.Nil && resource.AdvisorID != nil && *resource.AdvisorID == subject.LecturerID
+}
+
+// DelegateOfAdvisor matches a lecturer the advisor of the owner delegated to
+func DelegateOfAdvisor(subject Subject, resource Resource) bool {
+	if subject.LecturerID == uuid.Nil || resource.AdvisorID == nil || resource.OwnerID == uuid.Nil {
+		return false
+	}
+	for _, delegation := range subject.Delegations {
+		if delegation.AdvisorID == *resource.AdvisorID && (delegation.OwnerID == uuid.Nil || delegation.OwnerID == resource.OwnerID) {
+			return true
+		}
+	}
+	return false
+}
+
+// SameDepartment matches lecturers of the department the owner studies in
+func SameDepartment(subject Subject, resource Resource) bool {
+	if subject.LecturerID == uuid.Nil || resource.OwnerID == uuid.Nil {
+		return false
+	}
+	department := strings.TrimSpace(subject.Department)
+	return department != "" && strings.EqualFold(department, strings.TrimSpace(resource.Department))
+}
+
+// StageApprover matches who decides the approval stage the resource waits in. Without
+// an approval chain that is the advisor of the owner, or whoever they delegated to.
+func StageApprover(subject Subject, resource Resource) bool {
+	stage := resource.Stage
+	if stage == nil || stage.Advisor {
+		return AdvisorOfOwner(subject, resource) || DelegateOfAdvisor(subject, resource)
+	}
+	if subject.Service {
+		return false
+	}
+	if stage.UserID != uuid.Nil {
+		return subject.UserID == stage.UserID
+	}
+	return stage.Role != "" && subject.RoleName == stage.Role
+}
+
+// OnBehalfOf returns the advisor for whom subject decides the stage the resource waits
+// in, when they may decide it only because the advisor delegated to them
+func OnBehalfOf(subject Subject, resource Resource) (uuid.UUID, bool) {
+	if stage := resource.Stage; stage != nil && !stage.Advisor {
+		return uuid.Nil, false
+	}
+	if Admin(subject, resource) || AdvisorOfOwner(subject, resource) || !DelegateOfAdvisor(subject, resource) {
+		return uuid.Nil, false
+	}
+	return *resource.AdvisorID, true
+}
+
+// policies lists, per resource kind and action, the rules of which any one grants access.
+// Combinations that are not listed are denied.
+var policies = map[ResourceKind]map[Action][]Rule{
+	KindAchievement: {
+		ActionList:   {Admin, Integration},
+		ActionRead:   {Admin, Integration, Owner, AdvisorOfOwner, DelegateOfAdvisor, SameDepartment, StageApprover},
+		ActionCreate: {Student},
+		ActionUpdate: {Admin, Owner},
+		ActionDelete: {Admin, Owner},
+		ActionSubmit: {Admin, Owner},
+		ActionAttach: {Admin, Owner},
+		ActionVerify: {Admin, StageApprover},
+
+		// Discussion between the student and those verifying the achievement
+		ActionComment: {Admin, Owner, AdvisorOfOwner, DelegateOfAdvisor, StageApprover},
+	},
+	KindStudent: {
+		ActionRead:   {Admin, Integration, Owner, AdvisorOfOwner, DelegateOfAdvisor, SameDepartment},
+		ActionManage: {Admin},
+	},
+	KindFile: {
+		ActionCreate: {Admin, Student},
+		ActionDelete: {Admin, Owner},
+	},
+}
+
+// Can reports whether subject may perform action on resource
+func Can(subject Subject, action Action, resource Resource) bool {
+	for _, rule := range policies[resource.Kind][action] {
+		if rule(subject, resource) {
+			return true
+		}
+	}
+	return false
+}
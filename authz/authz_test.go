@@ -0,0 +1,230 @@
+package authz
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCan(t *testing.T) {
+	ownerID := uuid.New()
+	otherStudentID := uuid.New()
+	advisorID := uuid.New()
+	otherLecturerID := uuid.New()
+
+	admin := Subject{UserID: uuid.New(), RoleName: RoleAdmin}
+	owner := Subject{UserID: uuid.New(), RoleName: "Mahasiswa", StudentID: ownerID}
+	otherStudent := Subject{UserID: uuid.New(), RoleName: "Mahasiswa", StudentID: otherStudentID}
+	advisor := Subject{UserID: uuid.New(), RoleName: "Dosen Wali", LecturerID: advisorID, Department: "Computer Science"}
+	colleague := Subject{UserID: uuid.New(), RoleName: "Dosen Wali", LecturerID: otherLecturerID, Department: " teknik informatika "}
+	outsider := Subject{UserID: uuid.New(), RoleName: "Dosen Wali", LecturerID: otherLecturerID, Department: "Physics"}
+	integration := Subject{Service: true}
+	adminKey := Subject{RoleName: RoleAdmin, Service: true}
+	nobody := Subject{UserID: uuid.New(), RoleName: "Tamu"}
+
+	achievement := Resource{Kind: KindAchievement, OwnerID: ownerID, AdvisorID: &advisorID, Department: "Teknik Informatika"}
+	unadvised := Resource{Kind: KindAchievement, OwnerID: ownerID, Department: "Teknik Informatika"}
+	student := Resource{Kind: KindStudent, OwnerID: ownerID, AdvisorID: &advisorID, Department: "Teknik Informatika"}
+	file := Resource{Kind: KindFile, OwnerID: ownerID, AdvisorID: &advisorID}
+	orphanFile := Resource{Kind: KindFile}
+	newAchievement := Resource{Kind: KindAchievement}
+
+	kemahasiswaan := Subject{UserID: uuid.New(), RoleName: "Kemahasiswaan"}
+	officeStage := achievement
+	officeStage.Stage = &Stage{Role: "Kemahasiswaan"}
+	deanStage := achievement
+	deanStage.Stage = &Stage{UserID: nobody.UserID}
+	advisorStage := achievement
+	advisorStage.Stage = &Stage{Advisor: true}
+	officeKey := Subject{RoleName: "Kemahasiswaan", Service: true}
+
+	substitute := Subject{UserID: uuid.New(), RoleName: "Dosen Wali", LecturerID: uuid.New(), Department: "Physics",
+		Delegations: []Delegation{{AdvisorID: advisorID}}}
+	scopedSubstitute := Subject{UserID: uuid.New(), RoleName: "Dosen Wali", LecturerID: uuid.New(), Department: "Physics",
+		Delegations: []Delegation{{AdvisorID: advisorID, OwnerID: otherStudentID}}}
+	otherSubstitute := Subject{UserID: uuid.New(), RoleName: "Dosen Wali", LecturerID: uuid.New(), Department: "Physics",
+		Delegations: []Delegation{{AdvisorID: otherLecturerID}}}
+
+	tests := []struct {
+		name     string
+		subject  Subject
+		action   Action
+		resource Resource
+		want     bool
+	}{
+		// Achievements
+		{"admin lists achievements", admin, ActionList, newAchievement, true},
+		{"integration lists achievements", integration, ActionList, newAchievement, true},
+		{"student cannot list all achievements", owner, ActionList, newAchievement, false},
+		{"lecturer cannot list all achievements", advisor, ActionList, newAchievement, false},
+
+		{"admin reads achievement", admin, ActionRead, achievement, true},
+		{"owner reads achievement", owner, ActionRead, achievement, true},
+		{"advisor reads achievement", advisor, ActionRead, achievement, true},
+		{"lecturer of same department reads achievement", colleague, ActionRead, achievement, true},
+		{"lecturer of other department cannot read achievement", outsider, ActionRead, achievement, false},
+		{"other student cannot read achievement", otherStudent, ActionRead, achievement, false},
+		{"integration reads achievement", integration, ActionRead, achievement, true},
+		{"unknown role cannot read achievement", nobody, ActionRead, achievement, false},
+
+		{"student creates achievement", owner, ActionCreate, newAchievement, true},
+		{"lecturer cannot create achievement", advisor, ActionCreate, newAchievement, false},
+		{"admin without student profile cannot create achievement", admin, ActionCreate, newAchievement, false},
+
+		{"owner updates achievement", owner, ActionUpdate, achievement, true},
+		{"admin updates achievement", admin, ActionUpdate, achievement, true},
+		{"advisor cannot update achievement", advisor, ActionUpdate, achievement, false},
+		{"other student cannot update achievement", otherStudent, ActionUpdate, achievement, false},
+		{"integration cannot update achievement", integration, ActionUpdate, achievement, false},
+
+		{"owner deletes achievement", owner, ActionDelete, achievement, true},
+		{"other student cannot delete achievement", otherStudent, ActionDelete, achievement, false},
+		{"owner submits achievement", owner, ActionSubmit, achievement, true},
+		{"other student cannot submit achievement", otherStudent, ActionSubmit, achievement, false},
+		{"owner attaches files", owner, ActionAttach, achievement, true},
+		{"advisor cannot attach files", advisor, ActionAttach, achievement, false},
+
+		{"advisor verifies achievement", advisor, ActionVerify, achievement, true},
+		{"admin verifies achievement", admin, ActionVerify, achievement, true},
+		{"same department is not enough to verify", colleague, ActionVerify, achievement, false},
+		{"owner cannot verify own achievement", owner, ActionVerify, achievement, false},
+		{"nobody advises an unadvised student", advisor, ActionVerify, unadvised, false},
+		{"integration cannot verify", integration, ActionVerify, achievement, false},
+		{"admin role on API key does not make it admin", adminKey, ActionVerify, achievement, false},
+
+		{"owner comments on achievement", owner, ActionComment, achievement, true},
+		{"advisor comments on achievement", advisor, ActionComment, achievement, true},
+		{"advisor comments while another stage decides", advisor, ActionComment, officeStage, true},
+		{"office comments on achievement in its stage", kemahasiswaan, ActionComment, officeStage, true},
+		{"same department is not enough to comment", colleague, ActionComment, achievement, false},
+		{"other student cannot comment", otherStudent, ActionComment, achievement, false},
+		{"integration cannot comment", integration, ActionComment, achievement, false},
+
+		// Approval stages
+		{"advisor decides advisor stage", advisor, ActionVerify, advisorStage, true},
+		{"office decides its stage", kemahasiswaan, ActionVerify, officeStage, true},
+		{"office reads achievement waiting in its stage", kemahasiswaan, ActionRead, officeStage, true},
+		{"office cannot decide advisor stage", kemahasiswaan, ActionVerify, achievement, false},
+		{"office cannot read achievement outside its stage", kemahasiswaan, ActionRead, achievement, false},
+		{"advisor cannot decide office stage", advisor, ActionVerify, officeStage, false},
+		{"admin decides any stage", admin, ActionVerify, officeStage, true},
+		{"assigned user decides stage", nobody, ActionVerify, deanStage, true},
+		{"role member is not the assigned user", kemahasiswaan, ActionVerify, deanStage, false},
+		{"API key with the stage role cannot decide", officeKey, ActionVerify, officeStage, false},
+
+		// Delegations
+		{"substitute reads achievement", substitute, ActionRead, achievement, true},
+		{"substitute verifies achievement", substitute, ActionVerify, achievement, true},
+		{"substitute decides advisor stage", substitute, ActionVerify, advisorStage, true},
+		{"substitute cannot decide office stage", substitute, ActionVerify, officeStage, false},
+		{"substitute comments on achievement", substitute, ActionComment, achievement, true},
+		{"substitute cannot update achievement", substitute, ActionUpdate, achievement, false},
+		{"substitute of one advisee cannot verify another", scopedSubstitute, ActionVerify, achievement, false},
+		{"substitute of another advisor cannot verify", otherSubstitute, ActionVerify, achievement, false},
+		{"nobody stands in for the advisor of an unadvised student", substitute, ActionVerify, unadvised, false},
+		{"substitute reads advisee", substitute, ActionRead, student, true},
+
+		// Students
+		{"student reads own profile", owner, ActionRead, student, true},
+		{"student cannot read other profile", otherStudent, ActionRead, student, false},
+		{"advisor reads advisee", advisor, ActionRead, student, true},
+		{"lecturer of same department reads student", colleague, ActionRead, student, true},
+		{"lecturer of other department cannot read student", outsider, ActionRead, student, false},
+		{"admin manages student", admin, ActionManage, student, true},
+		{"advisor cannot manage student", advisor, ActionManage, student, false},
+		{"integration cannot manage student", integration, ActionManage, student, false},
+
+		// Files
+		{"student uploads file", owner, ActionCreate, orphanFile, true},
+		{"admin uploads file", admin, ActionCreate, orphanFile, true},
+		{"lecturer cannot upload file", advisor, ActionCreate, orphanFile, false},
+		{"owner deletes attached file", owner, ActionDelete, file, true},
+		{"other student cannot delete attached file", otherStudent, ActionDelete, file, false},
+		{"advisor cannot delete attached file", advisor, ActionDelete, file, false},
+		{"admin deletes unattached file", admin, ActionDelete, orphanFile, true},
+		{"student cannot delete unattached file", owner, ActionDelete, orphanFile, false},
+
+		// Unknown combinations are denied
+		{"unlisted action is denied", admin, ActionSubmit, student, false},
+		{"unknown resource kind is denied", admin, ActionRead, Resource{Kind: "grade"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Can(tt.subject, tt.action, tt.resource); got != tt.want {
+				t.Errorf("Can(%s on %s) = %v, want %v", tt.action, tt.resource.Kind, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSameDepartmentRequiresDepartment(t *testing.T) {
+	lecturer := Subject{LecturerID: uuid.New()}
+	resource := Resource{Kind: KindStudent, OwnerID: uuid.New()}
+
+	tests := []struct {
+		name               string
+		subjectDepartment  string
+		resourceDepartment string
+		want               bool
+	}{
+		{"both empty", "", "", false},
+		{"lecturer without department", "", "Teknik Informatika", false},
+		{"student without program", "Teknik Informatika", "", false},
+		{"case and spacing differ", "TEKNIK INFORMATIKA ", "teknik informatika", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lecturer.Department = tt.subjectDepartment
+			resource.Department = tt.resourceDepartment
+			if got := SameDepartment(lecturer, resource); got != tt.want {
+				t.Errorf("SameDepartment() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOnBehalfOf(t *testing.T) {
+	ownerID := uuid.New()
+	advisorID := uuid.New()
+	delegations := []Delegation{{AdvisorID: advisorID}}
+
+	admin := Subject{UserID: uuid.New(), RoleName: RoleAdmin}
+	advisor := Subject{UserID: uuid.New(), RoleName: "Dosen Wali", LecturerID: advisorID}
+	substitute := Subject{UserID: uuid.New(), RoleName: "Dosen Wali", LecturerID: uuid.New(), Delegations: delegations}
+	adminSubstitute := Subject{UserID: uuid.New(), RoleName: RoleAdmin, LecturerID: uuid.New(), Delegations: delegations}
+	officeSubstitute := Subject{UserID: uuid.New(), RoleName: "Kemahasiswaan", LecturerID: uuid.New(), Delegations: delegations}
+
+	achievement := Resource{Kind: KindAchievement, OwnerID: ownerID, AdvisorID: &advisorID}
+	advisorStage := achievement
+	advisorStage.Stage = &Stage{Advisor: true}
+	officeStage := achievement
+	officeStage.Stage = &Stage{Role: "Kemahasiswaan"}
+
+	tests := []struct {
+		name     string
+		subject  Subject
+		resource Resource
+		want     bool
+	}{
+		{"advisor decides for themselves", advisor, achievement, false},
+		{"admin decides for themselves", admin, achievement, false},
+		{"substitute decides on behalf of the advisor", substitute, achievement, true},
+		{"substitute decides advisor stage on behalf of the advisor", substitute, advisorStage, true},
+		{"admin holding a delegation decides as admin", adminSubstitute, achievement, false},
+		{"role member decides their own stage", officeSubstitute, officeStage, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			advisor, got := OnBehalfOf(tt.subject, tt.resource)
+			if got != tt.want {
+				t.Fatalf("OnBehalfOf() = %v, want %v", got, tt.want)
+			}
+			if got && advisor != advisorID {
+				t.Errorf("OnBehalfOf() advisor = %v, want %v", advisor, advisorID)
+			}
+		})
+	}
+}
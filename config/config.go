@@ -3,6 +3,8 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -28,10 +30,71 @@ type Config struct {
 	MongoDBName string
 
 	// JWT
-	JWTSecret           string
-	JWTRefreshSecret    string
-	JWTExpiresIn        time.Duration
-	JWTRefreshExpiresIn time.Duration
+	JWTSecret            string
+	JWTRefreshSecret     string
+	JWTExpiresIn         time.Duration
+	JWTRefreshExpiresIn  time.Duration
+	TokenCleanupInterval time.Duration
+
+	// Asymmetric access token signing (HS256 with JWTSecret when unset)
+	JWTSigningKeyFile       string
+	JWTVerificationKeyFiles []string
+
+	// Login throttling
+	LoginMaxAttempts     int
+	LoginBackoffBase     time.Duration
+	LoginLockoutDuration time.Duration
+	LoginAttemptWindow   time.Duration
+	LoginRateLimitMax    int
+	LoginRateLimitWindow time.Duration
+
+	// Two-factor authentication
+	MFAIssuer        string
+	MFARequiredRoles []string
+	MFAPendingTTL    time.Duration
+
+	// OpenID Connect single sign-on (disabled when OIDCIssuerURL is empty)
+	OIDCIssuerURL            string
+	OIDCClientID             string
+	OIDCClientSecret         string
+	OIDCRedirectURL          string
+	OIDCScopes               []string
+	OIDCStudentIDClaim       string
+	OIDCLecturerIDClaim      string
+	OIDCAutoProvision        bool
+	OIDCLinkExisting         bool
+	OIDCRequireVerifiedEmail bool
+
+	// Cache lifetime of resolved user roles and role permissions
+	PermissionCacheTTL time.Duration
+
+	// API keys for service-to-service integrations
+	APIKeyMaxTTL time.Duration
+
+	// Lifetime of the token an admin receives to act as another user
+	ImpersonationTTL time.Duration
+
+	// How long the author of an achievement comment may still edit it
+	CommentEditWindow time.Duration
+
+	// Verification SLA: approvers are reminded of submissions waiting longer than
+	// VerificationSLA and admins are alerted past VerificationEscalateAfter (0 disables either)
+	VerificationSLA              time.Duration
+	VerificationEscalateAfter    time.Duration
+	VerificationSLACheckInterval time.Duration
+
+	// Password reset
+	PasswordResetURL string
+	PasswordResetTTL time.Duration
+
+	// Mail
+	MailDriver   string
+	MailFrom     string
+	MailLogPath  string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
 
 	// File Upload
 	MaxFileSize int64
@@ -52,27 +115,66 @@ func LoadConfig() *Config {
 	}
 
 	return &Config{
-		AppEnv:              getEnv("APP_ENV", "development"),
-		Port:                getEnv("APP_PORT", "3000"),
-		APIVersion:          getEnv("API_VERSION", "v1"),
-		DBHost:              getEnv("DB_HOST", "localhost"),
-		DBPort:              getEnv("DB_PORT", "5432"),
-		DBName:              getEnv("DB_NAME", "achievement_db"),
-		DBUser:              getEnv("DB_USER", "postgres"),
-		DBPassword:          getEnv("DB_PASSWORD", "postgres"),
-		DBSSLMode:           getEnv("DB_SSLMODE", "disable"),
-		DBTimezone:          getEnv("DB_TIMEZONE", "Asia/Jakarta"),
-		MongoURI:            getEnv("MONGO_URI", "mongodb://localhost:27017"),
-		MongoDBName:         getEnv("MONGO_DB_NAME", "achievement_db"),
-		JWTSecret:           getEnv("JWT_SECRET", "your-secret-key"),
-		JWTRefreshSecret:    getEnv("JWT_REFRESH_SECRET", "your-refresh-secret-key"),
-		JWTExpiresIn:        parseDuration(getEnv("JWT_EXPIRES_IN", "1h")),
-		JWTRefreshExpiresIn: parseDuration(getEnv("JWT_REFRESH_EXPIRES_IN", "168h")),
-		MaxFileSize:         5242880, // 5MB
-		UploadPath:          getEnv("UPLOAD_PATH", "./uploads"),
-		CORSOrigin:          getEnv("CORS_ORIGIN", "*"),
-		RateLimitMax:        100,
-		RateLimitDuration:   1 * time.Minute,
+		AppEnv:                   getEnv("APP_ENV", "development"),
+		Port:                     getEnv("APP_PORT", "3000"),
+		APIVersion:               getEnv("API_VERSION", "v1"),
+		DBHost:                   getEnv("DB_HOST", "localhost"),
+		DBPort:                   getEnv("DB_PORT", "5432"),
+		DBName:                   getEnv("DB_NAME", "achievement_db"),
+		DBUser:                   getEnv("DB_USER", "postgres"),
+		DBPassword:               getEnv("DB_PASSWORD", "postgres"),
+		DBSSLMode:                getEnv("DB_SSLMODE", "disable"),
+		DBTimezone:               getEnv("DB_TIMEZONE", "Asia/Jakarta"),
+		MongoURI:                 getEnv("MONGO_URI", "mongodb://localhost:27017"),
+		MongoDBName:              getEnv("MONGO_DB_NAME", "achievement_db"),
+		JWTSecret:                getEnv("JWT_SECRET", "your-secret-key"),
+		JWTRefreshSecret:         getEnv("JWT_REFRESH_SECRET", "your-refresh-secret-key"),
+		JWTExpiresIn:             parseDuration(getEnv("JWT_EXPIRES_IN", "1h")),
+		JWTRefreshExpiresIn:      parseDuration(getEnv("JWT_REFRESH_EXPIRES_IN", "168h")),
+		TokenCleanupInterval:     parseDuration(getEnv("TOKEN_CLEANUP_INTERVAL", "1h")),
+		JWTSigningKeyFile:        getEnv("JWT_SIGNING_KEY_FILE", ""),
+		JWTVerificationKeyFiles:  parseList(getEnv("JWT_VERIFICATION_KEY_FILES", "")),
+		LoginMaxAttempts:         parseInt(getEnv("LOGIN_MAX_ATTEMPTS", "5"), 5),
+		LoginBackoffBase:         parseDuration(getEnv("LOGIN_BACKOFF_BASE", "1s")),
+		LoginLockoutDuration:     parseDuration(getEnv("LOGIN_LOCKOUT_DURATION", "15m")),
+		LoginAttemptWindow:       parseDuration(getEnv("LOGIN_ATTEMPT_WINDOW", "15m")),
+		LoginRateLimitMax:        parseInt(getEnv("LOGIN_RATE_LIMIT_MAX", "100"), 100),
+		LoginRateLimitWindow:     parseDuration(getEnv("LOGIN_RATE_LIMIT_WINDOW", "1m")),
+		MFAIssuer:                getEnv("MFA_ISSUER", "Student Achievement System"),
+		MFARequiredRoles:         parseList(getEnv("MFA_REQUIRED_ROLES", "Admin,Dosen Wali")),
+		MFAPendingTTL:            parseDuration(getEnv("MFA_PENDING_TTL", "5m")),
+		OIDCIssuerURL:            getEnv("OIDC_ISSUER_URL", ""),
+		OIDCClientID:             getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret:         getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:          getEnv("OIDC_REDIRECT_URL", "http://localhost:5173/auth/callback"),
+		OIDCScopes:               parseList(getEnv("OIDC_SCOPES", "openid,email,profile")),
+		OIDCStudentIDClaim:       getEnv("OIDC_STUDENT_ID_CLAIM", "nim"),
+		OIDCLecturerIDClaim:      getEnv("OIDC_LECTURER_ID_CLAIM", "nip"),
+		OIDCAutoProvision:        parseBool(getEnv("OIDC_AUTO_PROVISION", "false"), false),
+		OIDCLinkExisting:         parseBool(getEnv("OIDC_LINK_EXISTING", "false"), false),
+		OIDCRequireVerifiedEmail: parseBool(getEnv("OIDC_REQUIRE_VERIFIED_EMAIL", "true"), true),
+		PermissionCacheTTL:       parseDuration(getEnv("PERMISSION_CACHE_TTL", "1m")),
+		APIKeyMaxTTL:             parseDuration(getEnv("API_KEY_MAX_TTL", "8760h")),
+		ImpersonationTTL:         parseDuration(getEnv("IMPERSONATION_TTL", "15m")),
+		CommentEditWindow:        parseDuration(getEnv("COMMENT_EDIT_WINDOW", "30m")),
+		PasswordResetURL:         getEnv("PASSWORD_RESET_URL", "http://localhost:5173/reset-password"),
+		PasswordResetTTL:         parseDuration(getEnv("PASSWORD_RESET_TTL", "30m")),
+		MailDriver:               getEnv("MAIL_DRIVER", "log"),
+		MailFrom:                 getEnv("MAIL_FROM", "no-reply@localhost"),
+		MailLogPath:              getEnv("MAIL_LOG_PATH", "./storage/mail"),
+		SMTPHost:                 getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:                 getEnv("SMTP_PORT", "587"),
+		SMTPUsername:             getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:             getEnv("SMTP_PASSWORD", ""),
+		MaxFileSize:              5242880, // 5MB
+		UploadPath:               getEnv("UPLOAD_PATH", "./uploads"),
+		CORSOrigin:               getEnv("CORS_ORIGIN", "*"),
+		RateLimitMax:             100,
+		RateLimitDuration:        1 * time.Minute,
+
+		VerificationSLA:              parseDuration(getEnv("VERIFICATION_SLA", "72h")),
+		VerificationEscalateAfter:    parseDuration(getEnv("VERIFICATION_ESCALATE_AFTER", "168h")),
+		VerificationSLACheckInterval: parseDuration(getEnv("VERIFICATION_SLA_CHECK_INTERVAL", "1h")),
 	}
 }
 
@@ -91,3 +193,32 @@ func parseDuration(s string) time.Duration {
 	}
 	return duration
 }
+
+func parseInt(s string, defaultValue int) int {
+	value, err := strconv.Atoi(s)
+	if err != nil {
+		log.Printf("Invalid integer format: %s, using default %d", s, defaultValue)
+		return defaultValue
+	}
+	return value
+}
+
+// parseList splits a comma-separated value, dropping empty entries
+func parseList(s string) []string {
+	items := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func parseBool(s string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(s)
+	if err != nil {
+		log.Printf("Invalid boolean format: %s, using default %t", s, defaultValue)
+		return defaultValue
+	}
+	return value
+}
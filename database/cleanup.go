@@ -23,9 +23,33 @@ func CleanupAllDataExceptAdmin() error {
 	}
 	log.Printf("✓ Deleted %d achievements from MongoDB", deleteResult.DeletedCount)
 
-	// 2. Delete all achievement_references from PostgreSQL
+	log.Println("Deleting all achievement revisions from MongoDB...")
+	deleteResult, err = MongoDB.Collection("achievement_revisions").DeleteMany(context.Background(), bson.M{})
+	if err != nil {
+		log.Printf("Error deleting MongoDB achievement revisions: %v", err)
+		return err
+	}
+	log.Printf("✓ Deleted %d achievement revisions from MongoDB", deleteResult.DeletedCount)
+
+	// 2. Delete all achievement_references with their comments and approval decisions from PostgreSQL
+	log.Println("Deleting all achievement comments from PostgreSQL...")
+	result := PostgresDB.Exec("DELETE FROM achievement_comments")
+	if result.Error != nil {
+		log.Printf("Error deleting achievement_comments: %v", result.Error)
+		return result.Error
+	}
+	log.Printf("✓ Deleted %d achievement_comments from PostgreSQL", result.RowsAffected)
+
+	log.Println("Deleting all approval decisions from PostgreSQL...")
+	result = PostgresDB.Exec("DELETE FROM approval_decisions")
+	if result.Error != nil {
+		log.Printf("Error deleting approval_decisions: %v", result.Error)
+		return result.Error
+	}
+	log.Printf("✓ Deleted %d approval_decisions from PostgreSQL", result.RowsAffected)
+
 	log.Println("Deleting all achievement_references from PostgreSQL...")
-	result := PostgresDB.Exec("DELETE FROM achievement_references")
+	result = PostgresDB.Exec("DELETE FROM achievement_references")
 	if result.Error != nil {
 		log.Printf("Error deleting achievement_references: %v", result.Error)
 		return result.Error
@@ -41,7 +65,15 @@ func CleanupAllDataExceptAdmin() error {
 	}
 	log.Printf("✓ Deleted %d students from PostgreSQL", result.RowsAffected)
 
-	// 4. Delete all lecturers
+	// 4. Delete all lecturers with the delegations between them
+	log.Println("Deleting all advisor delegations...")
+	result = PostgresDB.Exec("DELETE FROM advisor_delegations")
+	if result.Error != nil {
+		log.Printf("Error deleting advisor_delegations: %v", result.Error)
+		return result.Error
+	}
+	log.Printf("✓ Deleted %d advisor_delegations from PostgreSQL", result.RowsAffected)
+
 	log.Println("Deleting all lecturers...")
 	result = PostgresDB.Exec("DELETE FROM lecturers")
 	if result.Error != nil {
@@ -97,10 +129,21 @@ func CleanupAllData() error {
 	}
 	log.Printf("✓ Deleted %d achievements from MongoDB", deleteResult.DeletedCount)
 
+	log.Println("Deleting all achievement revisions from MongoDB...")
+	deleteResult, err = MongoDB.Collection("achievement_revisions").DeleteMany(context.Background(), bson.M{})
+	if err != nil {
+		log.Printf("Error deleting MongoDB achievement revisions: %v", err)
+		return err
+	}
+	log.Printf("✓ Deleted %d achievement revisions from MongoDB", deleteResult.DeletedCount)
+
 	// PostgreSQL - order matters due to foreign keys
 	tables := []string{
+		"achievement_comments",
+		"approval_decisions",
 		"achievement_references",
 		"students",
+		"advisor_delegations",
 		"lecturers",
 		"users",
 	}
@@ -1,8 +1,17 @@
 package database
 
 import (
+	"context"
+	"encoding/json"
 	"log"
 	"student-achievement-system/models"
+	"student-achievement-system/points"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // Migrate runs database migrations
@@ -23,6 +32,21 @@ func Migrate() {
 		&models.AchievementReference{},
 		&models.AchievementStatusHistory{},
 		&models.Notification{},
+		&models.RevokedToken{},
+		&models.Session{},
+		&models.PasswordResetToken{},
+		&models.LoginAttempt{},
+		&models.MFARecoveryCode{},
+		&models.OIDCState{},
+		&models.APIKey{},
+		&models.APIKeyPermission{},
+		&models.AuditEvent{},
+		&models.ApprovalChain{},
+		&models.ApprovalStage{},
+		&models.ApprovalDecision{},
+		&models.AchievementComment{},
+		&models.AdvisorDelegation{},
+		&models.PointsRuleSet{},
 	)
 
 	// Re-enable foreign key constraints
@@ -33,12 +57,26 @@ func Migrate() {
 		log.Println("Continuing with existing schema...")
 	}
 
+	migrateMongo()
+
 	// Seed initial data
 	seedInitialData()
 
 	log.Println("Migrations completed successfully")
 }
 
+// migrateMongo creates the MongoDB indexes the repositories rely on
+func migrateMongo() {
+	// Revision numbers are unique per achievement
+	_, err := MongoDB.Collection("achievement_revisions").Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "achievementId", Value: 1}, {Key: "revision", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Printf("Migration warning: %v", err)
+	}
+}
+
 // seedInitialData creates initial roles and permissions
 func seedInitialData() {
 	// Create permissions
@@ -54,13 +92,20 @@ func seedInitialData() {
 		{Name: "achievement:delete", Description: "Delete achievements"},
 		{Name: "achievement:verify", Description: "Verify achievements"},
 		{Name: "report:read", Description: "Read reports"},
+		{Name: "role:manage", Description: "Manage roles and permissions"},
+		{Name: "user:impersonate", Description: "Act as another user for support"},
+		{Name: "audit:read", Description: "Read the audit log"},
+		{Name: "approval:manage", Description: "Configure achievement approval chains"},
+		{Name: "points:manage", Description: "Configure achievement points rules"},
 	}
 
+	builtInPermissions := make([]string, 0, len(permissions))
 	for _, perm := range permissions {
 		var existingPerm models.Permission
 		if err := PostgresDB.Where("name = ?", perm.Name).First(&existingPerm).Error; err != nil {
 			PostgresDB.Create(&perm)
 		}
+		builtInPermissions = append(builtInPermissions, perm.Name)
 	}
 
 	// Create roles
@@ -70,27 +115,37 @@ func seedInitialData() {
 		{Name: "Mahasiswa", Description: "Student"},
 	}
 
+	builtInRoles := make([]string, 0, len(roles))
 	for _, role := range roles {
 		var existingRole models.Role
 		if err := PostgresDB.Where("name = ?", role.Name).First(&existingRole).Error; err != nil {
 			PostgresDB.Create(&role)
 		}
+		builtInRoles = append(builtInRoles, role.Name)
 	}
 
+	// Seeded roles and permissions are referenced by the code and must not be renamed or deleted
+	PostgresDB.Model(&models.Permission{}).Where("name IN ?", builtInPermissions).Update("is_built_in", true)
+	PostgresDB.Model(&models.Role{}).Where("name IN ?", builtInRoles).Update("is_built_in", true)
+
 	// Assign permissions to roles
 	assignRolePermissions()
 
+	seedPointsRules()
+
 	log.Println("Initial data seeded successfully")
 }
 
-// assignRolePermissions assigns permissions to roles
+// assignRolePermissions assigns permissions to roles.
+// Default grants of Dosen Wali and Mahasiswa are only seeded while the role has none,
+// so that changes made through the role admin API survive restarts.
 func assignRolePermissions() {
-	// Admin - all permissions
+	// Admin - all built-in permissions; custom permissions are granted through the API
 	var adminRole models.Role
 	PostgresDB.Where("name = ?", "Admin").First(&adminRole)
 
 	var allPermissions []models.Permission
-	PostgresDB.Find(&allPermissions)
+	PostgresDB.Where("is_built_in = ?", true).Find(&allPermissions)
 
 	for _, perm := range allPermissions {
 		var existing models.RolePermission
@@ -107,6 +162,9 @@ func assignRolePermissions() {
 	PostgresDB.Where("name = ?", "Dosen Wali").First(&dosenRole)
 
 	dosenPerms := []string{"achievement:read", "achievement:verify", "report:read"}
+	if roleHasPermissions(dosenRole.ID) {
+		dosenPerms = nil
+	}
 	for _, permName := range dosenPerms {
 		var perm models.Permission
 		if PostgresDB.Where("name = ?", permName).First(&perm).Error == nil {
@@ -125,6 +183,9 @@ func assignRolePermissions() {
 	PostgresDB.Where("name = ?", "Mahasiswa").First(&mahasiswaRole)
 
 	mahasiswaPerms := []string{"achievement:create", "achievement:read", "achievement:update", "achievement:delete"}
+	if roleHasPermissions(mahasiswaRole.ID) {
+		mahasiswaPerms = nil
+	}
 	for _, permName := range mahasiswaPerms {
 		var perm models.Permission
 		if PostgresDB.Where("name = ?", permName).First(&perm).Error == nil {
@@ -138,3 +199,32 @@ func assignRolePermissions() {
 		}
 	}
 }
+
+// seedPointsRules stores the default points rules as version 1, in force since always,
+// until an admin configures other rule sets
+func seedPointsRules() {
+	var count int64
+	PostgresDB.Model(&models.PointsRuleSet{}).Count(&count)
+	if count > 0 {
+		return
+	}
+
+	rules, err := json.Marshal(points.DefaultRules())
+	if err != nil {
+		log.Printf("Seeding warning: %v", err)
+		return
+	}
+	PostgresDB.Create(&models.PointsRuleSet{
+		Version:       1,
+		Name:          "Default",
+		EffectiveFrom: time.Unix(0, 0).UTC(),
+		RulesJSON:     string(rules),
+	})
+}
+
+// roleHasPermissions reports whether any permission has been granted to the role
+func roleHasPermissions(roleID uuid.UUID) bool {
+	var count int64
+	PostgresDB.Model(&models.RolePermission{}).Where("role_id = ?", roleID).Count(&count)
+	return count > 0
+}
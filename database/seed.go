@@ -37,6 +37,11 @@ func SeedData(db *gorm.DB) {
 		{ID: uuid.New(), Name: "achievement:delete", Description: "Delete achievements"},
 		{ID: uuid.New(), Name: "achievement:verify", Description: "Verify achievements"},
 		{ID: uuid.New(), Name: "report:read", Description: "Read reports"},
+		{ID: uuid.New(), Name: "role:manage", Description: "Manage roles and permissions"},
+		{ID: uuid.New(), Name: "user:impersonate", Description: "Act as another user for support"},
+		{ID: uuid.New(), Name: "audit:read", Description: "Read the audit log"},
+		{ID: uuid.New(), Name: "approval:manage", Description: "Configure achievement approval chains"},
+		{ID: uuid.New(), Name: "points:manage", Description: "Configure achievement points rules"},
 	}
 
 	for _, perm := range permissions {
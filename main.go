@@ -12,6 +12,7 @@ import (
 	"student-achievement-system/repository"
 	"student-achievement-system/routes"
 	"student-achievement-system/service"
+	"student-achievement-system/sla"
 	"student-achievement-system/utils"
 
 	"github.com/gofiber/fiber/v2"
@@ -41,10 +42,22 @@ import (
 // @name Authorization
 // @description Enter token with "Bearer " prefix. Example: "Bearer eyJhbGc..."
 
+// @securityDefinitions.apikey APIKeyAuth
+// @in header
+// @name X-API-Key
+// @description API key issued by an admin for service integrations, limited to the key's permissions
+
 func main() {
 	// Parse command line flags
 	cleanupFlag := flag.Bool("cleanup", false, "Delete all data except admin user")
 	cleanupAllFlag := flag.Bool("cleanup-all", false, "Delete ALL data including admin (DANGER!)")
+	rescoreFlag := flag.Bool("rescore", false, "Recalculate achievement points with the points rules and exit (dry run unless -rescore-apply)")
+	rescoreApply := flag.Bool("rescore-apply", false, "Save the points recalculated by -rescore")
+	rescoreStatus := flag.String("rescore-status", "", "Only rescore achievements in this status")
+	rescoreType := flag.String("rescore-type", "", "Only rescore achievements of this type")
+	rescoreFrom := flag.String("rescore-from", "", "Only rescore achievements created on or after this date (YYYY-MM-DD)")
+	rescoreTo := flag.String("rescore-to", "", "Only rescore achievements created on or before this date (YYYY-MM-DD)")
+	rescoreVersion := flag.Int("rescore-version", 0, "Points rule set version to rescore with (default: the one in force)")
 	flag.Parse()
 
 	// Load configuration
@@ -112,25 +125,102 @@ func main() {
 	roleRepo := repository.NewRoleRepository(database.PostgresDB)
 	achievementRefRepo := repository.NewAchievementReferenceRepository(database.PostgresDB)
 	achievementRepo := repository.NewAchievementRepository(database.MongoDB)
+	revisionRepo := repository.NewAchievementRevisionRepository(database.MongoDB)
 	notificationRepo := repository.NewNotificationRepository(database.PostgresDB)
+	revokedTokenRepo := repository.NewRevokedTokenRepository(database.PostgresDB)
+	sessionRepo := repository.NewSessionRepository(database.PostgresDB)
+	passwordResetRepo := repository.NewPasswordResetRepository(database.PostgresDB)
+	loginAttemptRepo := repository.NewLoginAttemptRepository(database.PostgresDB)
+	mfaRepo := repository.NewMFARepository(database.PostgresDB)
+	oidcStateRepo := repository.NewOIDCStateRepository(database.PostgresDB)
+	apiKeyRepo := repository.NewAPIKeyRepository(database.PostgresDB)
+	permissionRepo := repository.NewPermissionRepository(database.PostgresDB)
+	auditRepo := repository.NewAuditRepository(database.PostgresDB)
+	approvalRepo := repository.NewApprovalRepository(database.PostgresDB)
+	commentRepo := repository.NewAchievementCommentRepository(database.PostgresDB)
+	delegationRepo := repository.NewAdvisorDelegationRepository(database.PostgresDB)
+	pointsRuleRepo := repository.NewPointsRuleRepository(database.PostgresDB)
+
+	// Handle the rescoring command
+	pointsRescorer := service.NewPointsRescorer(achievementRepo, revisionRepo, achievementRefRepo, studentRepo, pointsRuleRepo)
+	if *rescoreFlag {
+		options, err := service.NewRescoreOptions(*rescoreStatus, *rescoreType, *rescoreFrom, *rescoreTo, *rescoreVersion, *rescoreApply)
+		if err != nil {
+			log.Fatalf("Rescoring failed: %v", err)
+		}
+		options.AuthorName = "command line"
+		if err := rescoreAchievements(pointsRescorer, options); err != nil {
+			log.Fatalf("Rescoring failed: %v", err)
+		}
+		return
+	}
+
+	// Initialize mailer
+	var mailer utils.Mailer
+	if cfg.MailDriver == "smtp" {
+		mailer = utils.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	} else {
+		mailer = utils.NewLogMailer(cfg.MailLogPath, cfg.MailFrom)
+	}
+
+	// Load asymmetric JWT keys, falling back to the shared HS256 secret
+	var signingKeys *utils.KeySet
+	if cfg.JWTSigningKeyFile != "" {
+		keys, err := utils.LoadKeySet(cfg.JWTSigningKeyFile, cfg.JWTVerificationKeyFiles)
+		if err != nil {
+			log.Fatalf("Failed to load JWT keys: %v", err)
+		}
+		signingKeys = keys
+	} else if cfg.JWTSecret == "your-secret-key" {
+		log.Println("Warning: JWT_SECRET is using the default value, set JWT_SECRET or JWT_SIGNING_KEY_FILE")
+	}
+
+	// Single sign-on is enabled when an identity provider is configured
+	var oidcProvider *utils.OIDCProvider
+	if cfg.OIDCIssuerURL != "" {
+		oidcProvider = utils.NewOIDCProvider(cfg.OIDCIssuerURL, cfg.OIDCClientID, cfg.OIDCClientSecret, cfg.OIDCRedirectURL, cfg.OIDCScopes)
+	}
 
 	// Initialize services
-	authService := service.NewAuthService(userRepo, cfg)
-	userService := service.NewUserService(userRepo, studentRepo, lecturerRepo, roleRepo)
-	achievementService := service.NewAchievementService(achievementRepo, achievementRefRepo, studentRepo, lecturerRepo)
-	verificationService := service.NewVerificationService(achievementRepo, achievementRefRepo, studentRepo, lecturerRepo, notificationRepo)
-	studentService := service.NewStudentService(studentRepo, lecturerRepo, achievementRefRepo)
+	permissionCache := service.NewPermissionCache(userRepo, roleRepo, cfg.PermissionCacheTTL)
+	tokenService := service.NewTokenService(revokedTokenRepo, sessionRepo, apiKeyRepo, permissionCache, signingKeys, cfg)
+	authService := service.NewAuthService(userRepo, sessionRepo, passwordResetRepo, loginAttemptRepo, mfaRepo, auditRepo, tokenService, mailer, cfg)
+	sessionService := service.NewSessionService(sessionRepo, userRepo, auditRepo, tokenService)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, auditRepo, cfg)
+	roleService := service.NewRoleService(roleRepo, permissionRepo, auditRepo, permissionCache)
+	auditService := service.NewAuditService(auditRepo)
+	approvalService := service.NewApprovalService(approvalRepo, achievementRefRepo, roleRepo, userRepo, auditRepo)
+	pointsRuleService := service.NewPointsRuleService(pointsRuleRepo, pointsRescorer, auditRepo)
+	oidcService := service.NewOIDCService(authService, userRepo, studentRepo, lecturerRepo, roleRepo, oidcStateRepo, oidcProvider, cfg)
+	userService := service.NewUserService(userRepo, studentRepo, lecturerRepo, roleRepo, loginAttemptRepo, tokenService, permissionCache, auditRepo)
+	achievementService := service.NewAchievementService(achievementRepo, revisionRepo, achievementRefRepo, studentRepo, lecturerRepo, delegationRepo, approvalRepo, pointsRuleRepo, auditRepo)
+	verificationService := service.NewVerificationService(achievementRepo, revisionRepo, achievementRefRepo, studentRepo, lecturerRepo, delegationRepo, userRepo, notificationRepo, approvalRepo, auditRepo)
+	commentService := service.NewCommentService(achievementRepo, achievementRefRepo, studentRepo, lecturerRepo, delegationRepo, userRepo, commentRepo, notificationRepo, auditRepo, cfg)
+	delegationService := service.NewDelegationService(delegationRepo, studentRepo, lecturerRepo, notificationRepo, auditRepo)
+	studentService := service.NewStudentService(studentRepo, lecturerRepo, delegationRepo, achievementRefRepo, auditRepo)
 	lecturerService := service.NewLecturerService(lecturerRepo, studentRepo)
-	reportService := service.NewReportService(achievementRepo, achievementRefRepo, studentRepo, lecturerRepo)
-	fileService := service.NewFileService()
+	slaPolicy := sla.Policy{RemindAfter: cfg.VerificationSLA, EscalateAfter: cfg.VerificationEscalateAfter}
+	reportService := service.NewReportService(achievementRepo, achievementRefRepo, studentRepo, lecturerRepo, slaPolicy, sla.SystemClock)
+	fileService := service.NewFileService(achievementRepo, revisionRepo, achievementRefRepo, studentRepo, lecturerRepo, delegationRepo, auditRepo)
 	notificationService := service.NewNotificationService(notificationRepo, userRepo)
+	slaMonitor := service.NewSLAMonitor(achievementRefRepo, achievementRepo, studentRepo, lecturerRepo, delegationRepo, userRepo, roleRepo, notificationRepo, slaPolicy, sla.SystemClock)
 
 	// Create services struct
 	services := &routes.Services{
 		AuthService:         authService,
+		TokenService:        tokenService,
+		SessionService:      sessionService,
+		OIDCService:         oidcService,
+		APIKeyService:       apiKeyService,
+		RoleService:         roleService,
+		AuditService:        auditService,
+		ApprovalService:     approvalService,
+		PointsRuleService:   pointsRuleService,
 		UserService:         userService,
 		AchievementService:  achievementService,
 		VerificationService: verificationService,
+		CommentService:      commentService,
+		DelegationService:   delegationService,
 		StudentService:      studentService,
 		LecturerService:     lecturerService,
 		ReportService:       reportService,
@@ -138,6 +228,14 @@ func main() {
 		NotificationService: notificationService,
 	}
 
+	// Purge expired token revocation entries in the background
+	tokenService.StartCleanup(cfg.TokenCleanupInterval)
+
+	// Remind approvers of overdue submissions and escalate stale ones
+	if slaPolicy.Enabled() {
+		slaMonitor.Start(cfg.VerificationSLACheckInterval)
+	}
+
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
 		AppName:      "Student Achievement System",
@@ -146,12 +244,16 @@ func main() {
 
 	// Middleware
 	app.Use(recover.New())
+	app.Use(middleware.RequestIDMiddleware())
 	app.Use(middleware.RequestLoggerMiddleware()) // Use custom structured logger
 	app.Use(helmet.New())
 	app.Use(fiberCors.New(fiberCors.Config{
-		AllowOrigins: cfg.CORSOrigin,
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
-		AllowMethods: "GET, POST, PUT, DELETE, OPTIONS",
+		AllowOrigins:  cfg.CORSOrigin,
+		AllowHeaders:  "Origin, Content-Type, Accept, Authorization, X-API-Key, X-Request-ID",
+		AllowMethods:  "GET, POST, PUT, DELETE, OPTIONS",
+		ExposeHeaders: "X-Request-ID",
+		// Lets a frontend on the configured origin send the single sign-on binding cookie
+		AllowCredentials: cfg.CORSOrigin != "*",
 	}))
 
 	// Swagger documentation
@@ -174,6 +276,9 @@ func main() {
 		})
 	})
 
+	// Public keys for verifying access tokens in other services
+	app.Get("/.well-known/jwks.json", tokenService.JWKS)
+
 	// Setup routes
 	api := app.Group("/api/" + cfg.APIVersion)
 	routes.SetupRoutes(api, services, cfg)
@@ -191,6 +296,28 @@ func main() {
 	log.Fatal(app.Listen(":" + port))
 }
 
+// rescoreAchievements runs a rescoring and prints the change of points per student
+func rescoreAchievements(rescorer service.PointsRescorer, options service.RescoreOptions) error {
+	report, err := rescorer.Rescore(options)
+	if err != nil {
+		return err
+	}
+
+	for _, student := range report.Students {
+		fmt.Printf("%-12s %-30s %3d achievement(s) %6d -> %6d (%+d)\n",
+			student.StudentNumber, student.FullName, len(student.Changes), student.PointsBefore, student.PointsAfter, student.Delta)
+	}
+	fmt.Printf("Rule set version %d: %d achievement(s) scanned, %d scored differently, %+d points in total\n",
+		report.RuleSetVersion, report.Scanned, report.Changed, report.Delta)
+
+	if report.Applied {
+		log.Printf("✅ Rescoring completed, %d achievement(s) updated, %d missing revision(s) recorded. Exiting...", report.Updated, report.Repaired)
+	} else {
+		log.Println("Dry run, no points were changed. Run again with -rescore-apply to save them.")
+	}
+	return nil
+}
+
 // customErrorHandler handles errors globally
 func customErrorHandler(c *fiber.Ctx, err error) error {
 	code := fiber.StatusInternalServerError
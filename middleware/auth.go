@@ -1,16 +1,46 @@
 package middleware
 
 import (
+	"errors"
 	"strings"
-	"student-achievement-system/config"
 	"student-achievement-system/utils"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-// AuthMiddleware validates JWT token
-func AuthMiddleware(cfg *config.Config) fiber.Handler {
+// APIKeyHeader carries an API key for service-to-service requests
+const APIKeyHeader = "X-API-Key"
+
+// TokenVerifier validates an access token or API key, checks it against the revocation store
+// and records session and key activity
+type TokenVerifier interface {
+	VerifyAccessToken(tokenString string, allowedScopes ...string) (*utils.JWTClaims, error)
+	VerifyAPIKey(key string, ipAddress string) (*utils.JWTClaims, error)
+	TouchSession(claims *utils.JWTClaims, ipAddress, userAgent string)
+}
+
+// AuthMiddleware validates JWT token or an API key sent in the X-API-Key header.
+// Restricted tokens (e.g. mfa_pending) are only accepted when their scope is listed in allowedScopes;
+// API keys are never accepted on such endpoints.
+func AuthMiddleware(verifier TokenVerifier, allowedScopes ...string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		if apiKey := c.Get(APIKeyHeader); apiKey != "" {
+			if len(allowedScopes) > 0 {
+				return utils.ErrorResponse(c, fiber.StatusUnauthorized, "API keys are not valid for this endpoint")
+			}
+
+			claims, err := verifier.VerifyAPIKey(apiKey, c.IP())
+			if err != nil {
+				if errors.Is(err, utils.ErrTokenRevoked) {
+					return utils.ErrorResponse(c, fiber.StatusUnauthorized, "API key has been revoked")
+				}
+				return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Invalid or expired API key")
+			}
+
+			c.Locals("user", claims)
+			return c.Next()
+		}
+
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
 			return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Missing authorization header")
@@ -23,17 +53,56 @@ func AuthMiddleware(cfg *config.Config) fiber.Handler {
 		}
 
 		token := parts[1]
-		claims, err := utils.ValidateToken(token, cfg.JWTSecret)
+		claims, err := verifier.VerifyAccessToken(token, allowedScopes...)
 		if err != nil {
+			if errors.Is(err, utils.ErrTokenRevoked) {
+				return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Token has been revoked")
+			}
+			if errors.Is(err, utils.ErrTokenScope) {
+				return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Token is not valid for this endpoint")
+			}
+			if errors.Is(err, utils.ErrAccountUnavailable) {
+				return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Account is inactive or no longer exists")
+			}
 			return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Invalid or expired token")
 		}
 
+		// Keep the session's last-seen information up to date
+		verifier.TouchSession(claims, c.IP(), c.Get("User-Agent"))
+
 		// Store claims in context
 		c.Locals("user", claims)
+
+		if claims.Impersonating() {
+			return logImpersonatedRequest(c, claims)
+		}
 		return c.Next()
 	}
 }
 
+// logImpersonatedRequest runs the request and logs it with both the impersonated user and the admin
+func logImpersonatedRequest(c *fiber.Ctx, claims *utils.JWTClaims) error {
+	err := c.Next()
+
+	status := c.Response().StatusCode()
+	if fiberErr, ok := err.(*fiber.Error); ok {
+		status = fiberErr.Code
+	}
+
+	utils.GlobalLogger.Info("Impersonated request", map[string]interface{}{
+		"method":          c.Method(),
+		"path":            c.Path(),
+		"status":          status,
+		"ip":              c.IP(),
+		"user_id":         claims.UserID,
+		"username":        claims.Username,
+		"impersonator_id": claims.Actor.UserID,
+		"impersonator":    claims.Actor.Username,
+		"token_id":        claims.ID,
+	})
+	return err
+}
+
 // GetUserFromContext retrieves user claims from context
 func GetUserFromContext(c *fiber.Ctx) *utils.JWTClaims {
 	user := c.Locals("user")
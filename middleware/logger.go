@@ -1,12 +1,20 @@
 package middleware
 
 import (
+	"regexp"
 	"student-achievement-system/utils"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 )
 
+// RequestIDHeader carries the ID that ties a request to its log lines and audit events
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID limits client-supplied request IDs to what is safe to store and log
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
 // ErrorHandlerMiddleware handles all errors globally with structured logging
 func ErrorHandlerMiddleware() fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -43,6 +51,28 @@ func ErrorHandlerMiddleware() fiber.Handler {
 	}
 }
 
+// RequestIDMiddleware assigns every request an ID, reusing the caller's X-Request-ID
+// when it is well formed, and echoes it in the response
+func RequestIDMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestID := c.Get(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.New().String()
+		}
+
+		c.Locals(utils.RequestIDKey, requestID)
+		c.Set(RequestIDHeader, requestID)
+
+		return c.Next()
+	}
+}
+
+// GetRequestID returns the ID assigned to the current request
+func GetRequestID(c *fiber.Ctx) string {
+	requestID, _ := c.Locals(utils.RequestIDKey).(string)
+	return requestID
+}
+
 // RequestLoggerMiddleware logs all incoming requests
 func RequestLoggerMiddleware() fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -7,11 +7,13 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/limiter"
 )
 
-// LoginRateLimiter restricts login attempts to prevent brute force attacks
-func LoginRateLimiter() fiber.Handler {
+// LoginRateLimiter restricts authentication requests per IP address.
+// The limit is deliberately generous because many users share an IP behind
+// campus NAT; per-account throttling happens in the auth service.
+func LoginRateLimiter(max int, expiration time.Duration) fiber.Handler {
 	return limiter.New(limiter.Config{
-		Max:        1000,              // Increased for testing (original: 5)
-		Expiration: 1 * time.Minute,   // Reduced for testing (original: 15 minutes)
+		Max:        max,
+		Expiration: expiration,
 		KeyGenerator: func(c *fiber.Ctx) string {
 			// Rate limit by IP address
 			return c.IP()
@@ -20,7 +22,7 @@ func LoginRateLimiter() fiber.Handler {
 			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
 				"status":  "error",
 				"error":   "Too many login attempts",
-				"message": "Please try again in 15 minutes",
+				"message": "Please try again in " + expiration.String(),
 			})
 		},
 		SkipFailedRequests:     false, // Count failed requests
@@ -6,7 +6,9 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-// RequirePermission checks if user has the required permission
+// RequirePermission checks if user has the required permission.
+// Permissions are those of the user's current role as resolved by AuthMiddleware,
+// or the granted scopes of an API key.
 func RequirePermission(permission string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		user := GetUserFromContext(c)
@@ -67,3 +69,33 @@ func RequireRole(roleName string) fiber.Handler {
 		return c.Next()
 	}
 }
+
+// RequireUser rejects requests authenticated with an API key, for endpoints that act on the
+// caller's own account or manage credentials
+func RequireUser() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := GetUserFromContext(c)
+		if user == nil {
+			return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Unauthorized")
+		}
+		if user.APIKeyID != "" {
+			return utils.ErrorResponse(c, fiber.StatusForbidden, "API keys cannot access this endpoint")
+		}
+		return c.Next()
+	}
+}
+
+// ForbidImpersonation rejects requests made with an impersonation token, for actions that
+// only the account owner may take, such as changing credentials or deleting data for good
+func ForbidImpersonation() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := GetUserFromContext(c)
+		if user == nil {
+			return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Unauthorized")
+		}
+		if user.Impersonating() {
+			return utils.ErrorResponse(c, fiber.StatusForbidden, "This action is not allowed while impersonating another user")
+		}
+		return c.Next()
+	}
+}
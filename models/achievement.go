@@ -29,6 +29,7 @@ type Achievement struct {
 	Attachments     []Attachment       `bson:"attachments" json:"attachments"`
 	Tags            []string           `bson:"tags" json:"tags"`
 	Points          int                `bson:"points" json:"points"`
+	PointsVersion   int                `bson:"pointsVersion" json:"points_version"` // rule set version the points were scored with, 0 for the default rules
 	CreatedAt       time.Time          `bson:"createdAt" json:"created_at"`
 	UpdatedAt       time.Time          `bson:"updatedAt" json:"updated_at"`
 }
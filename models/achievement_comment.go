@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AchievementComment is a message in the discussion thread of an achievement between
+// the student and those verifying it. Internal comments are hidden from students.
+type AchievementComment struct {
+	ID               uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	AchievementRefID uuid.UUID  `gorm:"type:uuid;not null;index" json:"achievement_ref_id"`
+	AuthorID         uuid.UUID  `gorm:"type:uuid;not null" json:"author_id"`
+	AuthorName       string     `gorm:"type:varchar(100)" json:"author_name"`
+	AuthorRole       string     `gorm:"type:varchar(50)" json:"author_role"` // role of the author when the comment was written
+	Body             string     `gorm:"type:text;not null" json:"body"`
+	AttachmentURL    string     `gorm:"type:varchar(255)" json:"attachment_url,omitempty"` // attachment of the achievement the comment refers to
+	Internal         bool       `gorm:"not null;default:false" json:"internal"`
+	EditedAt         *time.Time `json:"edited_at,omitempty"`
+	DeletedAt        *time.Time `gorm:"index" json:"-"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// BeforeCreate hook for AchievementComment
+func (a *AchievementComment) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName specifies the table name for AchievementComment
+func (AchievementComment) TableName() string {
+	return "achievement_comments"
+}
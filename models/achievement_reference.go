@@ -29,7 +29,16 @@ type AchievementReference struct {
 	VerifiedAt         *time.Time        `json:"verified_at,omitempty"`
 	VerifiedBy         *uuid.UUID        `gorm:"type:uuid" json:"verified_by,omitempty"`
 	VerifiedByUser     *User             `gorm:"foreignKey:VerifiedBy" json:"verified_by_user,omitempty"`
+	VerifiedRevision   *int              `json:"verified_revision,omitempty"` // content revision that was approved
 	RejectionNote      string            `gorm:"type:text" json:"rejection_note,omitempty"`
+	ResubmissionCount  int               `gorm:"not null;default:0" json:"resubmission_count"`
+	ApprovalChainID    *uuid.UUID        `gorm:"type:uuid" json:"approval_chain_id,omitempty"`
+	CurrentStageID     *uuid.UUID        `gorm:"type:uuid" json:"current_stage_id,omitempty"` // stage awaiting a decision while submitted
+	CurrentStage       *ApprovalStage    `gorm:"-" json:"current_stage,omitempty"`
+	FirstViewedAt      *time.Time        `json:"first_viewed_at,omitempty"` // when a verifier first opened the current submission
+	FirstViewedBy      *uuid.UUID        `gorm:"type:uuid" json:"first_viewed_by,omitempty"`
+	SLARemindedAt      *time.Time        `json:"sla_reminded_at,omitempty"`  // when its approvers were reminded of the overdue submission
+	SLAEscalatedAt     *time.Time        `json:"sla_escalated_at,omitempty"` // when the overdue submission was escalated to the admins
 	CreatedAt          time.Time         `json:"created_at"`
 	UpdatedAt          time.Time         `json:"updated_at"`
 }
@@ -56,7 +65,9 @@ type AchievementStatusHistory struct {
 	NewStatus        AchievementStatus     `gorm:"type:varchar(20);not null" json:"new_status"`
 	ChangedBy        uuid.UUID             `gorm:"type:uuid;not null" json:"changed_by"`
 	ChangedByUser    *User                 `gorm:"foreignKey:ChangedBy" json:"changed_by_user,omitempty"`
+	OnBehalfOf       *uuid.UUID            `gorm:"type:uuid" json:"on_behalf_of,omitempty"` // user of the advisor a delegate decided for
 	Notes            string                `gorm:"type:text" json:"notes,omitempty"`
+	Snapshot         *string               `gorm:"type:jsonb" json:"-"` // achievement content as JSON at the time of the change
 	CreatedAt        time.Time             `json:"created_at"`
 }
 
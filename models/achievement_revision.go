@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AchievementRevision is an immutable snapshot of an achievement document in MongoDB,
+// taken every time its content changes. Revisions are numbered from 1 per achievement.
+type AchievementRevision struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	AchievementID string             `bson:"achievementId" json:"achievement_id"`
+	Revision      int                `bson:"revision" json:"revision"`
+	Change        string             `bson:"change" json:"change"` // what produced it, e.g. "create", "update" or "sync" for content recorded late
+	Content       Achievement        `bson:"content" json:"content"`
+	AuthorID      string             `bson:"authorId,omitempty" json:"author_id,omitempty"`
+	AuthorName    string             `bson:"authorName,omitempty" json:"author_name,omitempty"`
+	CreatedAt     time.Time          `bson:"createdAt" json:"created_at"`
+}
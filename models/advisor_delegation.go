@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AdvisorDelegation lets a lecturer act as the advisor of another lecturer's advisees for
+// a period, e.g. while the advisor is on leave. It may be limited to a single advisee.
+type AdvisorDelegation struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	DelegatorID uuid.UUID  `gorm:"type:uuid;not null;index" json:"delegator_id"` // lecturer profile of the advisor
+	Delegator   *Lecturer  `gorm:"-" json:"delegator,omitempty"`
+	DelegateID  uuid.UUID  `gorm:"type:uuid;not null;index" json:"delegate_id"` // lecturer profile acting for them
+	Delegate    *Lecturer  `gorm:"-" json:"delegate,omitempty"`
+	StudentID   *uuid.UUID `gorm:"type:uuid" json:"student_id,omitempty"` // the only advisee covered, all advisees when nil
+	StartsAt    time.Time  `gorm:"not null" json:"starts_at"`
+	EndsAt      time.Time  `gorm:"not null" json:"ends_at"`
+	Reason      string     `gorm:"type:text" json:"reason,omitempty"`
+	CreatedBy   uuid.UUID  `gorm:"type:uuid;not null" json:"created_by"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	RevokedBy   *uuid.UUID `gorm:"type:uuid" json:"revoked_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// BeforeCreate hook for AdvisorDelegation
+func (d *AdvisorDelegation) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName specifies the table name for AdvisorDelegation
+func (AdvisorDelegation) TableName() string {
+	return "advisor_delegations"
+}
+
+// Active reports whether the delegation is in force at t
+func (d *AdvisorDelegation) Active(t time.Time) bool {
+	return d.RevokedAt == nil && !t.Before(d.StartsAt) && t.Before(d.EndsAt)
+}
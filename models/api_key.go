@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// APIKey is an admin-issued credential for service-to-service integrations.
+// Only the SHA-256 hash of the key is stored; Prefix identifies the key in listings.
+// A key is granted an explicit set of permissions through api_key_permissions.
+type APIKey struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Name        string     `gorm:"type:varchar(100);not null" json:"name"`
+	Description string     `gorm:"type:text" json:"description"`
+	Prefix      string     `gorm:"type:varchar(16);not null;index" json:"prefix"`
+	KeyHash     string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	CreatedBy   uuid.UUID  `gorm:"type:uuid;not null;index" json:"created_by"`
+	ExpiresAt   time.Time  `gorm:"not null" json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP  string     `gorm:"type:varchar(45)" json:"last_used_ip,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	Scopes      []string   `gorm:"-" json:"scopes"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// BeforeCreate hook for APIKey
+func (k *APIKey) BeforeCreate(tx *gorm.DB) error {
+	if k.ID == uuid.Nil {
+		k.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName specifies the table name for APIKey
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// APIKeyPermission grants a permission to an API key
+type APIKeyPermission struct {
+	APIKeyID     uuid.UUID  `gorm:"type:uuid;primaryKey" json:"api_key_id"`
+	PermissionID uuid.UUID  `gorm:"type:uuid;primaryKey" json:"permission_id"`
+	APIKey       APIKey     `gorm:"foreignKey:APIKeyID" json:"api_key,omitempty"`
+	Permission   Permission `gorm:"foreignKey:PermissionID" json:"permission,omitempty"`
+}
+
+// TableName specifies the table name for APIKeyPermission
+func (APIKeyPermission) TableName() string {
+	return "api_key_permissions"
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Who decides an approval stage
+const (
+	ApproverAdvisor = "advisor" // the academic advisor of the student
+	ApproverRole    = "role"    // members of a role, e.g. the Kemahasiswaan office
+	ApproverUser    = "user"    // one named user
+)
+
+// Decisions taken at an approval stage
+const (
+	DecisionApprove = "approve"
+	DecisionReject  = "reject"
+)
+
+// ApprovalChain is the ordered list of stages a submitted achievement of a type, and
+// optionally of a competition level, has to pass before it is verified. Achievements
+// without a matching chain are verified by the advisor alone.
+type ApprovalChain struct {
+	ID               uuid.UUID       `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Name             string          `gorm:"type:varchar(100);not null" json:"name"`
+	AchievementType  AchievementType `gorm:"type:varchar(50);not null;index" json:"achievement_type"`
+	CompetitionLevel string          `gorm:"type:varchar(50)" json:"competition_level"` // empty matches every level
+	Stages           []ApprovalStage `gorm:"foreignKey:ChainID" json:"stages"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+}
+
+// BeforeCreate hook for ApprovalChain
+func (a *ApprovalChain) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName specifies the table name for ApprovalChain
+func (ApprovalChain) TableName() string {
+	return "approval_chains"
+}
+
+// ApprovalStage is one step of an approval chain. It completes once Quorum approvers
+// have approved; a single rejection rejects the achievement.
+type ApprovalStage struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	ChainID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"chain_id"`
+	Position     int        `gorm:"not null" json:"position"` // 1 for the first stage
+	Name         string     `gorm:"type:varchar(100);not null" json:"name"`
+	ApproverType string     `gorm:"type:varchar(20);not null" json:"approver_type"`
+	RoleID       *uuid.UUID `gorm:"type:uuid" json:"role_id,omitempty"`
+	Role         *Role      `gorm:"-" json:"role,omitempty"`
+	UserID       *uuid.UUID `gorm:"type:uuid" json:"user_id,omitempty"`
+	Quorum       int        `gorm:"not null;default:1" json:"quorum"`
+}
+
+// BeforeCreate hook for ApprovalStage
+func (a *ApprovalStage) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName specifies the table name for ApprovalStage
+func (ApprovalStage) TableName() string {
+	return "approval_stages"
+}
+
+// ApprovalDecision records an approver's decision at a stage. Round is the resubmission
+// count of the achievement, so decisions on earlier submissions do not count again.
+type ApprovalDecision struct {
+	ID               uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	AchievementRefID uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_approval_decision_vote" json:"achievement_ref_id"`
+	StageID          uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_approval_decision_vote" json:"stage_id"`
+	StageName        string     `gorm:"type:varchar(100)" json:"stage_name"`
+	Position         int        `gorm:"not null" json:"position"`
+	Round            int        `gorm:"not null;uniqueIndex:idx_approval_decision_vote" json:"round"`
+	DecidedBy        uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_approval_decision_vote" json:"decided_by"`
+	DecidedByUser    *User      `gorm:"-" json:"decided_by_user,omitempty"`
+	OnBehalfOf       *uuid.UUID `gorm:"type:uuid" json:"on_behalf_of,omitempty"` // user of the advisor a delegate decided for
+	OnBehalfOfUser   *User      `gorm:"-" json:"on_behalf_of_user,omitempty"`
+	Decision         string     `gorm:"type:varchar(10);not null" json:"decision"`
+	Comments         string     `gorm:"type:text" json:"comments,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// BeforeCreate hook for ApprovalDecision
+func (a *ApprovalDecision) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName specifies the table name for ApprovalDecision
+func (ApprovalDecision) TableName() string {
+	return "approval_decisions"
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AuditEvent records a change made through the API and who made it.
+// Changes holds a JSON object with the "before" and/or "after" state of the resource;
+// for updates only the fields that changed are included.
+type AuditEvent struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	ActorID      *uuid.UUID `gorm:"type:uuid;index" json:"actor_id,omitempty"`
+	ActorName    string     `gorm:"type:varchar(100)" json:"actor_name"`
+	Action       string     `gorm:"type:varchar(100);not null;index" json:"action"`
+	ResourceType string     `gorm:"type:varchar(50);not null;index:idx_audit_resource" json:"resource_type"`
+	ResourceID   string     `gorm:"type:varchar(100);index:idx_audit_resource" json:"resource_id"`
+	Changes      string     `gorm:"type:jsonb" json:"changes"`
+	IPAddress    string     `gorm:"type:varchar(45)" json:"ip_address"`
+	RequestID    string     `gorm:"type:varchar(64);index" json:"request_id"`
+	CreatedAt    time.Time  `gorm:"index" json:"created_at"`
+}
+
+// BeforeCreate hook for AuditEvent
+func (e *AuditEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName specifies the table name for AuditEvent
+func (AuditEvent) TableName() string {
+	return "audit_events"
+}
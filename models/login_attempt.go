@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoginAttempt tracks consecutive failed logins for one identity.
+// Identifier is "user:<id>" for known accounts so username and email logins
+// share one counter, or the normalized identifier for unknown accounts.
+type LoginAttempt struct {
+	Identifier   string     `gorm:"type:varchar(255);primaryKey" json:"identifier"`
+	UserID       *uuid.UUID `gorm:"type:uuid;index" json:"user_id,omitempty"`
+	FailedCount  int        `gorm:"not null;default:0" json:"failed_count"`
+	LastFailedAt time.Time  `json:"last_failed_at"`
+	LockedUntil  *time.Time `json:"locked_until,omitempty"`
+	// ReservedUntil is set while a login attempt is being checked, so concurrent
+	// guesses cannot all pass the throttle before the first failure is counted
+	ReservedUntil *time.Time `json:"reserved_until,omitempty"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// TableName specifies the table name for LoginAttempt
+func (LoginAttempt) TableName() string {
+	return "login_attempts"
+}
@@ -9,10 +9,15 @@ import (
 type NotificationType string
 
 const (
-	NotificationTypeAchievementSubmitted NotificationType = "achievement_submitted"
-	NotificationTypeAchievementVerified  NotificationType = "achievement_verified"
-	NotificationTypeAchievementRejected  NotificationType = "achievement_rejected"
-	NotificationTypeAdvisorAssigned      NotificationType = "advisor_assigned"
+	NotificationTypeAchievementSubmitted  NotificationType = "achievement_submitted"
+	NotificationTypeAchievementVerified   NotificationType = "achievement_verified"
+	NotificationTypeAchievementRejected   NotificationType = "achievement_rejected"
+	NotificationTypeAchievementWithdrawn  NotificationType = "achievement_withdrawn"
+	NotificationTypeVerificationOverdue   NotificationType = "verification_overdue"
+	NotificationTypeVerificationEscalated NotificationType = "verification_escalated"
+	NotificationTypeAdvisorAssigned       NotificationType = "advisor_assigned"
+	NotificationTypeAdvisorDelegation     NotificationType = "advisor_delegation"
+	NotificationTypeAchievementComment    NotificationType = "achievement_comment"
 )
 
 type Notification struct {
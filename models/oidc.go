@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// OIDCState holds the per-login secrets of a pending OpenID Connect
+// authorization request until the callback redeems it. BindingHash is the hash
+// of the cookie given to the browser that started the login.
+type OIDCState struct {
+	StateHash    string    `gorm:"type:varchar(64);primaryKey" json:"-"`
+	Nonce        string    `gorm:"type:varchar(64);not null" json:"-"`
+	CodeVerifier string    `gorm:"type:varchar(128);not null" json:"-"`
+	BindingHash  string    `gorm:"type:varchar(64)" json:"-"`
+	ExpiresAt    time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for OIDCState
+func (OIDCState) TableName() string {
+	return "oidc_states"
+}
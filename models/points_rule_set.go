@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PointsRuleSet is a version of the rules achievements are scored with. The set in force
+// at a time is the one with the latest EffectiveFrom not after it; sets already in force
+// are never changed, a new version replaces them instead.
+type PointsRuleSet struct {
+	ID            uuid.UUID    `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Version       int          `gorm:"not null;uniqueIndex" json:"version"`
+	Name          string       `gorm:"type:varchar(100);not null" json:"name"`
+	EffectiveFrom time.Time    `gorm:"not null;index" json:"effective_from"`
+	RulesJSON     string       `gorm:"column:rules;type:jsonb;not null" json:"-"`
+	Rules         []PointsRule `gorm:"-" json:"rules"`
+	CreatedBy     *uuid.UUID   `gorm:"type:uuid" json:"created_by,omitempty"`
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+}
+
+// PointsRule awards its points to every achievement meeting all of its conditions.
+// Conditions left empty match any achievement; text is compared case-insensitively.
+type PointsRule struct {
+	Name             string            `json:"name"`
+	Points           int               `json:"points"`
+	AchievementType  string            `json:"achievement_type,omitempty"`
+	CompetitionLevel string            `json:"competition_level,omitempty"`
+	Rank             *int              `json:"rank,omitempty"`
+	MedalType        string            `json:"medal_type,omitempty"`
+	PublicationType  string            `json:"publication_type,omitempty"`
+	CustomFields     map[string]string `json:"custom_fields,omitempty"` // values the achievement's custom fields must have
+}
+
+// BeforeCreate hook for PointsRuleSet
+func (p *PointsRuleSet) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName specifies the table name for PointsRuleSet
+func (PointsRuleSet) TableName() string {
+	return "points_rule_sets"
+}
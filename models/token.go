@@ -0,0 +1,82 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RevokedToken represents a server-side token revocation entry.
+// JTI holds either the jti claim of a single revoked token, a session key
+// ("session:<id>") that revokes a whole refresh token family, or a
+// user-wide key ("user:<id>") that revokes every token issued before IssuedBefore.
+type RevokedToken struct {
+	JTI          string     `gorm:"type:varchar(100);primaryKey" json:"jti"`
+	UserID       uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	IssuedBefore *time.Time `json:"issued_before,omitempty"`
+	ExpiresAt    time.Time  `gorm:"not null;index" json:"expires_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// TableName specifies the table name for RevokedToken
+func (RevokedToken) TableName() string {
+	return "revoked_tokens"
+}
+
+// Session represents a refresh token family created at login.
+// Every refresh rotates RefreshJTI; presenting an older refresh token of the
+// same family is treated as token theft and revokes the whole family.
+type Session struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID        uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	User          *User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	RefreshJTI    string     `gorm:"type:varchar(100);not null" json:"-"`
+	RotationCount int        `gorm:"default:0" json:"rotation_count"`
+	UserAgent     string     `gorm:"type:varchar(255)" json:"user_agent"`
+	IPAddress     string     `gorm:"type:varchar(45)" json:"ip_address"`
+	LastSeenAt    time.Time  `json:"last_seen_at"`
+	ExpiresAt     time.Time  `gorm:"not null;index" json:"expires_at"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	RevokedReason string     `gorm:"type:varchar(50)" json:"revoked_reason,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// BeforeCreate hook for Session
+func (s *Session) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName specifies the table name for Session
+func (Session) TableName() string {
+	return "sessions"
+}
+
+// PasswordResetToken is a single-use token emailed to a user who forgot their password.
+// Only the SHA-256 hash of the token is stored.
+type PasswordResetToken struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	User      *User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	TokenHash string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null;index" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// BeforeCreate hook for PasswordResetToken
+func (t *PasswordResetToken) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+// TableName specifies the table name for PasswordResetToken
+func (PasswordResetToken) TableName() string {
+	return "password_reset_tokens"
+}
@@ -7,6 +7,12 @@ import (
 	"gorm.io/gorm"
 )
 
+// Login methods an account can use
+const (
+	AuthProviderLocal = "local"
+	AuthProviderOIDC  = "oidc"
+)
+
 // User represents a user in the system
 type User struct {
 	ID           uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
@@ -15,6 +21,11 @@ type User struct {
 	PasswordHash string         `gorm:"type:varchar(255);not null" json:"-"`
 	FullName     string         `gorm:"type:varchar(100);not null" json:"full_name"`
 	IsActive     bool           `gorm:"default:true" json:"is_active"`
+	MFAEnabled   bool           `gorm:"column:mfa_enabled;default:false" json:"mfa_enabled"`
+	MFASecret    string         `gorm:"column:mfa_secret;type:varchar(64)" json:"-"`
+	MFALastStep  int64          `gorm:"column:mfa_last_step;default:0" json:"-"`
+	AuthProvider string         `gorm:"type:varchar(20);not null;default:'local'" json:"auth_provider"`
+	ExternalID   *string        `gorm:"column:external_subject;type:varchar(255);uniqueIndex" json:"external_subject,omitempty"`
 	RoleID       uuid.UUID      `gorm:"type:uuid;not null" json:"role_id"`
 	Role         Role           `gorm:"foreignKey:RoleID" json:"role,omitempty"`
 	CreatedAt    time.Time      `json:"created_at"`
@@ -32,10 +43,12 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 
 // Role represents a user role
 type Role struct {
-	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	Name        string    `gorm:"type:varchar(50);unique;not null" json:"name"`
-	Description string    `gorm:"type:text" json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID                uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Name              string    `gorm:"type:varchar(50);unique;not null" json:"name"`
+	Description       string    `gorm:"type:text" json:"description"`
+	IsBuiltIn         bool      `gorm:"not null;default:false" json:"is_built_in"`    // Built-in roles cannot be renamed or deleted
+	PermissionVersion int64     `gorm:"not null;default:1" json:"permission_version"` // Incremented whenever the role's grants change
+	CreatedAt         time.Time `json:"created_at"`
 }
 
 // BeforeCreate hook for Role
@@ -51,6 +64,7 @@ type Permission struct {
 	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
 	Name        string    `gorm:"type:varchar(100);unique;not null" json:"name"`
 	Description string    `gorm:"type:text" json:"description"`
+	IsBuiltIn   bool      `gorm:"not null;default:false" json:"is_built_in"` // Built-in permissions are checked by routes and cannot be renamed or deleted
 	CreatedAt   time.Time `json:"created_at"`
 }
 
@@ -0,0 +1,107 @@
+// Package points scores achievements with rules kept as data rather than code.
+//
+// Rules are grouped in versioned rule sets, each in force from a given date. Every rule
+// whose conditions an achievement meets adds its points, so a set usually holds a base
+// rule per achievement type and bonus rules for levels, ranks, medals and publication
+// types. Scoring reads the parsed achievement details, never the raw request.
+package points
+
+import (
+	"fmt"
+	"strings"
+	"student-achievement-system/models"
+)
+
+// Result is the score of an achievement under a list of rules
+type Result struct {
+	Points  int
+	Applied []string // names of the rules that matched, in rule order
+}
+
+// Score adds up the points of every rule the achievement meets
+func Score(rules []models.PointsRule, achievement *models.Achievement) Result {
+	result := Result{Applied: []string{}}
+	for _, rule := range rules {
+		if Matches(rule, achievement) {
+			result.Points += rule.Points
+			result.Applied = append(result.Applied, rule.Name)
+		}
+	}
+	return result
+}
+
+// Matches reports whether the achievement meets every condition of the rule
+func Matches(rule models.PointsRule, achievement *models.Achievement) bool {
+	details := achievement.Details
+	if !matchText(rule.AchievementType, string(achievement.AchievementType)) ||
+		!matchText(rule.CompetitionLevel, details.CompetitionLevel) ||
+		!matchText(rule.MedalType, details.MedalType) ||
+		!matchText(rule.PublicationType, details.PublicationType) {
+		return false
+	}
+	if rule.Rank != nil && (details.Rank == nil || *details.Rank != *rule.Rank) {
+		return false
+	}
+	for field, want := range rule.CustomFields {
+		value, ok := details.CustomFields[field]
+		if !ok || value == nil || !matchText(want, fmt.Sprint(value)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate checks that a list of rules can be stored as a rule set
+func Validate(rules []models.PointsRule) error {
+	if len(rules) == 0 {
+		return fmt.Errorf("a rule set needs at least one rule")
+	}
+	names := make(map[string]bool, len(rules))
+	for i, rule := range rules {
+		name := strings.ToLower(strings.TrimSpace(rule.Name))
+		if name == "" {
+			return fmt.Errorf("rule %d has no name", i+1)
+		}
+		if names[name] {
+			return fmt.Errorf("rule name '%s' is used more than once", rule.Name)
+		}
+		names[name] = true
+		if rule.Rank != nil && *rule.Rank < 1 {
+			return fmt.Errorf("rule '%s' has an invalid rank", rule.Name)
+		}
+	}
+	return nil
+}
+
+// DefaultRules returns the weights achievements were scored with before rule sets
+// became configurable. They apply while no rule set is in force.
+func DefaultRules() []models.PointsRule {
+	rank := func(r int) *int { return &r }
+	return []models.PointsRule{
+		{Name: "Competition", Points: 100, AchievementType: "competition"},
+		{Name: "Publication", Points: 150, AchievementType: "publication"},
+		{Name: "Organization", Points: 50, AchievementType: "organization"},
+		{Name: "Certification", Points: 75, AchievementType: "certification"},
+		{Name: "Academic", Points: 25, AchievementType: "academic"},
+		{Name: "Other", Points: 10, AchievementType: "other"},
+
+		{Name: "International competition", Points: 200, AchievementType: "competition", CompetitionLevel: "international"},
+		{Name: "National competition", Points: 100, AchievementType: "competition", CompetitionLevel: "national"},
+		{Name: "Regional competition", Points: 50, AchievementType: "competition", CompetitionLevel: "regional"},
+		{Name: "Local competition", Points: 25, AchievementType: "competition", CompetitionLevel: "local"},
+
+		{Name: "First place", Points: 100, AchievementType: "competition", Rank: rank(1)},
+		{Name: "Second place", Points: 75, AchievementType: "competition", Rank: rank(2)},
+		{Name: "Third place", Points: 50, AchievementType: "competition", Rank: rank(3)},
+
+		{Name: "Journal publication", Points: 100, AchievementType: "publication", PublicationType: "journal"},
+		{Name: "Conference publication", Points: 75, AchievementType: "publication", PublicationType: "conference"},
+		{Name: "Book publication", Points: 150, AchievementType: "publication", PublicationType: "book"},
+	}
+}
+
+// matchText compares case-insensitively; an empty condition matches anything
+func matchText(want, got string) bool {
+	want = strings.TrimSpace(want)
+	return want == "" || strings.EqualFold(want, strings.TrimSpace(got))
+}
@@ -0,0 +1,102 @@
+package points
+
+import (
+	"strings"
+	"student-achievement-system/models"
+	"testing"
+)
+
+func achievement(achievementType string, details models.AchievementDetails) *models.Achievement {
+	return &models.Achievement{AchievementType: models.AchievementType(achievementType), Details: details}
+}
+
+func TestScoreDefaultRules(t *testing.T) {
+	rank := func(r int) *int { return &r }
+
+	// The default rules must score exactly like the weights they replaced
+	tests := []struct {
+		name        string
+		achievement *models.Achievement
+		want        int
+	}{
+		{"international competition won", achievement("competition", models.AchievementDetails{CompetitionLevel: "international", Rank: rank(1)}), 400},
+		{"national runner-up", achievement("competition", models.AchievementDetails{CompetitionLevel: "national", Rank: rank(2)}), 275},
+		{"local fourth place", achievement("competition", models.AchievementDetails{CompetitionLevel: "local", Rank: rank(4)}), 125},
+		{"competition without details", achievement("competition", models.AchievementDetails{}), 100},
+		{"journal publication", achievement("publication", models.AchievementDetails{PublicationType: "journal"}), 250},
+		{"book publication", achievement("publication", models.AchievementDetails{PublicationType: "book"}), 300},
+		{"organization", achievement("organization", models.AchievementDetails{}), 50},
+		{"certification", achievement("certification", models.AchievementDetails{}), 75},
+		{"academic", achievement("academic", models.AchievementDetails{}), 25},
+		{"other", achievement("other", models.AchievementDetails{}), 10},
+		{"unknown type", achievement("hobby", models.AchievementDetails{}), 0},
+	}
+
+	rules := DefaultRules()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Score(rules, tt.achievement); got.Points != tt.want {
+				t.Errorf("Score() = %d (%v), want %d", got.Points, got.Applied, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	gold := models.PointsRule{Name: "Gold", Points: 50, AchievementType: "competition", MedalType: "gold"}
+	dean := models.PointsRule{Name: "Dean's list", Points: 40, CustomFields: map[string]string{"list": "dean", "semester": "5"}}
+
+	tests := []struct {
+		name        string
+		rule        models.PointsRule
+		achievement *models.Achievement
+		want        bool
+	}{
+		{"medal matches", gold, achievement("competition", models.AchievementDetails{MedalType: "Gold"}), true},
+		{"medal differs", gold, achievement("competition", models.AchievementDetails{MedalType: "silver"}), false},
+		{"type differs", gold, achievement("academic", models.AchievementDetails{MedalType: "gold"}), false},
+		{"wildcard rule", models.PointsRule{Name: "Any", Points: 1}, achievement("other", models.AchievementDetails{}), true},
+		{"custom fields match", dean, achievement("academic", models.AchievementDetails{CustomFields: map[string]interface{}{"list": "Dean", "semester": float64(5)}}), true},
+		{"custom field missing", dean, achievement("academic", models.AchievementDetails{CustomFields: map[string]interface{}{"list": "dean"}}), false},
+		{"custom field differs", dean, achievement("academic", models.AchievementDetails{CustomFields: map[string]interface{}{"list": "rector", "semester": "5"}}), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Matches(tt.rule, tt.achievement); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScoreApplied(t *testing.T) {
+	rank := 1
+	got := Score(DefaultRules(), achievement("competition", models.AchievementDetails{CompetitionLevel: "national", Rank: &rank}))
+	if want := "Competition, National competition, First place"; strings.Join(got.Applied, ", ") != want {
+		t.Errorf("Score().Applied = %v, want %s", got.Applied, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	zero := 0
+	tests := []struct {
+		name    string
+		rules   []models.PointsRule
+		wantErr bool
+	}{
+		{"default rules", DefaultRules(), false},
+		{"no rules", nil, true},
+		{"unnamed rule", []models.PointsRule{{Points: 10}}, true},
+		{"duplicate names", []models.PointsRule{{Name: "Base", Points: 10}, {Name: "base", Points: 5}}, true},
+		{"invalid rank", []models.PointsRule{{Name: "Zero", Rank: &zero}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.rules); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
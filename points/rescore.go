@@ -0,0 +1,80 @@
+package points
+
+import (
+	"sort"
+	"student-achievement-system/models"
+)
+
+// Change is the new score of an achievement whose points or rule set version differ from
+// the ones it was scored with
+type Change struct {
+	AchievementID string `json:"achievement_id"`
+	StudentID     string `json:"student_id"`
+	Title         string `json:"title"`
+	PointsBefore  int    `json:"points_before"`
+	PointsAfter   int    `json:"points_after"`
+	VersionBefore int    `json:"version_before"`
+	VersionAfter  int    `json:"version_after"`
+}
+
+// Delta is the number of points the achievement gains, negative when it loses some
+func (c Change) Delta() int {
+	return c.PointsAfter - c.PointsBefore
+}
+
+// StudentDelta sums the changes to one student's achievements. Points cover only the
+// achievements that changed.
+type StudentDelta struct {
+	StudentID    string   `json:"student_id"`
+	PointsBefore int      `json:"points_before"`
+	PointsAfter  int      `json:"points_after"`
+	Delta        int      `json:"delta"`
+	Changes      []Change `json:"achievements"`
+}
+
+// Rescore scores the achievement with the rules of a rule set version, returning the
+// change and whether there is one. Rescoring an achievement already scored with the
+// version finds no change, so a rescoring can safely be repeated.
+func Rescore(rules []models.PointsRule, version int, achievement *models.Achievement) (Change, bool) {
+	change := Change{
+		AchievementID: achievement.ID.Hex(),
+		StudentID:     achievement.StudentID,
+		Title:         achievement.Title,
+		PointsBefore:  achievement.Points,
+		PointsAfter:   Score(rules, achievement).Points,
+		VersionBefore: achievement.PointsVersion,
+		VersionAfter:  version,
+	}
+	return change, change.PointsAfter != change.PointsBefore || change.VersionAfter != change.VersionBefore
+}
+
+// ByStudent groups changes per student, largest delta in either direction first
+func ByStudent(changes []Change) []StudentDelta {
+	index := make(map[string]int)
+	students := make([]StudentDelta, 0)
+	for _, change := range changes {
+		i, ok := index[change.StudentID]
+		if !ok {
+			i = len(students)
+			index[change.StudentID] = i
+			students = append(students, StudentDelta{StudentID: change.StudentID, Changes: []Change{}})
+		}
+		student := &students[i]
+		student.PointsBefore += change.PointsBefore
+		student.PointsAfter += change.PointsAfter
+		student.Delta += change.Delta()
+		student.Changes = append(student.Changes, change)
+	}
+
+	sort.SliceStable(students, func(i, j int) bool {
+		return abs(students[i].Delta) > abs(students[j].Delta)
+	})
+	return students
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,58 @@
+package points
+
+import (
+	"student-achievement-system/models"
+	"testing"
+)
+
+func TestRescore(t *testing.T) {
+	rules := []models.PointsRule{
+		{Name: "Competition", Points: 120, AchievementType: "competition"},
+		{Name: "Academic", Points: 25, AchievementType: "academic"},
+	}
+
+	tests := []struct {
+		name        string
+		achievement *models.Achievement
+		wantChanged bool
+		wantDelta   int
+	}{
+		{"weight raised", &models.Achievement{AchievementType: "competition", Points: 100, PointsVersion: 1}, true, 20},
+		{"same points under a new version", &models.Achievement{AchievementType: "academic", Points: 25, PointsVersion: 1}, true, 0},
+		{"already rescored", &models.Achievement{AchievementType: "competition", Points: 120, PointsVersion: 2}, false, 0},
+		{"no longer matched", &models.Achievement{AchievementType: "other", Points: 10, PointsVersion: 1}, true, -10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change, changed := Rescore(rules, 2, tt.achievement)
+			if changed != tt.wantChanged {
+				t.Fatalf("Rescore() changed = %v, want %v", changed, tt.wantChanged)
+			}
+			if change.Delta() != tt.wantDelta || change.VersionAfter != 2 {
+				t.Errorf("Rescore() = %+v, want delta %d under version 2", change, tt.wantDelta)
+			}
+		})
+	}
+}
+
+func TestByStudent(t *testing.T) {
+	changes := []Change{
+		{AchievementID: "a1", StudentID: "s1", PointsBefore: 100, PointsAfter: 120},
+		{AchievementID: "a2", StudentID: "s2", PointsBefore: 300, PointsAfter: 200},
+		{AchievementID: "a3", StudentID: "s1", PointsBefore: 50, PointsAfter: 60},
+	}
+
+	students := ByStudent(changes)
+	if len(students) != 2 {
+		t.Fatalf("ByStudent() returned %d students, want 2", len(students))
+	}
+
+	// The largest loss comes before the smaller gain
+	if got := students[0]; got.StudentID != "s2" || got.Delta != -100 || len(got.Changes) != 1 {
+		t.Errorf("ByStudent()[0] = %+v, want s2 losing 100 points", got)
+	}
+	if got := students[1]; got.StudentID != "s1" || got.PointsBefore != 150 || got.PointsAfter != 180 || got.Delta != 30 || len(got.Changes) != 2 {
+		t.Errorf("ByStudent()[1] = %+v, want s1 going from 150 to 180 points", got)
+	}
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"student-achievement-system/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AchievementCommentRepository interface {
+	FindByRefID(refID uuid.UUID, includeInternal bool) ([]models.AchievementComment, error)
+	FindByID(id uuid.UUID) (*models.AchievementComment, error)
+	FindParticipants(refID uuid.UUID) ([]uuid.UUID, error)
+	Create(comment *models.AchievementComment) error
+	Update(comment *models.AchievementComment) error
+	Delete(id uuid.UUID) error
+}
+
+type achievementCommentRepository struct {
+	db *gorm.DB
+}
+
+func NewAchievementCommentRepository(db *gorm.DB) AchievementCommentRepository {
+	return &achievementCommentRepository{db: db}
+}
+
+// FindByRefID returns the thread of an achievement, oldest first, without deleted comments
+func (r *achievementCommentRepository) FindByRefID(refID uuid.UUID, includeInternal bool) ([]models.AchievementComment, error) {
+	comments := []models.AchievementComment{}
+	query := `
+		SELECT * FROM achievement_comments
+		WHERE achievement_ref_id = ? AND deleted_at IS NULL AND (? OR internal = false)
+		ORDER BY created_at ASC
+	`
+	err := r.db.Raw(query, refID, includeInternal).Scan(&comments).Error
+	return comments, err
+}
+
+// FindByID returns the comment, or nil if there is none or it was deleted
+func (r *achievementCommentRepository) FindByID(id uuid.UUID) (*models.AchievementComment, error) {
+	var comment models.AchievementComment
+	query := `SELECT * FROM achievement_comments WHERE id = ? AND deleted_at IS NULL LIMIT 1`
+	if err := r.db.Raw(query, id).Scan(&comment).Error; err != nil || comment.ID == uuid.Nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// FindParticipants returns the users who commented on an achievement
+func (r *achievementCommentRepository) FindParticipants(refID uuid.UUID) ([]uuid.UUID, error) {
+	participants := []uuid.UUID{}
+	query := `SELECT DISTINCT author_id FROM achievement_comments WHERE achievement_ref_id = ? AND deleted_at IS NULL`
+	err := r.db.Raw(query, refID).Scan(&participants).Error
+	return participants, err
+}
+
+func (r *achievementCommentRepository) Create(comment *models.AchievementComment) error {
+	if comment.ID == uuid.Nil {
+		comment.ID = uuid.New()
+	}
+
+	query := `
+		INSERT INTO achievement_comments
+		(id, achievement_ref_id, author_id, author_name, author_role, body, attachment_url, internal, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	return r.db.Exec(query,
+		comment.ID, comment.AchievementRefID, comment.AuthorID, comment.AuthorName, comment.AuthorRole,
+		comment.Body, comment.AttachmentURL, comment.Internal, comment.CreatedAt,
+	).Error
+}
+
+func (r *achievementCommentRepository) Update(comment *models.AchievementComment) error {
+	query := `
+		UPDATE achievement_comments
+		SET body = ?, attachment_url = ?, edited_at = ?
+		WHERE id = ? AND deleted_at IS NULL
+	`
+	return r.db.Exec(query, comment.Body, comment.AttachmentURL, comment.EditedAt, comment.ID).Error
+}
+
+// Delete soft-deletes the comment so that the audit log can still refer to it
+func (r *achievementCommentRepository) Delete(id uuid.UUID) error {
+	query := `UPDATE achievement_comments SET deleted_at = NOW() WHERE id = ?`
+	return r.db.Exec(query, id).Error
+}
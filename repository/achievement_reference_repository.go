@@ -1,7 +1,9 @@
 package repository
 
 import (
+	"errors"
 	"student-achievement-system/models"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -10,11 +12,21 @@ import (
 type AchievementReferenceRepository interface {
 	FindByID(id uuid.UUID) (*models.AchievementReference, error)
 	FindByMongoID(mongoID string) (*models.AchievementReference, error)
+	FindByMongoIDs(mongoIDs []string) ([]models.AchievementReference, error)
 	FindByStudentID(studentID uuid.UUID, offset, limit int, status string) ([]models.AchievementReference, int64, error)
 	FindByStudentIDs(studentIDs []uuid.UUID, offset, limit int, status string) ([]models.AchievementReference, int64, error)
 	FindAll(offset, limit int, status string) ([]models.AchievementReference, int64, error)
 	Create(ref *models.AchievementReference) error
 	Update(ref *models.AchievementReference) error
+	Transition(ref *models.AchievementReference, from models.AchievementStatus, fromStage *uuid.UUID, unopened bool, history *models.AchievementStatusHistory) error
+	MarkViewed(refID, userID uuid.UUID) error
+	FindOverdue(submittedBefore time.Time) ([]models.AchievementReference, error)
+	MarkReminded(refID uuid.UUID, at time.Time) (bool, error)
+	MarkEscalated(refID uuid.UUID, at time.Time) (bool, error)
+	CountOverdueByAdvisor(overdueBefore, escalatedBefore time.Time) ([]OverdueCount, error)
+	FindAwaitingApproval(roleID, userID uuid.UUID, offset, limit int) ([]models.AchievementReference, int64, error)
+	FindHistory(refID uuid.UUID) ([]models.AchievementStatusHistory, error)
+	FindLatestHistory(refID uuid.UUID, from, to models.AchievementStatus) (*models.AchievementStatusHistory, error)
 	Delete(id uuid.UUID) error
 	CountByStatus() (map[string]int64, error)
 	CountByStudentID(studentID uuid.UUID) (map[string]int64, error)
@@ -24,6 +36,22 @@ type AchievementReferenceRepository interface {
 	}, error)
 }
 
+// ErrStatusChanged is returned by Transition when the achievement left the expected status
+// or approval stage in the meantime, e.g. because a concurrent request verified it first,
+// or was opened by a verifier when it had to be unopened
+var ErrStatusChanged = errors.New("achievement status has changed")
+
+// OverdueCount is the number of submissions waiting for an advisor past the verification SLA
+type OverdueCount struct {
+	LecturerID        uuid.UUID
+	LecturerNumber    string
+	FullName          string
+	Department        string
+	Overdue           int64
+	Escalated         int64
+	OldestSubmittedAt time.Time
+}
+
 type achievementReferenceRepository struct {
 	db *gorm.DB
 }
@@ -76,9 +104,40 @@ func (r *achievementReferenceRepository) FindByMongoID(mongoID string) (*models.
 	if err != nil {
 		return nil, err
 	}
+
+	r.loadCurrentStage(&ref)
+
 	return &ref, nil
 }
 
+// FindByMongoIDs returns the references of the achievements, without their approval stages
+func (r *achievementReferenceRepository) FindByMongoIDs(mongoIDs []string) ([]models.AchievementReference, error) {
+	refs := []models.AchievementReference{}
+	if len(mongoIDs) == 0 {
+		return refs, nil
+	}
+	query := `SELECT * FROM achievement_references WHERE mongo_achievement_id IN ?`
+	err := r.db.Raw(query, mongoIDs).Scan(&refs).Error
+	return refs, err
+}
+
+// loadCurrentStage loads the approval stage ref waits in, with the role deciding it
+func (r *achievementReferenceRepository) loadCurrentStage(ref *models.AchievementReference) {
+	if ref.CurrentStageID == nil || *ref.CurrentStageID == uuid.Nil {
+		return
+	}
+	var stage models.ApprovalStage
+	r.db.Raw("SELECT * FROM approval_stages WHERE id = ?", ref.CurrentStageID).Scan(&stage)
+	if stage.RoleID != nil && *stage.RoleID != uuid.Nil {
+		var role models.Role
+		r.db.Raw("SELECT * FROM roles WHERE id = ?", stage.RoleID).Scan(&role)
+		stage.Role = &role
+	}
+	if stage.ID != uuid.Nil {
+		ref.CurrentStage = &stage
+	}
+}
+
 func (r *achievementReferenceRepository) FindByStudentID(studentID uuid.UUID, offset, limit int, status string) ([]models.AchievementReference, int64, error) {
 	var refs []models.AchievementReference
 	var total int64
@@ -244,15 +303,204 @@ func (r *achievementReferenceRepository) Create(ref *models.AchievementReference
 func (r *achievementReferenceRepository) Update(ref *models.AchievementReference) error {
 	query := `
 		UPDATE achievement_references 
-		SET status = ?, verified_by = ?, verified_at = ?, updated_at = ?
+		SET status = ?, submitted_at = ?, verified_by = ?, verified_at = ?, verified_revision = ?,
+			rejection_note = ?, resubmission_count = ?, approval_chain_id = ?, current_stage_id = ?, updated_at = ?
 		WHERE id = ?
 	`
 	return r.db.Exec(query,
-		ref.Status, ref.VerifiedBy, ref.VerifiedAt,
-		ref.UpdatedAt, ref.ID,
+		ref.Status, ref.SubmittedAt, ref.VerifiedBy, ref.VerifiedAt, ref.VerifiedRevision,
+		ref.RejectionNote, ref.ResubmissionCount, ref.ApprovalChainID, ref.CurrentStageID, ref.UpdatedAt, ref.ID,
 	).Error
 }
 
+// Transition saves a status change of ref, provided it is still in status from and
+// approval stage fromStage and, if unopened is set, no verifier has opened it. It records
+// the change in the status history within the same transaction. Leaving a status forgets
+// who opened the achievement in it and the SLA reminders sent while it was in it.
+func (r *achievementReferenceRepository) Transition(
+	ref *models.AchievementReference,
+	from models.AchievementStatus,
+	fromStage *uuid.UUID,
+	unopened bool,
+	history *models.AchievementStatusHistory,
+) error {
+	if history.ID == uuid.Nil {
+		history.ID = uuid.New()
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Exec(`
+			UPDATE achievement_references
+			SET status = ?, submitted_at = ?, verified_by = ?, verified_at = ?, verified_revision = ?,
+				rejection_note = ?, resubmission_count = ?, approval_chain_id = ?, current_stage_id = ?, updated_at = ?,
+				first_viewed_at = CASE WHEN status = ? THEN first_viewed_at END,
+				first_viewed_by = CASE WHEN status = ? THEN first_viewed_by END,
+				sla_reminded_at = CASE WHEN status = ? THEN sla_reminded_at END,
+				sla_escalated_at = CASE WHEN status = ? THEN sla_escalated_at END
+			WHERE id = ? AND status = ? AND current_stage_id IS NOT DISTINCT FROM ?
+				AND (NOT ? OR first_viewed_at IS NULL)
+		`,
+			ref.Status, ref.SubmittedAt, ref.VerifiedBy, ref.VerifiedAt, ref.VerifiedRevision,
+			ref.RejectionNote, ref.ResubmissionCount, ref.ApprovalChainID, ref.CurrentStageID, ref.UpdatedAt,
+			ref.Status, ref.Status, ref.Status, ref.Status,
+			ref.ID, from, fromStage, unopened,
+		)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrStatusChanged
+		}
+
+		return tx.Exec(`
+			INSERT INTO achievement_status_history
+			(id, achievement_ref_id, old_status, new_status, changed_by, on_behalf_of, notes, snapshot, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, NOW())
+		`,
+			history.ID, history.AchievementRefID, history.OldStatus,
+			history.NewStatus, history.ChangedBy, history.OnBehalfOf, history.Notes, history.Snapshot,
+		).Error
+	})
+}
+
+// MarkViewed records that the user opened the submitted achievement, unless someone did before
+func (r *achievementReferenceRepository) MarkViewed(refID, userID uuid.UUID) error {
+	query := `
+		UPDATE achievement_references
+		SET first_viewed_at = NOW(), first_viewed_by = ?
+		WHERE id = ? AND status = ? AND first_viewed_at IS NULL
+	`
+	return r.db.Exec(query, userID, refID, models.StatusSubmitted).Error
+}
+
+// FindOverdue returns the achievements submitted before submittedBefore that are still
+// submitted and not both reminded and escalated, with the stage they wait in
+func (r *achievementReferenceRepository) FindOverdue(submittedBefore time.Time) ([]models.AchievementReference, error) {
+	refs := []models.AchievementReference{}
+	query := `
+		SELECT * FROM achievement_references
+		WHERE status = ? AND submitted_at < ? AND (sla_reminded_at IS NULL OR sla_escalated_at IS NULL)
+		ORDER BY submitted_at ASC
+	`
+	if err := r.db.Raw(query, models.StatusSubmitted, submittedBefore).Scan(&refs).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range refs {
+		r.loadCurrentStage(&refs[i])
+	}
+	return refs, nil
+}
+
+// MarkReminded records that the approvers of the submitted achievement were reminded. It
+// reports false if someone else reminded them first.
+func (r *achievementReferenceRepository) MarkReminded(refID uuid.UUID, at time.Time) (bool, error) {
+	result := r.db.Exec(`
+		UPDATE achievement_references SET sla_reminded_at = ?
+		WHERE id = ? AND status = ? AND sla_reminded_at IS NULL
+	`, at, refID, models.StatusSubmitted)
+	return result.RowsAffected > 0, result.Error
+}
+
+// MarkEscalated records that the submitted achievement was escalated. It reports false if
+// someone else escalated it first.
+func (r *achievementReferenceRepository) MarkEscalated(refID uuid.UUID, at time.Time) (bool, error) {
+	result := r.db.Exec(`
+		UPDATE achievement_references SET sla_escalated_at = ?
+		WHERE id = ? AND status = ? AND sla_escalated_at IS NULL
+	`, at, refID, models.StatusSubmitted)
+	return result.RowsAffected > 0, result.Error
+}
+
+// CountOverdueByAdvisor counts, per advisor, the submissions made before overdueBefore that
+// wait for the advisor's decision, and those among them made before escalatedBefore.
+// Submissions waiting in a stage decided by a role or a named user are not counted.
+func (r *achievementReferenceRepository) CountOverdueByAdvisor(overdueBefore, escalatedBefore time.Time) ([]OverdueCount, error) {
+	counts := []OverdueCount{}
+	query := `
+		SELECT l.id AS lecturer_id, l.lecturer_id AS lecturer_number, u.full_name, l.department,
+			COUNT(*) AS overdue,
+			COUNT(*) FILTER (WHERE ar.submitted_at < ?) AS escalated,
+			MIN(ar.submitted_at) AS oldest_submitted_at
+		FROM achievement_references ar
+		INNER JOIN students s ON s.id = ar.student_id
+		INNER JOIN lecturers l ON l.id = s.advisor_id
+		INNER JOIN users u ON u.id = l.user_id
+		LEFT JOIN approval_stages st ON st.id = ar.current_stage_id
+		WHERE ar.status = ? AND ar.submitted_at < ? AND (st.id IS NULL OR st.approver_type = ?)
+		GROUP BY l.id, l.lecturer_id, u.full_name, l.department
+		ORDER BY overdue DESC, oldest_submitted_at ASC
+	`
+	err := r.db.Raw(query, escalatedBefore, models.StatusSubmitted, overdueBefore, models.ApproverAdvisor).Scan(&counts).Error
+	return counts, err
+}
+
+// FindAwaitingApproval returns the submitted achievements waiting in an approval stage
+// decided by the role or the user, that the user has not decided yet, oldest submission first
+func (r *achievementReferenceRepository) FindAwaitingApproval(roleID, userID uuid.UUID, offset, limit int) ([]models.AchievementReference, int64, error) {
+	var refs []models.AchievementReference
+	var total int64
+
+	where := `
+		FROM achievement_references ar
+		INNER JOIN approval_stages s ON s.id = ar.current_stage_id
+		WHERE ar.status = ?
+			AND ((s.approver_type = ? AND s.role_id = ?) OR (s.approver_type = ? AND s.user_id = ?))
+			AND NOT EXISTS (
+				SELECT 1 FROM approval_decisions d
+				WHERE d.achievement_ref_id = ar.id AND d.stage_id = s.id
+					AND d.round = ar.resubmission_count AND d.decided_by = ?
+			)
+	`
+	args := []interface{}{models.StatusSubmitted, models.ApproverRole, roleID, models.ApproverUser, userID, userID}
+
+	r.db.Raw(`SELECT COUNT(*) `+where, args...).Scan(&total)
+	err := r.db.Raw(`SELECT ar.* `+where+` ORDER BY ar.submitted_at ASC LIMIT ? OFFSET ?`,
+		append(args, limit, offset)...).Scan(&refs).Error
+
+	// Load Student with User for each ref
+	for i := range refs {
+		if refs[i].StudentID != uuid.Nil {
+			var student models.Student
+			r.db.Raw("SELECT * FROM students WHERE id = ?", refs[i].StudentID).Scan(&student)
+			if student.UserID != uuid.Nil {
+				r.db.Raw("SELECT * FROM users WHERE id = ?", student.UserID).Scan(&student.User)
+			}
+			refs[i].Student = &student
+		}
+	}
+
+	return refs, total, err
+}
+
+// FindHistory returns the status changes of an achievement, oldest first
+func (r *achievementReferenceRepository) FindHistory(refID uuid.UUID) ([]models.AchievementStatusHistory, error) {
+	history := []models.AchievementStatusHistory{}
+	query := `SELECT * FROM achievement_status_history WHERE achievement_ref_id = ? ORDER BY created_at ASC`
+	err := r.db.Raw(query, refID).Scan(&history).Error
+	return history, err
+}
+
+// FindLatestHistory returns the most recent change of an achievement from one status to
+// another. An empty from matches any status. It returns nil if there was no such change.
+func (r *achievementReferenceRepository) FindLatestHistory(
+	refID uuid.UUID,
+	from, to models.AchievementStatus,
+) (*models.AchievementStatusHistory, error) {
+	var history models.AchievementStatusHistory
+	query := `
+		SELECT * FROM achievement_status_history
+		WHERE achievement_ref_id = ? AND new_status = ? AND (? = '' OR old_status = ?)
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+	err := r.db.Raw(query, refID, to, from, from).Scan(&history).Error
+	if err != nil || history.ID == uuid.Nil {
+		return nil, err
+	}
+	return &history, nil
+}
+
 func (r *achievementReferenceRepository) Delete(id uuid.UUID) error {
 	query := `DELETE FROM achievement_references WHERE id = ?`
 	return r.db.Exec(query, id).Error
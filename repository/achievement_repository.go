@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"regexp"
 	"student-achievement-system/models"
 	"time"
 
@@ -14,13 +15,33 @@ import (
 type AchievementRepository interface {
 	Create(ctx context.Context, achievement *models.Achievement) (string, error)
 	FindByID(ctx context.Context, id string) (*models.Achievement, error)
+	FindByAttachment(ctx context.Context, filename string) (*models.Achievement, error)
 	Update(ctx context.Context, id string, achievement *models.Achievement) error
+	FindBatch(ctx context.Context, filter AchievementFilter, afterID string, limit int) ([]models.Achievement, error)
+	UpdatePoints(ctx context.Context, updates []PointsUpdate) ([]string, error)
 	Delete(ctx context.Context, id string) error
 	CountByType(ctx context.Context) (map[string]int64, error)
 	CountByStatus(ctx context.Context) (map[string]int64, error)
 	CountByStudentIDAndType(ctx context.Context, studentID string) (map[string]int64, error)
 }
 
+// AchievementFilter narrows the achievements FindBatch returns; zero fields match any
+type AchievementFilter struct {
+	AchievementType string
+	CreatedFrom     *time.Time
+	CreatedTo       *time.Time // exclusive
+}
+
+// PointsUpdate rescores an achievement, provided it still has the points and rule set
+// version it was read with
+type PointsUpdate struct {
+	ID              string
+	Points          int
+	Version         int
+	PreviousPoints  int
+	PreviousVersion int
+}
+
 type achievementRepository struct {
 	collection *mongo.Collection
 }
@@ -58,6 +79,20 @@ func (r *achievementRepository) FindByID(ctx context.Context, id string) (*model
 	return &achievement, nil
 }
 
+// FindByAttachment returns the achievement an uploaded file is attached to
+func (r *achievementRepository) FindByAttachment(ctx context.Context, filename string) (*models.Achievement, error) {
+	filter := bson.M{
+		"attachments.fileUrl": bson.M{"$regex": "/" + regexp.QuoteMeta(filename) + "$"},
+	}
+
+	var achievement models.Achievement
+	if err := r.collection.FindOne(ctx, filter).Decode(&achievement); err != nil {
+		return nil, err
+	}
+
+	return &achievement, nil
+}
+
 func (r *achievementRepository) Update(ctx context.Context, id string, achievement *models.Achievement) error {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -70,7 +105,10 @@ func (r *achievementRepository) Update(ctx context.Context, id string, achieveme
 			"title":           achievement.Title,
 			"description":     achievement.Description,
 			"details":         achievement.Details,
+			"attachments":     achievement.Attachments,
 			"tags":            achievement.Tags,
+			"points":          achievement.Points,
+			"pointsVersion":   achievement.PointsVersion,
 			"updatedAt":       time.Now(),
 		},
 	}
@@ -79,6 +117,75 @@ func (r *achievementRepository) Update(ctx context.Context, id string, achieveme
 	return err
 }
 
+// FindBatch returns up to limit achievements matching the filter in ID order, starting
+// after afterID, or from the first one when it is empty
+func (r *achievementRepository) FindBatch(ctx context.Context, filter AchievementFilter, afterID string, limit int) ([]models.Achievement, error) {
+	query := bson.M{}
+	if filter.AchievementType != "" {
+		query["achievementType"] = filter.AchievementType
+	}
+	createdAt := bson.M{}
+	if filter.CreatedFrom != nil {
+		createdAt["$gte"] = *filter.CreatedFrom
+	}
+	if filter.CreatedTo != nil {
+		createdAt["$lt"] = *filter.CreatedTo
+	}
+	if len(createdAt) > 0 {
+		query["createdAt"] = createdAt
+	}
+	if afterID != "" {
+		objectID, err := primitive.ObjectIDFromHex(afterID)
+		if err != nil {
+			return nil, err
+		}
+		query["_id"] = bson.M{"$gt": objectID}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(limit))
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	achievements := []models.Achievement{}
+	if err := cursor.All(ctx, &achievements); err != nil {
+		return nil, err
+	}
+	return achievements, nil
+}
+
+// UpdatePoints applies the updates one by one and returns the IDs of the achievements it
+// changed. An achievement whose points changed since it was read is left alone, so
+// repeating the updates changes nothing.
+func (r *achievementRepository) UpdatePoints(ctx context.Context, updates []PointsUpdate) ([]string, error) {
+	updated := make([]string, 0, len(updates))
+	for _, update := range updates {
+		objectID, err := primitive.ObjectIDFromHex(update.ID)
+		if err != nil {
+			return updated, err
+		}
+
+		// Achievements scored before versions were recorded have no version at all
+		previousVersion := interface{}(update.PreviousVersion)
+		if update.PreviousVersion == 0 {
+			previousVersion = bson.M{"$in": bson.A{0, nil}}
+		}
+		result, err := r.collection.UpdateOne(ctx,
+			bson.M{"_id": objectID, "points": update.PreviousPoints, "pointsVersion": previousVersion},
+			bson.M{"$set": bson.M{"points": update.Points, "pointsVersion": update.Version}},
+		)
+		if err != nil {
+			return updated, err
+		}
+		if result.ModifiedCount > 0 {
+			updated = append(updated, update.ID)
+		}
+	}
+	return updated, nil
+}
+
 func (r *achievementRepository) Delete(ctx context.Context, id string) error {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
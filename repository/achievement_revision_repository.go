@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"student-achievement-system/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// revisionNumberAttempts bounds the retries when concurrent changes race for the same
+// revision number
+const revisionNumberAttempts = 3
+
+type AchievementRevisionRepository interface {
+	Create(ctx context.Context, revision *models.AchievementRevision) error
+	FindByAchievementID(ctx context.Context, achievementID string) ([]models.AchievementRevision, error)
+	FindByRevision(ctx context.Context, achievementID string, revision int) (*models.AchievementRevision, error)
+	FindLatest(ctx context.Context, achievementID string) (*models.AchievementRevision, error)
+	FindLatestByAchievementIDs(ctx context.Context, achievementIDs []string) (map[string]*models.AchievementRevision, error)
+}
+
+type achievementRevisionRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAchievementRevisionRepository(db *mongo.Database) AchievementRevisionRepository {
+	return &achievementRevisionRepository{
+		collection: db.Collection("achievement_revisions"),
+	}
+}
+
+// Create appends a revision, numbering it after the latest one of the same achievement.
+// Revisions are never updated or deleted.
+func (r *achievementRevisionRepository) Create(ctx context.Context, revision *models.AchievementRevision) error {
+	revision.CreatedAt = time.Now()
+
+	var err error
+	for attempt := 0; attempt < revisionNumberAttempts; attempt++ {
+		latest, findErr := r.FindLatest(ctx, revision.AchievementID)
+		if findErr != nil && !errors.Is(findErr, mongo.ErrNoDocuments) {
+			return findErr
+		}
+		revision.Revision = 1
+		if latest != nil {
+			revision.Revision = latest.Revision + 1
+		}
+
+		// The unique index on achievementId and revision rejects a number taken meanwhile
+		if _, err = r.collection.InsertOne(ctx, revision); !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// FindByAchievementID returns the revisions of an achievement, oldest first
+func (r *achievementRevisionRepository) FindByAchievementID(ctx context.Context, achievementID string) ([]models.AchievementRevision, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "revision", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"achievementId": achievementID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	revisions := make([]models.AchievementRevision, 0)
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (r *achievementRevisionRepository) FindByRevision(ctx context.Context, achievementID string, revision int) (*models.AchievementRevision, error) {
+	var result models.AchievementRevision
+	filter := bson.M{"achievementId": achievementID, "revision": revision}
+	if err := r.collection.FindOne(ctx, filter).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// FindLatest returns the current revision of an achievement, or mongo.ErrNoDocuments
+// if none was recorded
+func (r *achievementRevisionRepository) FindLatest(ctx context.Context, achievementID string) (*models.AchievementRevision, error) {
+	var result models.AchievementRevision
+	opts := options.FindOne().SetSort(bson.D{{Key: "revision", Value: -1}})
+	if err := r.collection.FindOne(ctx, bson.M{"achievementId": achievementID}, opts).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// FindLatestByAchievementIDs returns the current revision of each of the achievements,
+// keyed by achievement ID. Achievements without revisions are left out.
+func (r *achievementRevisionRepository) FindLatestByAchievementIDs(ctx context.Context, achievementIDs []string) (map[string]*models.AchievementRevision, error) {
+	latest := make(map[string]*models.AchievementRevision, len(achievementIDs))
+	if len(achievementIDs) == 0 {
+		return latest, nil
+	}
+
+	pipeline := []bson.M{
+		{"$match": bson.M{"achievementId": bson.M{"$in": achievementIDs}}},
+		{"$sort": bson.D{{Key: "achievementId", Value: 1}, {Key: "revision", Value: -1}}},
+		{"$group": bson.M{"_id": "$achievementId", "latest": bson.M{"$first": "$$ROOT"}}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Latest models.AchievementRevision `bson:"latest"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	for i := range results {
+		latest[results[i].Latest.AchievementID] = &results[i].Latest
+	}
+	return latest, nil
+}
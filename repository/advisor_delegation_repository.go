@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"student-achievement-system/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AdvisorDelegationRepository interface {
+	FindAll(lecturerID uuid.UUID, offset, limit int) ([]models.AdvisorDelegation, int64, error)
+	FindByID(id uuid.UUID) (*models.AdvisorDelegation, error)
+	FindActiveByDelegate(delegateID uuid.UUID, at time.Time) ([]models.AdvisorDelegation, error)
+	FindActiveByDelegator(delegatorID uuid.UUID, at time.Time) ([]models.AdvisorDelegation, error)
+	Create(delegation *models.AdvisorDelegation) error
+	Revoke(id, revokedBy uuid.UUID, at time.Time) (bool, error)
+}
+
+type advisorDelegationRepository struct {
+	db *gorm.DB
+}
+
+func NewAdvisorDelegationRepository(db *gorm.DB) AdvisorDelegationRepository {
+	return &advisorDelegationRepository{db: db}
+}
+
+// FindAll returns the delegations, newest first, given or received by the lecturer, or all
+// of them when lecturerID is uuid.Nil
+func (r *advisorDelegationRepository) FindAll(lecturerID uuid.UUID, offset, limit int) ([]models.AdvisorDelegation, int64, error) {
+	delegations := []models.AdvisorDelegation{}
+	var total int64
+
+	where := ` WHERE 1 = 1`
+	args := []interface{}{}
+	if lecturerID != uuid.Nil {
+		where += ` AND (delegator_id = ? OR delegate_id = ?)`
+		args = append(args, lecturerID, lecturerID)
+	}
+
+	if err := r.db.Raw(`SELECT COUNT(*) FROM advisor_delegations`+where, args...).Scan(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT * FROM advisor_delegations` + where + ` ORDER BY created_at DESC LIMIT ? OFFSET ?`
+	if err := r.db.Raw(query, append(args, limit, offset)...).Scan(&delegations).Error; err != nil {
+		return nil, 0, err
+	}
+
+	for i := range delegations {
+		r.loadLecturers(&delegations[i])
+	}
+	return delegations, total, nil
+}
+
+// FindByID returns the delegation, or nil if there is none
+func (r *advisorDelegationRepository) FindByID(id uuid.UUID) (*models.AdvisorDelegation, error) {
+	var delegation models.AdvisorDelegation
+	query := `SELECT * FROM advisor_delegations WHERE id = ? LIMIT 1`
+	if err := r.db.Raw(query, id).Scan(&delegation).Error; err != nil || delegation.ID == uuid.Nil {
+		return nil, err
+	}
+	r.loadLecturers(&delegation)
+	return &delegation, nil
+}
+
+// FindActiveByDelegate returns the delegations the lecturer may act on at the given time
+func (r *advisorDelegationRepository) FindActiveByDelegate(delegateID uuid.UUID, at time.Time) ([]models.AdvisorDelegation, error) {
+	delegations := []models.AdvisorDelegation{}
+	query := `
+		SELECT * FROM advisor_delegations
+		WHERE delegate_id = ? AND revoked_at IS NULL AND starts_at <= ? AND ends_at > ?
+		ORDER BY starts_at ASC
+	`
+	err := r.db.Raw(query, delegateID, at, at).Scan(&delegations).Error
+	return delegations, err
+}
+
+// FindActiveByDelegator returns the delegations in force for the lecturer's advisees at
+// the given time
+func (r *advisorDelegationRepository) FindActiveByDelegator(delegatorID uuid.UUID, at time.Time) ([]models.AdvisorDelegation, error) {
+	delegations := []models.AdvisorDelegation{}
+	query := `
+		SELECT * FROM advisor_delegations
+		WHERE delegator_id = ? AND revoked_at IS NULL AND starts_at <= ? AND ends_at > ?
+		ORDER BY starts_at ASC
+	`
+	err := r.db.Raw(query, delegatorID, at, at).Scan(&delegations).Error
+	return delegations, err
+}
+
+func (r *advisorDelegationRepository) Create(delegation *models.AdvisorDelegation) error {
+	if delegation.ID == uuid.Nil {
+		delegation.ID = uuid.New()
+	}
+
+	query := `
+		INSERT INTO advisor_delegations
+		(id, delegator_id, delegate_id, student_id, starts_at, ends_at, reason, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	return r.db.Exec(query,
+		delegation.ID, delegation.DelegatorID, delegation.DelegateID, delegation.StudentID,
+		delegation.StartsAt, delegation.EndsAt, delegation.Reason, delegation.CreatedBy, delegation.CreatedAt,
+	).Error
+}
+
+// Revoke ends the delegation early. It reports false if it was already revoked.
+func (r *advisorDelegationRepository) Revoke(id, revokedBy uuid.UUID, at time.Time) (bool, error) {
+	result := r.db.Exec(`
+		UPDATE advisor_delegations SET revoked_at = ?, revoked_by = ?
+		WHERE id = ? AND revoked_at IS NULL
+	`, at, revokedBy, id)
+	return result.RowsAffected > 0, result.Error
+}
+
+// loadLecturers loads the delegator and delegate with their users
+func (r *advisorDelegationRepository) loadLecturers(delegation *models.AdvisorDelegation) {
+	delegation.Delegator = r.findLecturer(delegation.DelegatorID)
+	delegation.Delegate = r.findLecturer(delegation.DelegateID)
+}
+
+func (r *advisorDelegationRepository) findLecturer(id uuid.UUID) *models.Lecturer {
+	var lecturer models.Lecturer
+	if err := r.db.Raw("SELECT * FROM lecturers WHERE id = ?", id).Scan(&lecturer).Error; err != nil || lecturer.ID == uuid.Nil {
+		return nil
+	}
+	r.db.Raw("SELECT * FROM users WHERE id = ?", lecturer.UserID).Scan(&lecturer.User)
+	return &lecturer
+}
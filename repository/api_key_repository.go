@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"student-achievement-system/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository interface {
+	Create(key *models.APIKey, permissionIDs []uuid.UUID) error
+	FindByID(id uuid.UUID) (*models.APIKey, error)
+	FindByHash(keyHash string) (*models.APIKey, error)
+	FindAll() ([]models.APIKey, error)
+	FindPermissionIDs(names []string) (map[string]uuid.UUID, error)
+	Revoke(id uuid.UUID, revokedAt time.Time) (bool, error)
+	TouchLastUsed(id uuid.UUID, ipAddress string, usedAt time.Time) error
+}
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+// Create stores the key together with its permission grants
+func (r *apiKeyRepository) Create(key *models.APIKey, permissionIDs []uuid.UUID) error {
+	// Generate UUID if not set
+	if key.ID == uuid.Nil {
+		key.ID = uuid.New()
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		query := `
+			INSERT INTO api_keys (id, name, description, prefix, key_hash, created_by, expires_at, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, NOW(), NOW())
+		`
+		if err := tx.Exec(query,
+			key.ID, key.Name, key.Description, key.Prefix, key.KeyHash, key.CreatedBy, key.ExpiresAt,
+		).Error; err != nil {
+			return err
+		}
+
+		for _, permissionID := range permissionIDs {
+			if err := tx.Exec(
+				`INSERT INTO api_key_permissions (api_key_id, permission_id) VALUES (?, ?)`,
+				key.ID, permissionID,
+			).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *apiKeyRepository) FindByID(id uuid.UUID) (*models.APIKey, error) {
+	return r.findOne(`SELECT * FROM api_keys WHERE id = ? LIMIT 1`, id)
+}
+
+func (r *apiKeyRepository) FindByHash(keyHash string) (*models.APIKey, error) {
+	return r.findOne(`SELECT * FROM api_keys WHERE key_hash = ? LIMIT 1`, keyHash)
+}
+
+func (r *apiKeyRepository) FindAll() ([]models.APIKey, error) {
+	var keys []models.APIKey
+	query := `SELECT * FROM api_keys ORDER BY created_at DESC`
+	if err := r.db.Raw(query).Scan(&keys).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range keys {
+		scopes, err := r.findScopes(keys[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		keys[i].Scopes = scopes
+	}
+	return keys, nil
+}
+
+// FindPermissionIDs maps permission names to their IDs; unknown names are absent from the result
+func (r *apiKeyRepository) FindPermissionIDs(names []string) (map[string]uuid.UUID, error) {
+	var permissions []models.Permission
+	query := `SELECT * FROM permissions WHERE name IN ?`
+	if err := r.db.Raw(query, names).Scan(&permissions).Error; err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]uuid.UUID, len(permissions))
+	for _, permission := range permissions {
+		ids[permission.Name] = permission.ID
+	}
+	return ids, nil
+}
+
+// Revoke disables a key. It returns false if the key does not exist or was already revoked.
+func (r *apiKeyRepository) Revoke(id uuid.UUID, revokedAt time.Time) (bool, error) {
+	query := `
+		UPDATE api_keys
+		SET revoked_at = ?, updated_at = NOW()
+		WHERE id = ? AND revoked_at IS NULL
+	`
+	result := r.db.Exec(query, revokedAt, id)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *apiKeyRepository) TouchLastUsed(id uuid.UUID, ipAddress string, usedAt time.Time) error {
+	query := `
+		UPDATE api_keys
+		SET last_used_at = ?, last_used_ip = ?
+		WHERE id = ?
+	`
+	return r.db.Exec(query, usedAt, ipAddress, id).Error
+}
+
+func (r *apiKeyRepository) findOne(query string, arg interface{}) (*models.APIKey, error) {
+	var key models.APIKey
+	result := r.db.Raw(query, arg).Scan(&key)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	scopes, err := r.findScopes(key.ID)
+	if err != nil {
+		return nil, err
+	}
+	key.Scopes = scopes
+	return &key, nil
+}
+
+func (r *apiKeyRepository) findScopes(keyID uuid.UUID) ([]string, error) {
+	scopes := []string{}
+	query := `
+		SELECT p.name
+		FROM permissions p
+		JOIN api_key_permissions akp ON akp.permission_id = p.id
+		WHERE akp.api_key_id = ?
+		ORDER BY p.name
+	`
+	err := r.db.Raw(query, keyID).Scan(&scopes).Error
+	return scopes, err
+}
@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"errors"
+	"student-achievement-system/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ApprovalRepository interface {
+	FindChains() ([]models.ApprovalChain, error)
+	FindChainByID(id uuid.UUID) (*models.ApprovalChain, error)
+	FindChainFor(achievementType models.AchievementType, competitionLevel string) (*models.ApprovalChain, error)
+	FindChainByScope(achievementType models.AchievementType, competitionLevel string) (*models.ApprovalChain, error)
+	CreateChain(chain *models.ApprovalChain) error
+	UpdateChain(chain *models.ApprovalChain) error
+	DeleteChain(id uuid.UUID) error
+	CountInReview(chainID uuid.UUID) (int64, error)
+	RecordDecision(decision *models.ApprovalDecision) error
+	DeleteDecision(id uuid.UUID) error
+	CountApprovals(refID, stageID uuid.UUID, round int) (int64, error)
+	FindDecisions(refID uuid.UUID) ([]models.ApprovalDecision, error)
+}
+
+// ErrAlreadyDecided is returned by RecordDecision when the approver already decided the
+// stage in this round
+var ErrAlreadyDecided = errors.New("approver already decided this stage")
+
+type approvalRepository struct {
+	db *gorm.DB
+}
+
+func NewApprovalRepository(db *gorm.DB) ApprovalRepository {
+	return &approvalRepository{db: db}
+}
+
+func (r *approvalRepository) FindChains() ([]models.ApprovalChain, error) {
+	chains := []models.ApprovalChain{}
+	query := `SELECT * FROM approval_chains ORDER BY achievement_type, competition_level`
+	if err := r.db.Raw(query).Scan(&chains).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range chains {
+		stages, err := r.findStages(chains[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		chains[i].Stages = stages
+	}
+	return chains, nil
+}
+
+// FindChainByID returns the chain with its stages, or nil if there is none
+func (r *approvalRepository) FindChainByID(id uuid.UUID) (*models.ApprovalChain, error) {
+	return r.findChain(`SELECT * FROM approval_chains WHERE id = ? LIMIT 1`, id)
+}
+
+// FindChainFor returns the chain an achievement of a type and competition level goes
+// through: the one configured for that level, else the one for the whole type. It
+// returns nil if neither exists.
+func (r *approvalRepository) FindChainFor(achievementType models.AchievementType, competitionLevel string) (*models.ApprovalChain, error) {
+	query := `
+		SELECT * FROM approval_chains
+		WHERE achievement_type = ? AND (competition_level = '' OR LOWER(competition_level) = LOWER(?))
+		ORDER BY competition_level DESC
+		LIMIT 1
+	`
+	return r.findChain(query, achievementType, competitionLevel)
+}
+
+// FindChainByScope returns the chain configured for exactly this type and competition
+// level, or nil if there is none
+func (r *approvalRepository) FindChainByScope(achievementType models.AchievementType, competitionLevel string) (*models.ApprovalChain, error) {
+	query := `SELECT * FROM approval_chains WHERE achievement_type = ? AND LOWER(competition_level) = LOWER(?) LIMIT 1`
+	return r.findChain(query, achievementType, competitionLevel)
+}
+
+func (r *approvalRepository) findChain(query string, args ...interface{}) (*models.ApprovalChain, error) {
+	var chain models.ApprovalChain
+	if err := r.db.Raw(query, args...).Scan(&chain).Error; err != nil || chain.ID == uuid.Nil {
+		return nil, err
+	}
+
+	stages, err := r.findStages(chain.ID)
+	if err != nil {
+		return nil, err
+	}
+	chain.Stages = stages
+	return &chain, nil
+}
+
+func (r *approvalRepository) findStages(chainID uuid.UUID) ([]models.ApprovalStage, error) {
+	stages := []models.ApprovalStage{}
+	query := `SELECT * FROM approval_stages WHERE chain_id = ? ORDER BY position`
+	if err := r.db.Raw(query, chainID).Scan(&stages).Error; err != nil {
+		return nil, err
+	}
+	for i := range stages {
+		r.loadRole(&stages[i])
+	}
+	return stages, nil
+}
+
+func (r *approvalRepository) loadRole(stage *models.ApprovalStage) {
+	if stage.RoleID == nil || *stage.RoleID == uuid.Nil {
+		return
+	}
+	var role models.Role
+	r.db.Raw("SELECT * FROM roles WHERE id = ?", stage.RoleID).Scan(&role)
+	if role.ID != uuid.Nil {
+		stage.Role = &role
+	}
+}
+
+// CreateChain inserts the chain and its stages within one transaction
+func (r *approvalRepository) CreateChain(chain *models.ApprovalChain) error {
+	if chain.ID == uuid.Nil {
+		chain.ID = uuid.New()
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`
+			INSERT INTO approval_chains (id, name, achievement_type, competition_level, created_at, updated_at)
+			VALUES (?, ?, ?, ?, NOW(), NOW())
+		`, chain.ID, chain.Name, chain.AchievementType, chain.CompetitionLevel).Error
+		if err != nil {
+			return err
+		}
+		return insertStages(tx, chain)
+	})
+}
+
+// UpdateChain saves the chain and replaces its stages within one transaction
+func (r *approvalRepository) UpdateChain(chain *models.ApprovalChain) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`
+			UPDATE approval_chains
+			SET name = ?, achievement_type = ?, competition_level = ?, updated_at = NOW()
+			WHERE id = ?
+		`, chain.Name, chain.AchievementType, chain.CompetitionLevel, chain.ID).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Exec(`DELETE FROM approval_stages WHERE chain_id = ?`, chain.ID).Error; err != nil {
+			return err
+		}
+		return insertStages(tx, chain)
+	})
+}
+
+func insertStages(tx *gorm.DB, chain *models.ApprovalChain) error {
+	for i := range chain.Stages {
+		stage := &chain.Stages[i]
+		if stage.ID == uuid.Nil {
+			stage.ID = uuid.New()
+		}
+		stage.ChainID = chain.ID
+		stage.Position = i + 1
+
+		err := tx.Exec(`
+			INSERT INTO approval_stages (id, chain_id, position, name, approver_type, role_id, user_id, quorum)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, stage.ID, stage.ChainID, stage.Position, stage.Name, stage.ApproverType, stage.RoleID, stage.UserID, stage.Quorum).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *approvalRepository) DeleteChain(id uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`DELETE FROM approval_stages WHERE chain_id = ?`, id).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`DELETE FROM approval_chains WHERE id = ?`, id).Error
+	})
+}
+
+// CountInReview counts the submitted achievements that are going through the chain
+func (r *approvalRepository) CountInReview(chainID uuid.UUID) (int64, error) {
+	var count int64
+	query := `SELECT COUNT(*) FROM achievement_references WHERE approval_chain_id = ? AND status = ?`
+	err := r.db.Raw(query, chainID, models.StatusSubmitted).Scan(&count).Error
+	return count, err
+}
+
+// RecordDecision saves an approver's decision, returning ErrAlreadyDecided if they
+// already decided the stage in this round
+func (r *approvalRepository) RecordDecision(decision *models.ApprovalDecision) error {
+	if decision.ID == uuid.Nil {
+		decision.ID = uuid.New()
+	}
+
+	result := r.db.Exec(`
+		INSERT INTO approval_decisions
+		(id, achievement_ref_id, stage_id, stage_name, position, round, decided_by, on_behalf_of, decision, comments, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW())
+		ON CONFLICT DO NOTHING
+	`,
+		decision.ID, decision.AchievementRefID, decision.StageID, decision.StageName, decision.Position,
+		decision.Round, decision.DecidedBy, decision.OnBehalfOf, decision.Decision, decision.Comments,
+	)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAlreadyDecided
+	}
+	return nil
+}
+
+// DeleteDecision removes a decision whose status change could not be saved
+func (r *approvalRepository) DeleteDecision(id uuid.UUID) error {
+	return r.db.Exec(`DELETE FROM approval_decisions WHERE id = ?`, id).Error
+}
+
+// CountApprovals counts the approvals a stage received in a round
+func (r *approvalRepository) CountApprovals(refID, stageID uuid.UUID, round int) (int64, error) {
+	var count int64
+	query := `
+		SELECT COUNT(*) FROM approval_decisions
+		WHERE achievement_ref_id = ? AND stage_id = ? AND round = ? AND decision = ?
+	`
+	err := r.db.Raw(query, refID, stageID, round, models.DecisionApprove).Scan(&count).Error
+	return count, err
+}
+
+// FindDecisions returns the stage decisions on an achievement, oldest first
+func (r *approvalRepository) FindDecisions(refID uuid.UUID) ([]models.ApprovalDecision, error) {
+	decisions := []models.ApprovalDecision{}
+	query := `SELECT * FROM approval_decisions WHERE achievement_ref_id = ? ORDER BY created_at ASC`
+	if err := r.db.Raw(query, refID).Scan(&decisions).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range decisions {
+		var user models.User
+		r.db.Raw("SELECT * FROM users WHERE id = ?", decisions[i].DecidedBy).Scan(&user)
+		if user.ID != uuid.Nil {
+			decisions[i].DecidedByUser = &user
+		}
+		if decisions[i].OnBehalfOf != nil {
+			var advisor models.User
+			r.db.Raw("SELECT * FROM users WHERE id = ?", decisions[i].OnBehalfOf).Scan(&advisor)
+			if advisor.ID != uuid.Nil {
+				decisions[i].OnBehalfOfUser = &advisor
+			}
+		}
+	}
+	return decisions, nil
+}
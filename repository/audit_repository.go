@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"strings"
+	"student-achievement-system/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AuditRepository interface {
+	Create(event *models.AuditEvent) error
+	FindAll(filter AuditFilter, offset, limit int) ([]models.AuditEvent, int64, error)
+}
+
+// AuditFilter narrows an audit event query; zero values match everything.
+// An Action ending in ".*" matches every action with that prefix, e.g. "user.*".
+type AuditFilter struct {
+	ActorID      *uuid.UUID
+	Action       string
+	ResourceType string
+	ResourceID   string
+	RequestID    string
+	From         *time.Time
+	To           *time.Time
+}
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+func (r *auditRepository) Create(event *models.AuditEvent) error {
+	// Generate UUID if not set
+	if event.ID == uuid.Nil {
+		event.ID = uuid.New()
+	}
+
+	query := `
+		INSERT INTO audit_events
+		(id, actor_id, actor_name, action, resource_type, resource_id, changes, ip_address, request_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, NOW())
+	`
+	return r.db.Exec(query,
+		event.ID, event.ActorID, event.ActorName, event.Action,
+		event.ResourceType, event.ResourceID, event.Changes, event.IPAddress, event.RequestID,
+	).Error
+}
+
+// FindAll returns matching events, newest first, and the total number of matches
+func (r *auditRepository) FindAll(filter AuditFilter, offset, limit int) ([]models.AuditEvent, int64, error) {
+	var events []models.AuditEvent
+	var total int64
+
+	where := ` WHERE 1 = 1`
+	args := []interface{}{}
+
+	if filter.ActorID != nil {
+		where += ` AND actor_id = ?`
+		args = append(args, *filter.ActorID)
+	}
+	if filter.Action != "" {
+		if prefix, ok := strings.CutSuffix(filter.Action, "*"); ok {
+			where += ` AND action LIKE ?`
+			args = append(args, escapeLike(prefix)+"%")
+		} else {
+			where += ` AND action = ?`
+			args = append(args, filter.Action)
+		}
+	}
+	if filter.ResourceType != "" {
+		where += ` AND resource_type = ?`
+		args = append(args, filter.ResourceType)
+	}
+	if filter.ResourceID != "" {
+		where += ` AND resource_id = ?`
+		args = append(args, filter.ResourceID)
+	}
+	if filter.RequestID != "" {
+		where += ` AND request_id = ?`
+		args = append(args, filter.RequestID)
+	}
+	if filter.From != nil {
+		where += ` AND created_at >= ?`
+		args = append(args, *filter.From)
+	}
+	if filter.To != nil {
+		where += ` AND created_at < ?`
+		args = append(args, *filter.To)
+	}
+
+	if err := r.db.Raw(`SELECT COUNT(*) FROM audit_events`+where, args...).Scan(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT * FROM audit_events` + where + ` ORDER BY created_at DESC LIMIT ? OFFSET ?`
+	err := r.db.Raw(query, append(args, limit, offset)...).Scan(&events).Error
+	return events, total, err
+}
+
+// escapeLike escapes the LIKE wildcards in a literal prefix
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
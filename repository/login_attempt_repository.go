@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"strings"
+	"student-achievement-system/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type LoginAttemptRepository interface {
+	Find(identifier string) (*models.LoginAttempt, error)
+	Reserve(identifier string, userID *uuid.UUID, until time.Time) (bool, error)
+	RecordFailure(identifier string, userID *uuid.UUID, windowStart time.Time) (int, error)
+	Lock(identifier string, until time.Time) error
+	Reset(identifier string) error
+}
+
+type loginAttemptRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginAttemptRepository(db *gorm.DB) LoginAttemptRepository {
+	return &loginAttemptRepository{db: db}
+}
+
+// UserLoginKey builds the attempt key for a known account
+func UserLoginKey(userID uuid.UUID) string {
+	return "user:" + userID.String()
+}
+
+// IdentifierLoginKey builds the attempt key for a login identifier that matched no account
+func IdentifierLoginKey(identifier string) string {
+	return "id:" + strings.ToLower(strings.TrimSpace(identifier))
+}
+
+func (r *loginAttemptRepository) Find(identifier string) (*models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	query := `SELECT * FROM login_attempts WHERE identifier = ? LIMIT 1`
+	result := r.db.Raw(query, identifier).Scan(&attempt)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &attempt, nil
+}
+
+// Reserve claims the identity for one login attempt until the given time. It returns false
+// without claiming it while the identity is locked out or another attempt holds it; the
+// claim is released by Lock or Reset.
+func (r *loginAttemptRepository) Reserve(identifier string, userID *uuid.UUID, until time.Time) (bool, error) {
+	var reserved []string
+	query := `
+		INSERT INTO login_attempts (identifier, user_id, failed_count, last_failed_at, reserved_until, updated_at)
+		VALUES (?, ?, 0, NOW(), ?, NOW())
+		ON CONFLICT (identifier) DO UPDATE
+		SET reserved_until = EXCLUDED.reserved_until, updated_at = NOW()
+		WHERE (login_attempts.locked_until IS NULL OR login_attempts.locked_until <= NOW())
+			AND (login_attempts.reserved_until IS NULL OR login_attempts.reserved_until <= NOW())
+		RETURNING identifier
+	`
+	if err := r.db.Raw(query, identifier, userID, until).Scan(&reserved).Error; err != nil {
+		return false, err
+	}
+	return len(reserved) > 0, nil
+}
+
+// RecordFailure increments the failure counter and returns the new count.
+// Failures older than windowStart are forgotten and counting starts over.
+func (r *loginAttemptRepository) RecordFailure(identifier string, userID *uuid.UUID, windowStart time.Time) (int, error) {
+	var counts []int
+	query := `
+		INSERT INTO login_attempts (identifier, user_id, failed_count, last_failed_at, updated_at)
+		VALUES (?, ?, 1, NOW(), NOW())
+		ON CONFLICT (identifier) DO UPDATE
+		SET failed_count = CASE
+				WHEN login_attempts.last_failed_at < ? THEN 1
+				ELSE login_attempts.failed_count + 1
+			END,
+			last_failed_at = NOW(),
+			updated_at = NOW()
+		RETURNING failed_count
+	`
+	if err := r.db.Raw(query, identifier, userID, windowStart).Scan(&counts).Error; err != nil {
+		return 0, err
+	}
+	if len(counts) == 0 {
+		return 0, gorm.ErrRecordNotFound
+	}
+	return counts[0], nil
+}
+
+// Lock blocks the identity until the given time and releases its reservation. It never
+// shortens a lockout already in place.
+func (r *loginAttemptRepository) Lock(identifier string, until time.Time) error {
+	query := `
+		UPDATE login_attempts
+		SET locked_until = GREATEST(locked_until, ?), reserved_until = NULL, updated_at = NOW()
+		WHERE identifier = ?
+	`
+	return r.db.Exec(query, until, identifier).Error
+}
+
+func (r *loginAttemptRepository) Reset(identifier string) error {
+	return r.db.Exec(`DELETE FROM login_attempts WHERE identifier = ?`, identifier).Error
+}
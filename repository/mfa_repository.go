@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type MFARepository interface {
+	SetSecret(userID uuid.UUID, secret string) error
+	Enable(userID uuid.UUID, step int64) error
+	Disable(userID uuid.UUID) error
+	UseStep(userID uuid.UUID, step int64) (bool, error)
+	ReplaceRecoveryCodes(userID uuid.UUID, codeHashes []string) error
+	UseRecoveryCode(userID uuid.UUID, codeHash string) (bool, error)
+	CountRecoveryCodes(userID uuid.UUID) (int64, error)
+}
+
+type mfaRepository struct {
+	db *gorm.DB
+}
+
+func NewMFARepository(db *gorm.DB) MFARepository {
+	return &mfaRepository{db: db}
+}
+
+// SetSecret stores a pending secret; MFA stays disabled until enrollment is confirmed
+func (r *mfaRepository) SetSecret(userID uuid.UUID, secret string) error {
+	query := `
+		UPDATE users
+		SET mfa_secret = ?, mfa_enabled = false, mfa_last_step = 0, updated_at = NOW()
+		WHERE id = ? AND deleted_at IS NULL
+	`
+	return r.db.Exec(query, secret, userID).Error
+}
+
+func (r *mfaRepository) Enable(userID uuid.UUID, step int64) error {
+	query := `
+		UPDATE users
+		SET mfa_enabled = true, mfa_last_step = ?, updated_at = NOW()
+		WHERE id = ? AND deleted_at IS NULL
+	`
+	return r.db.Exec(query, step, userID).Error
+}
+
+func (r *mfaRepository) Disable(userID uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		query := `
+			UPDATE users
+			SET mfa_enabled = false, mfa_secret = '', mfa_last_step = 0, updated_at = NOW()
+			WHERE id = ?
+		`
+		if err := tx.Exec(query, userID).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`DELETE FROM mfa_recovery_codes WHERE user_id = ?`, userID).Error
+	})
+}
+
+// UseStep records the time step of an accepted TOTP code.
+// It returns false if the same or a later step was already used, which rejects replayed codes.
+func (r *mfaRepository) UseStep(userID uuid.UUID, step int64) (bool, error) {
+	query := `
+		UPDATE users
+		SET mfa_last_step = ?
+		WHERE id = ? AND mfa_last_step < ?
+	`
+	result := r.db.Exec(query, step, userID, step)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *mfaRepository) ReplaceRecoveryCodes(userID uuid.UUID, codeHashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`DELETE FROM mfa_recovery_codes WHERE user_id = ?`, userID).Error; err != nil {
+			return err
+		}
+
+		query := `
+			INSERT INTO mfa_recovery_codes (id, user_id, code_hash, created_at)
+			VALUES (?, ?, ?, NOW())
+		`
+		for _, hash := range codeHashes {
+			if err := tx.Exec(query, uuid.New(), userID, hash).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *mfaRepository) UseRecoveryCode(userID uuid.UUID, codeHash string) (bool, error) {
+	query := `
+		UPDATE mfa_recovery_codes
+		SET used_at = ?
+		WHERE user_id = ? AND code_hash = ? AND used_at IS NULL
+	`
+	result := r.db.Exec(query, time.Now(), userID, codeHash)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *mfaRepository) CountRecoveryCodes(userID uuid.UUID) (int64, error) {
+	var count int64
+	query := `SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = ? AND used_at IS NULL`
+	err := r.db.Raw(query, userID).Scan(&count).Error
+	return count, err
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"student-achievement-system/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type OIDCStateRepository interface {
+	Create(state *models.OIDCState) error
+	Consume(stateHash string, now time.Time) (*models.OIDCState, error)
+	DeleteExpired(now time.Time) (int64, error)
+}
+
+type oidcStateRepository struct {
+	db *gorm.DB
+}
+
+func NewOIDCStateRepository(db *gorm.DB) OIDCStateRepository {
+	return &oidcStateRepository{db: db}
+}
+
+func (r *oidcStateRepository) Create(state *models.OIDCState) error {
+	query := `
+		INSERT INTO oidc_states (state_hash, nonce, code_verifier, expires_at, created_at)
+		VALUES (?, ?, ?, ?, NOW())
+	`
+	return r.db.Exec(query, state.StateHash, state.Nonce, state.CodeVerifier, state.ExpiresAt).Error
+}
+
+// Consume deletes and returns an unexpired state so every authorization request is redeemed at most once
+func (r *oidcStateRepository) Consume(stateHash string, now time.Time) (*models.OIDCState, error) {
+	var states []models.OIDCState
+	query := `
+		DELETE FROM oidc_states
+		WHERE state_hash = ? AND expires_at > ?
+		RETURNING *
+	`
+	if err := r.db.Raw(query, stateHash, now).Scan(&states).Error; err != nil {
+		return nil, err
+	}
+	if len(states) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &states[0], nil
+}
+
+func (r *oidcStateRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.Exec(`DELETE FROM oidc_states WHERE expires_at < ?`, now)
+	return result.RowsAffected, result.Error
+}
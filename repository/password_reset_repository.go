@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"student-achievement-system/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PasswordResetRepository interface {
+	Create(token *models.PasswordResetToken) error
+	Consume(tokenHash string, now time.Time) (uuid.UUID, error)
+	InvalidateForUser(userID uuid.UUID) error
+}
+
+type passwordResetRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordResetRepository(db *gorm.DB) PasswordResetRepository {
+	return &passwordResetRepository{db: db}
+}
+
+func (r *passwordResetRepository) Create(token *models.PasswordResetToken) error {
+	// Generate UUID if not set
+	if token.ID == uuid.Nil {
+		token.ID = uuid.New()
+	}
+
+	query := `
+		INSERT INTO password_reset_tokens (id, user_id, token_hash, expires_at, created_at)
+		VALUES (?, ?, ?, ?, NOW())
+	`
+	return r.db.Exec(query, token.ID, token.UserID, token.TokenHash, token.ExpiresAt).Error
+}
+
+// Consume marks an unused, unexpired token as used and returns its owner.
+// The update is atomic so a token can only ever be redeemed once.
+func (r *passwordResetRepository) Consume(tokenHash string, now time.Time) (uuid.UUID, error) {
+	var userIDs []uuid.UUID
+	query := `
+		UPDATE password_reset_tokens
+		SET used_at = ?
+		WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?
+		RETURNING user_id
+	`
+	if err := r.db.Raw(query, now, tokenHash, now).Scan(&userIDs).Error; err != nil {
+		return uuid.Nil, err
+	}
+	if len(userIDs) == 0 {
+		return uuid.Nil, gorm.ErrRecordNotFound
+	}
+	return userIDs[0], nil
+}
+
+func (r *passwordResetRepository) InvalidateForUser(userID uuid.UUID) error {
+	query := `
+		UPDATE password_reset_tokens
+		SET used_at = NOW()
+		WHERE user_id = ? AND used_at IS NULL
+	`
+	return r.db.Exec(query, userID).Error
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"student-achievement-system/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PermissionRepository interface {
+	FindByID(id uuid.UUID) (*models.Permission, error)
+	FindByName(name string) (*models.Permission, error)
+	FindAll() ([]models.Permission, error)
+	Create(permission *models.Permission) error
+	Update(permission *models.Permission) error
+	Delete(id uuid.UUID) error
+}
+
+type permissionRepository struct {
+	db *gorm.DB
+}
+
+func NewPermissionRepository(db *gorm.DB) PermissionRepository {
+	return &permissionRepository{db: db}
+}
+
+func (r *permissionRepository) FindByID(id uuid.UUID) (*models.Permission, error) {
+	var permission models.Permission
+	query := `SELECT * FROM permissions WHERE id = ? LIMIT 1`
+	err := r.db.Raw(query, id).Scan(&permission).Error
+	return &permission, err
+}
+
+func (r *permissionRepository) FindByName(name string) (*models.Permission, error) {
+	var permission models.Permission
+	query := `SELECT * FROM permissions WHERE name = ? LIMIT 1`
+	err := r.db.Raw(query, name).Scan(&permission).Error
+	return &permission, err
+}
+
+func (r *permissionRepository) FindAll() ([]models.Permission, error) {
+	var permissions []models.Permission
+	query := `SELECT * FROM permissions ORDER BY name`
+	err := r.db.Raw(query).Scan(&permissions).Error
+	return permissions, err
+}
+
+func (r *permissionRepository) Create(permission *models.Permission) error {
+	// Generate UUID if not set
+	if permission.ID == uuid.Nil {
+		permission.ID = uuid.New()
+	}
+
+	query := `
+		INSERT INTO permissions (id, name, description, is_built_in, created_at)
+		VALUES (?, ?, ?, false, NOW())
+	`
+	return r.db.Exec(query, permission.ID, permission.Name, permission.Description).Error
+}
+
+// Update changes the name or description of a permission. Roles holding it get a new
+// permission version because their resolved permission names change.
+func (r *permissionRepository) Update(permission *models.Permission) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		query := `
+			UPDATE permissions
+			SET name = ?, description = ?
+			WHERE id = ?
+		`
+		if err := tx.Exec(query, permission.Name, permission.Description, permission.ID).Error; err != nil {
+			return err
+		}
+		return bumpRolesWithPermission(tx, permission.ID)
+	})
+}
+
+// Delete removes a permission together with its role and API key grants
+func (r *permissionRepository) Delete(id uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := bumpRolesWithPermission(tx, id); err != nil {
+			return err
+		}
+		if err := tx.Exec(`DELETE FROM role_permissions WHERE permission_id = ?`, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`DELETE FROM api_key_permissions WHERE permission_id = ?`, id).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`DELETE FROM permissions WHERE id = ?`, id).Error
+	})
+}
+
+func bumpRolesWithPermission(tx *gorm.DB, permissionID uuid.UUID) error {
+	query := `
+		UPDATE roles
+		SET permission_version = permission_version + 1
+		WHERE id IN (SELECT role_id FROM role_permissions WHERE permission_id = ?)
+	`
+	return tx.Exec(query, permissionID).Error
+}
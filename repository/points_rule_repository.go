@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"student-achievement-system/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PointsRuleRepository interface {
+	FindAll() ([]models.PointsRuleSet, error)
+	FindByID(id uuid.UUID) (*models.PointsRuleSet, error)
+	FindByVersion(version int) (*models.PointsRuleSet, error)
+	FindInForce(at time.Time) (*models.PointsRuleSet, error)
+	Create(set *models.PointsRuleSet) error
+	Update(set *models.PointsRuleSet) error
+	Delete(id uuid.UUID) error
+}
+
+// ruleSetVersionAttempts bounds the retries when concurrent creations race for the same
+// version number
+const ruleSetVersionAttempts = 3
+
+// ErrRuleSetInForce is returned by Update and Delete when the rule set took effect, or was
+// deleted, since it was loaded
+var ErrRuleSetInForce = errors.New("rule set is already in force")
+
+// ErrRuleSetVersionTaken is returned by Create when concurrent creations kept taking the
+// next version number
+var ErrRuleSetVersionTaken = errors.New("rule set version was taken concurrently")
+
+type pointsRuleRepository struct {
+	db *gorm.DB
+}
+
+func NewPointsRuleRepository(db *gorm.DB) PointsRuleRepository {
+	return &pointsRuleRepository{db: db}
+}
+
+// FindAll returns every rule set, newest version first
+func (r *pointsRuleRepository) FindAll() ([]models.PointsRuleSet, error) {
+	sets := []models.PointsRuleSet{}
+	if err := r.db.Raw(`SELECT * FROM points_rule_sets ORDER BY version DESC`).Scan(&sets).Error; err != nil {
+		return nil, err
+	}
+	for i := range sets {
+		if err := decodeRules(&sets[i]); err != nil {
+			return nil, err
+		}
+	}
+	return sets, nil
+}
+
+// FindByID returns the rule set, or nil if there is none
+func (r *pointsRuleRepository) FindByID(id uuid.UUID) (*models.PointsRuleSet, error) {
+	return r.findSet(`SELECT * FROM points_rule_sets WHERE id = ? LIMIT 1`, id)
+}
+
+// FindByVersion returns the rule set of a version, or nil if there is none
+func (r *pointsRuleRepository) FindByVersion(version int) (*models.PointsRuleSet, error) {
+	return r.findSet(`SELECT * FROM points_rule_sets WHERE version = ? LIMIT 1`, version)
+}
+
+// FindInForce returns the rule set achievements are scored with at the given time: the
+// one that took effect last, the latest version among sets taking effect together. It
+// returns nil if no rule set is in force yet.
+func (r *pointsRuleRepository) FindInForce(at time.Time) (*models.PointsRuleSet, error) {
+	query := `
+		SELECT * FROM points_rule_sets
+		WHERE effective_from <= ?
+		ORDER BY effective_from DESC, version DESC
+		LIMIT 1
+	`
+	return r.findSet(query, at)
+}
+
+func (r *pointsRuleRepository) findSet(query string, args ...interface{}) (*models.PointsRuleSet, error) {
+	var set models.PointsRuleSet
+	if err := r.db.Raw(query, args...).Scan(&set).Error; err != nil || set.ID == uuid.Nil {
+		return nil, err
+	}
+	if err := decodeRules(&set); err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+// Create inserts the rule set as the next version
+func (r *pointsRuleRepository) Create(set *models.PointsRuleSet) error {
+	if set.ID == uuid.Nil {
+		set.ID = uuid.New()
+	}
+	rules, err := json.Marshal(set.Rules)
+	if err != nil {
+		return err
+	}
+
+	// The unique index on version leaves a number taken meanwhile without a row
+	query := `
+		INSERT INTO points_rule_sets (id, version, name, effective_from, rules, created_by, created_at, updated_at)
+		SELECT ?, COALESCE(MAX(version), 0) + 1, ?, ?, ?, ?, NOW(), NOW() FROM points_rule_sets
+		ON CONFLICT (version) DO NOTHING
+		RETURNING version
+	`
+	for attempt := 0; attempt < ruleSetVersionAttempts; attempt++ {
+		set.Version = 0
+		if err := r.db.Raw(query, set.ID, set.Name, set.EffectiveFrom, string(rules), set.CreatedBy).Scan(&set.Version).Error; err != nil {
+			return err
+		}
+		if set.Version != 0 {
+			return nil
+		}
+	}
+	return ErrRuleSetVersionTaken
+}
+
+// Update saves the name, effective date and rules of the rule set; its version never
+// changes. Rule sets in force are not updated, ErrRuleSetInForce is returned instead.
+func (r *pointsRuleRepository) Update(set *models.PointsRuleSet) error {
+	rules, err := json.Marshal(set.Rules)
+	if err != nil {
+		return err
+	}
+
+	result := r.db.Exec(`
+		UPDATE points_rule_sets
+		SET name = ?, effective_from = ?, rules = ?, updated_at = NOW()
+		WHERE id = ? AND effective_from > NOW()
+	`, set.Name, set.EffectiveFrom, string(rules), set.ID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRuleSetInForce
+	}
+	return nil
+}
+
+// Delete removes a rule set that is not in force yet, returning ErrRuleSetInForce otherwise
+func (r *pointsRuleRepository) Delete(id uuid.UUID) error {
+	result := r.db.Exec(`DELETE FROM points_rule_sets WHERE id = ? AND effective_from > NOW()`, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRuleSetInForce
+	}
+	return nil
+}
+
+// decodeRules fills Rules from the rules stored as JSON
+func decodeRules(set *models.PointsRuleSet) error {
+	set.Rules = []models.PointsRule{}
+	if set.RulesJSON == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(set.RulesJSON), &set.Rules)
+}
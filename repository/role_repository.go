@@ -14,6 +14,10 @@ type RoleRepository interface {
 	Create(role *models.Role) error
 	Update(role *models.Role) error
 	Delete(id uuid.UUID) error
+	CountUsers(id uuid.UUID) (int64, error)
+	FindPermissions(id uuid.UUID) ([]models.Permission, error)
+	GrantPermissions(id uuid.UUID, permissionIDs []uuid.UUID) (int64, error)
+	RevokePermission(id uuid.UUID, permissionID uuid.UUID) (bool, error)
 }
 
 type roleRepository struct {
@@ -63,6 +67,77 @@ func (r *roleRepository) Update(role *models.Role) error {
 }
 
 func (r *roleRepository) Delete(id uuid.UUID) error {
-	query := `DELETE FROM roles WHERE id = ?`
-	return r.db.Exec(query, id).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`DELETE FROM role_permissions WHERE role_id = ?`, id).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`DELETE FROM roles WHERE id = ?`, id).Error
+	})
+}
+
+// CountUsers counts the users, including soft-deleted ones, that still reference the role
+func (r *roleRepository) CountUsers(id uuid.UUID) (int64, error) {
+	var count int64
+	query := `SELECT COUNT(*) FROM users WHERE role_id = ?`
+	err := r.db.Raw(query, id).Scan(&count).Error
+	return count, err
+}
+
+func (r *roleRepository) FindPermissions(id uuid.UUID) ([]models.Permission, error) {
+	permissions := []models.Permission{}
+	query := `
+		SELECT p.*
+		FROM permissions p
+		INNER JOIN role_permissions rp ON rp.permission_id = p.id
+		WHERE rp.role_id = ?
+		ORDER BY p.name
+	`
+	err := r.db.Raw(query, id).Scan(&permissions).Error
+	return permissions, err
+}
+
+// GrantPermissions adds permissions to a role, skipping ones it already has, and
+// bumps the role's permission version when anything changed. It returns the number of new grants.
+func (r *roleRepository) GrantPermissions(id uuid.UUID, permissionIDs []uuid.UUID) (int64, error) {
+	var granted int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for _, permissionID := range permissionIDs {
+			result := tx.Exec(`
+				INSERT INTO role_permissions (role_id, permission_id)
+				VALUES (?, ?)
+				ON CONFLICT DO NOTHING
+			`, id, permissionID)
+			if result.Error != nil {
+				return result.Error
+			}
+			granted += result.RowsAffected
+		}
+
+		if granted == 0 {
+			return nil
+		}
+		return bumpPermissionVersion(tx, id)
+	})
+	return granted, err
+}
+
+// RevokePermission removes a permission from a role and bumps the role's permission version.
+// It returns false if the role did not have the permission.
+func (r *roleRepository) RevokePermission(id uuid.UUID, permissionID uuid.UUID) (bool, error) {
+	revoked := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Exec(`DELETE FROM role_permissions WHERE role_id = ? AND permission_id = ?`, id, permissionID)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		revoked = true
+		return bumpPermissionVersion(tx, id)
+	})
+	return revoked, err
+}
+
+// bumpPermissionVersion marks the role's permissions as changed so that
+// cached copies on other instances are reloaded
+func bumpPermissionVersion(tx *gorm.DB, roleID uuid.UUID) error {
+	return tx.Exec(`UPDATE roles SET permission_version = permission_version + 1 WHERE id = ?`, roleID).Error
 }
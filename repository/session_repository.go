@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"student-achievement-system/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type SessionRepository interface {
+	Create(session *models.Session) error
+	FindByID(id uuid.UUID) (*models.Session, error)
+	FindActiveByUserID(userID uuid.UUID) ([]models.Session, error)
+	Touch(id uuid.UUID, ipAddress, userAgent string, seenAt time.Time) error
+	Rotate(id uuid.UUID, oldJTI, newJTI string, expiresAt time.Time) (bool, error)
+	Revoke(id uuid.UUID, reason string) error
+	RevokeAllForUser(userID uuid.UUID, reason string) error
+	DeleteExpired(now time.Time) (int64, error)
+}
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) Create(session *models.Session) error {
+	// Generate UUID if not set
+	if session.ID == uuid.Nil {
+		session.ID = uuid.New()
+	}
+
+	query := `
+		INSERT INTO sessions
+		(id, user_id, refresh_jti, rotation_count, user_agent, ip_address, last_seen_at, expires_at, created_at, updated_at)
+		VALUES (?, ?, ?, 0, ?, ?, NOW(), ?, NOW(), NOW())
+	`
+	return r.db.Exec(query,
+		session.ID, session.UserID, session.RefreshJTI,
+		session.UserAgent, session.IPAddress, session.ExpiresAt,
+	).Error
+}
+
+func (r *sessionRepository) FindByID(id uuid.UUID) (*models.Session, error) {
+	var session models.Session
+	query := `SELECT * FROM sessions WHERE id = ? LIMIT 1`
+	result := r.db.Raw(query, id).Scan(&session)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &session, nil
+}
+
+func (r *sessionRepository) FindActiveByUserID(userID uuid.UUID) ([]models.Session, error) {
+	var sessions []models.Session
+	query := `
+		SELECT * FROM sessions
+		WHERE user_id = ? AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_seen_at DESC
+	`
+	err := r.db.Raw(query, userID).Scan(&sessions).Error
+	return sessions, err
+}
+
+func (r *sessionRepository) Touch(id uuid.UUID, ipAddress, userAgent string, seenAt time.Time) error {
+	query := `
+		UPDATE sessions
+		SET ip_address = ?, user_agent = ?, last_seen_at = ?
+		WHERE id = ? AND revoked_at IS NULL
+	`
+	return r.db.Exec(query, ipAddress, userAgent, seenAt, id).Error
+}
+
+// Rotate swaps the current refresh jti only if oldJTI is still the current one.
+// It returns false when another refresh already rotated the family.
+func (r *sessionRepository) Rotate(id uuid.UUID, oldJTI, newJTI string, expiresAt time.Time) (bool, error) {
+	query := `
+		UPDATE sessions
+		SET refresh_jti = ?, rotation_count = rotation_count + 1, expires_at = ?, last_seen_at = NOW(), updated_at = NOW()
+		WHERE id = ? AND refresh_jti = ? AND revoked_at IS NULL
+	`
+	result := r.db.Exec(query, newJTI, expiresAt, id, oldJTI)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *sessionRepository) Revoke(id uuid.UUID, reason string) error {
+	query := `
+		UPDATE sessions
+		SET revoked_at = NOW(), revoked_reason = ?, updated_at = NOW()
+		WHERE id = ? AND revoked_at IS NULL
+	`
+	return r.db.Exec(query, reason, id).Error
+}
+
+func (r *sessionRepository) RevokeAllForUser(userID uuid.UUID, reason string) error {
+	query := `
+		UPDATE sessions
+		SET revoked_at = NOW(), revoked_reason = ?, updated_at = NOW()
+		WHERE user_id = ? AND revoked_at IS NULL
+	`
+	return r.db.Exec(query, reason, userID).Error
+}
+
+func (r *sessionRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.Exec(`DELETE FROM sessions WHERE expires_at < ?`, now)
+	return result.RowsAffected, result.Error
+}
@@ -13,6 +13,7 @@ type StudentRepository interface {
 	FindByStudentID(studentID string) (*models.Student, error)
 	FindAll(offset, limit int) ([]models.Student, int64, error)
 	FindByAdvisorID(advisorID uuid.UUID) ([]models.Student, error)
+	FindByProgramStudy(programStudy string) ([]models.Student, error)
 	Create(student *models.Student) error
 	Update(student *models.Student) error
 	Delete(id uuid.UUID) error
@@ -163,6 +164,22 @@ func (r *studentRepository) FindByAdvisorID(advisorID uuid.UUID) ([]models.Stude
 	return students, err
 }
 
+// FindByProgramStudy returns the students of a study program, ignoring case and surrounding spaces
+func (r *studentRepository) FindByProgramStudy(programStudy string) ([]models.Student, error) {
+	var students []models.Student
+	query := `SELECT * FROM students WHERE LOWER(TRIM(program_study)) = LOWER(TRIM(?)) ORDER BY created_at DESC`
+	err := r.db.Raw(query, programStudy).Scan(&students).Error
+
+	// Load User for each student
+	for i := range students {
+		if students[i].UserID != uuid.Nil {
+			r.db.Raw("SELECT * FROM users WHERE id = ?", students[i].UserID).Scan(&students[i].User)
+		}
+	}
+
+	return students, err
+}
+
 func (r *studentRepository) Create(student *models.Student) error {
 	// Generate UUID if not set
 	if student.ID == uuid.Nil {
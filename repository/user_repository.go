@@ -12,10 +12,15 @@ type UserRepository interface {
 	FindByEmail(email string) (*models.User, error)
 	FindByUsernameOrEmail(identifier string) (*models.User, error)
 	FindByID(id uuid.UUID) (*models.User, error)
+	FindByIDWithDeleted(id uuid.UUID) (*models.User, error)
+	FindByExternalID(subject string) (*models.User, error)
+	SetAuthProvider(id uuid.UUID, provider string, externalID *string) error
 	Create(user *models.User) error
+	CreateFederated(user *models.User, student *models.Student, lecturer *models.Lecturer) error
 	Update(user *models.User) error
 	Delete(id uuid.UUID) error
 	FindAll(offset, limit int) ([]models.User, int64, error)
+	FindByRoleID(roleID uuid.UUID) ([]models.User, error)
 	GetUserPermissions(roleID uuid.UUID) ([]string, error)
 	FindDeleted(offset, limit int) ([]models.User, int64, error)
 	Restore(id uuid.UUID) error
@@ -103,6 +108,46 @@ func (r *userRepository) FindByID(id uuid.UUID) (*models.User, error) {
 	return &user, nil
 }
 
+// FindByIDWithDeleted looks a user up whether or not they have been soft-deleted
+func (r *userRepository) FindByIDWithDeleted(id uuid.UUID) (*models.User, error) {
+	var user models.User
+	err := r.db.Raw("SELECT * FROM users WHERE id = ? LIMIT 1", id).Scan(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	if user.RoleID != uuid.Nil {
+		r.db.Raw("SELECT * FROM roles WHERE id = ?", user.RoleID).Scan(&user.Role)
+	}
+	return &user, nil
+}
+
+func (r *userRepository) FindByExternalID(subject string) (*models.User, error) {
+	var user models.User
+	query := `
+		SELECT * FROM users
+		WHERE external_subject = ? AND deleted_at IS NULL
+		LIMIT 1
+	`
+	err := r.db.Raw(query, subject).Scan(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	if user.RoleID != uuid.Nil {
+		r.db.Raw("SELECT * FROM roles WHERE id = ?", user.RoleID).Scan(&user.Role)
+	}
+	return &user, nil
+}
+
+// SetAuthProvider switches the login method of an account and links or unlinks its external identity
+func (r *userRepository) SetAuthProvider(id uuid.UUID, provider string, externalID *string) error {
+	query := `
+		UPDATE users
+		SET auth_provider = ?, external_subject = ?, updated_at = NOW()
+		WHERE id = ? AND deleted_at IS NULL
+	`
+	return r.db.Exec(query, provider, externalID, id).Error
+}
+
 func (r *userRepository) Create(user *models.User) error {
 	// Generate UUID if not set
 	if user.ID == uuid.Nil {
@@ -119,15 +164,50 @@ func (r *userRepository) Create(user *models.User) error {
 	).Error
 }
 
+// CreateFederated creates an account linked to an external identity together with its
+// student or lecturer profile; nothing is created when any of the inserts fails
+func (r *userRepository) CreateFederated(user *models.User, student *models.Student, lecturer *models.Lecturer) error {
+	if user.ID == uuid.Nil {
+		user.ID = uuid.New()
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		query := `
+			INSERT INTO users (id, username, email, password_hash, full_name, role_id, is_active, auth_provider, external_subject, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, NOW(), NOW())
+		`
+		if err := tx.Exec(query,
+			user.ID, user.Username, user.Email, user.PasswordHash,
+			user.FullName, user.RoleID, user.IsActive, user.AuthProvider, user.ExternalID,
+		).Error; err != nil {
+			return err
+		}
+
+		if student != nil {
+			student.UserID = user.ID
+			if err := NewStudentRepository(tx).Create(student); err != nil {
+				return err
+			}
+		}
+		if lecturer != nil {
+			lecturer.UserID = user.ID
+			if err := NewLecturerRepository(tx).Create(lecturer); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func (r *userRepository) Update(user *models.User) error {
 	query := `
 		UPDATE users 
-		SET username = ?, email = ?, password_hash = ?, full_name = ?, role_id = ?, updated_at = ?
+		SET username = ?, email = ?, password_hash = ?, full_name = ?, role_id = ?, is_active = ?, updated_at = ?
 		WHERE id = ? AND deleted_at IS NULL
 	`
 	return r.db.Exec(query,
 		user.Username, user.Email, user.PasswordHash, user.FullName, 
-		user.RoleID, user.UpdatedAt, user.ID,
+		user.RoleID, user.IsActive, user.UpdatedAt, user.ID,
 	).Error
 }
 
@@ -163,6 +243,14 @@ func (r *userRepository) FindAll(offset, limit int) ([]models.User, int64, error
 	return users, total, err
 }
 
+// FindByRoleID returns the active users holding a role
+func (r *userRepository) FindByRoleID(roleID uuid.UUID) ([]models.User, error) {
+	users := []models.User{}
+	query := `SELECT * FROM users WHERE role_id = ? AND is_active = true AND deleted_at IS NULL ORDER BY full_name`
+	err := r.db.Raw(query, roleID).Scan(&users).Error
+	return users, err
+}
+
 func (r *userRepository) GetUserPermissions(roleID uuid.UUID) ([]string, error) {
 	var permissions []string
 	query := `
@@ -207,7 +295,26 @@ func (r *userRepository) Restore(id uuid.UUID) error {
 	return r.db.Exec(query, id).Error
 }
 
+// userOwnedTables hold rows that only mean something for their user and reference it,
+// some of them by foreign key; they go when the user is deleted permanently
+var userOwnedTables = []string{
+	"sessions",
+	"password_reset_tokens",
+	"revoked_tokens",
+	"login_attempts",
+	"mfa_recovery_codes",
+	"notifications",
+}
+
+// HardDelete permanently deletes the user together with their sessions, tokens, login
+// attempts, recovery codes and notifications
 func (r *userRepository) HardDelete(id uuid.UUID) error {
-	query := `DELETE FROM users WHERE id = ?`
-	return r.db.Exec(query, id).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range userOwnedTables {
+			if err := tx.Exec(`DELETE FROM `+table+` WHERE user_id = ?`, id).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Exec(`DELETE FROM users WHERE id = ?`, id).Error
+	})
 }
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"os"
+	"student-achievement-system/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDB connects to the PostgreSQL database named by TEST_DATABASE_URL and migrates the
+// given models; tests needing it are skipped without one. Nothing is cleaned up, so use a
+// throwaway database.
+func testDB(t *testing.T, tables ...interface{}) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(tables...); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+	return db
+}
+
+func TestHardDeleteUserWithSession(t *testing.T) {
+	db := testDB(t,
+		&models.Role{}, &models.User{}, &models.Notification{}, &models.RevokedToken{},
+		&models.Session{}, &models.PasswordResetToken{}, &models.LoginAttempt{}, &models.MFARecoveryCode{},
+	)
+
+	role := &models.Role{ID: uuid.New(), Name: "Hard delete " + uuid.NewString()[:8]}
+	if err := db.Create(role).Error; err != nil {
+		t.Fatal(err)
+	}
+	users := NewUserRepository(db)
+	user := &models.User{
+		Username:     "hard-delete-" + uuid.NewString()[:8],
+		Email:        uuid.NewString() + "@example.com",
+		PasswordHash: "unused",
+		FullName:     "Hard Delete",
+		RoleID:       role.ID,
+		IsActive:     true,
+	}
+	if err := users.Create(user); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rows referencing the user by foreign key, as left behind by signing in and resetting the password
+	session := &models.Session{UserID: user.ID, RefreshJTI: uuid.NewString(), LastSeenAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}
+	if err := db.Create(session).Error; err != nil {
+		t.Fatal(err)
+	}
+	reset := &models.PasswordResetToken{UserID: user.ID, TokenHash: uuid.NewString(), ExpiresAt: time.Now().Add(time.Hour)}
+	if err := db.Create(reset).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.LoginAttempt{Identifier: UserLoginKey(user.ID), UserID: &user.ID, FailedCount: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := users.HardDelete(user.ID); err != nil {
+		t.Fatalf("HardDelete() error = %v", err)
+	}
+
+	for _, table := range append([]string{"users"}, userOwnedTables...) {
+		column := "user_id"
+		if table == "users" {
+			column = "id"
+		}
+		var count int64
+		if err := db.Table(table).Where(column+" = ?", user.ID).Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Errorf("%d row(s) of the user left in %s", count, table)
+		}
+	}
+}
@@ -4,15 +4,26 @@ import (
 	"student-achievement-system/config"
 	"student-achievement-system/middleware"
 	"student-achievement-system/service"
+	"student-achievement-system/utils"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type Services struct {
 	AuthService         service.AuthService
+	TokenService        service.TokenService
+	SessionService      service.SessionService
+	OIDCService         service.OIDCService
+	APIKeyService       service.APIKeyService
+	RoleService         service.RoleService
+	AuditService        service.AuditService
+	ApprovalService     service.ApprovalService
+	PointsRuleService   service.PointsRuleService
 	UserService         service.UserService
 	AchievementService  service.AchievementService
 	VerificationService service.VerificationService
+	CommentService      service.CommentService
+	DelegationService   service.DelegationService
 	StudentService      service.StudentService
 	LecturerService     service.LecturerService
 	ReportService       service.ReportService
@@ -24,18 +35,36 @@ func SetupRoutes(api fiber.Router, services *Services, cfg *config.Config) {
 	// Public routes - Authentication with rate limiting
 	auth := api.Group("/auth")
 	{
-		// Apply strict rate limiting to login endpoint (5 attempts per 15 minutes)
-		auth.Post("/login", middleware.LoginRateLimiter(), services.AuthService.Login)
+		// Per-IP rate limiting on login; per-account lockout is handled by the auth service
+		loginLimiter := middleware.LoginRateLimiter(cfg.LoginRateLimitMax, cfg.LoginRateLimitWindow)
+		auth.Post("/login", loginLimiter, services.AuthService.Login)
 		auth.Post("/refresh", services.AuthService.RefreshToken)
+		auth.Post("/password/forgot", loginLimiter, services.AuthService.ForgotPassword)
+		auth.Post("/password/reset", loginLimiter, services.AuthService.ResetPassword)
+
+		// Single sign-on via the university identity provider
+		auth.Get("/oidc/login", loginLimiter, services.OIDCService.Login)
+		auth.Post("/oidc/callback", loginLimiter, services.OIDCService.Callback)
+
+		// Two-factor routes also accept the mfa_pending token issued by login
+		mfaAuth := middleware.AuthMiddleware(services.TokenService, utils.ScopeMFAPending)
+		auth.Post("/mfa/verify", mfaAuth, services.AuthService.VerifyMFA)
+		auth.Post("/mfa/enroll", mfaAuth, middleware.ForbidImpersonation(), services.AuthService.EnrollMFA)
+		auth.Post("/mfa/enroll/verify", mfaAuth, middleware.ForbidImpersonation(), services.AuthService.ConfirmMFAEnrollment)
 		
 		// Protected auth routes
-		auth.Use(middleware.AuthMiddleware(cfg))
+		auth.Use(middleware.AuthMiddleware(services.TokenService), middleware.RequireUser())
 		auth.Post("/logout", services.AuthService.Logout)
 		auth.Get("/profile", services.AuthService.GetProfile)
+		auth.Post("/password/change", middleware.ForbidImpersonation(), services.AuthService.ChangePassword)
+		auth.Post("/mfa/disable", middleware.ForbidImpersonation(), services.AuthService.DisableMFA)
+		auth.Get("/sessions", services.SessionService.ListMySessions)
+		auth.Delete("/sessions", middleware.ForbidImpersonation(), services.SessionService.RevokeMyOtherSessions)
+		auth.Delete("/sessions/:id", middleware.ForbidImpersonation(), services.SessionService.RevokeMySession)
 	}
 
 	// Protected routes - require authentication
-	api.Use(middleware.AuthMiddleware(cfg))
+	api.Use(middleware.AuthMiddleware(services.TokenService))
 	
 	// Apply general API rate limiting (100 requests per minute)
 	api.Use(middleware.APIRateLimiter())
@@ -44,6 +73,68 @@ func SetupRoutes(api fiber.Router, services *Services, cfg *config.Config) {
 	roles := api.Group("/roles")
 	{
 		roles.Get("/", services.UserService.ListRoles)
+		roles.Get("/:id", middleware.RequireUser(), middleware.RequirePermission("role:manage"), services.RoleService.GetRole)
+		roles.Post("/", middleware.RequireUser(), middleware.RequirePermission("role:manage"), services.RoleService.CreateRole)
+		roles.Put("/:id", middleware.RequireUser(), middleware.RequirePermission("role:manage"), services.RoleService.UpdateRole)
+		roles.Delete("/:id", middleware.RequireUser(), middleware.RequirePermission("role:manage"), services.RoleService.DeleteRole)
+		roles.Post("/:id/permissions", middleware.RequireUser(), middleware.RequirePermission("role:manage"), services.RoleService.GrantRolePermissions)
+		roles.Delete("/:id/permissions/:permissionId", middleware.RequireUser(), middleware.RequirePermission("role:manage"), services.RoleService.RevokeRolePermission)
+	}
+
+	// Permission management (Admin only, never with an API key)
+	permissions := api.Group("/permissions")
+	{
+		permissions.Use(middleware.RequireUser())
+		permissions.Get("/", middleware.RequirePermission("role:manage"), services.RoleService.ListPermissions)
+		permissions.Get("/:id", middleware.RequirePermission("role:manage"), services.RoleService.GetPermission)
+		permissions.Post("/", middleware.RequirePermission("role:manage"), services.RoleService.CreatePermission)
+		permissions.Put("/:id", middleware.RequirePermission("role:manage"), services.RoleService.UpdatePermission)
+		permissions.Delete("/:id", middleware.RequirePermission("role:manage"), services.RoleService.DeletePermission)
+	}
+
+	// Audit log (Admin only, never with an API key)
+	audit := api.Group("/audit")
+	{
+		audit.Use(middleware.RequireUser())
+		audit.Get("/", middleware.RequirePermission("audit:read"), services.AuditService.ListAuditEvents)
+	}
+
+	// Approval chain configuration (Admin only, never with an API key)
+	approvalChains := api.Group("/approval-chains")
+	{
+		approvalChains.Use(middleware.RequireUser())
+		approvalChains.Get("/", middleware.RequirePermission("approval:manage"), services.ApprovalService.ListApprovalChains)
+		approvalChains.Get("/:id", middleware.RequirePermission("approval:manage"), services.ApprovalService.GetApprovalChain)
+		approvalChains.Post("/", middleware.RequirePermission("approval:manage"), services.ApprovalService.CreateApprovalChain)
+		approvalChains.Put("/:id", middleware.RequirePermission("approval:manage"), services.ApprovalService.UpdateApprovalChain)
+		approvalChains.Delete("/:id", middleware.RequirePermission("approval:manage"), services.ApprovalService.DeleteApprovalChain)
+	}
+
+	// Points rule sets achievements are scored with (Admin only, never with an API key)
+	pointsRuleSets := api.Group("/points-rule-sets")
+	{
+		pointsRuleSets.Use(middleware.RequireUser())
+		pointsRuleSets.Get("/", middleware.RequirePermission("points:manage"), services.PointsRuleService.ListRuleSets)
+		pointsRuleSets.Post("/rescore", middleware.RequirePermission("points:manage"), services.PointsRuleService.RescoreAchievements)
+		pointsRuleSets.Get("/:id", middleware.RequirePermission("points:manage"), services.PointsRuleService.GetRuleSet)
+		pointsRuleSets.Post("/", middleware.RequirePermission("points:manage"), services.PointsRuleService.CreateRuleSet)
+		pointsRuleSets.Put("/:id", middleware.RequirePermission("points:manage"), services.PointsRuleService.UpdateRuleSet)
+		pointsRuleSets.Delete("/:id", middleware.RequirePermission("points:manage"), services.PointsRuleService.DeleteRuleSet)
+	}
+
+	// Achievements awaiting a decision at an approval stage of the caller's role
+	approvals := api.Group("/approvals")
+	{
+		approvals.Get("/pending", middleware.RequireUser(), middleware.RequirePermission("achievement:verify"), services.ApprovalService.ListPendingApprovals)
+	}
+
+	// Advisor delegations while a lecturer is away (lecturers for their advisees, admins for anyone)
+	delegations := api.Group("/delegations")
+	{
+		delegations.Use(middleware.RequireUser())
+		delegations.Get("/", middleware.RequirePermission("achievement:verify"), services.DelegationService.ListDelegations)
+		delegations.Post("/", middleware.RequirePermission("achievement:verify"), services.DelegationService.CreateDelegation)
+		delegations.Delete("/:id", middleware.RequirePermission("achievement:verify"), services.DelegationService.RevokeDelegation)
 	}
 
 	// User management routes (Admin only)
@@ -56,8 +147,24 @@ func SetupRoutes(api fiber.Router, services *Services, cfg *config.Config) {
 		users.Put("/:id", middleware.RequirePermission("user:update"), services.UserService.UpdateUser)
 		users.Delete("/:id", middleware.RequirePermission("user:delete"), services.UserService.DeleteUser)
 		users.Post("/:id/restore", middleware.RequirePermission("user:manage"), services.UserService.RestoreUser)
-		users.Delete("/:id/hard-delete", middleware.RequirePermission("user:manage"), services.UserService.HardDeleteUser)
+		users.Delete("/:id/hard-delete", middleware.ForbidImpersonation(), middleware.RequirePermission("user:manage"), services.UserService.HardDeleteUser)
 		users.Put("/:id/role", middleware.RequirePermission("user:manage"), services.UserService.AssignRole)
+		users.Post("/:id/unlock", middleware.RequirePermission("user:manage"), services.UserService.UnlockUser)
+		users.Post("/:id/impersonate", middleware.RequireUser(), middleware.ForbidImpersonation(), middleware.RequirePermission("user:impersonate"), services.UserService.ImpersonateUser)
+		users.Delete("/:id/mfa", middleware.RequirePermission("user:manage"), services.AuthService.ResetUserMFA)
+		users.Get("/:id/sessions", middleware.RequirePermission("user:manage"), services.SessionService.ListUserSessions)
+		users.Delete("/:id/sessions", middleware.RequirePermission("user:manage"), services.SessionService.RevokeUserSessions)
+		users.Delete("/:id/sessions/:sessionId", middleware.RequirePermission("user:manage"), services.SessionService.RevokeUserSession)
+	}
+
+	// API key management (Admin only, never with an API key)
+	apiKeys := api.Group("/api-keys")
+	{
+		apiKeys.Use(middleware.RequireUser(), middleware.ForbidImpersonation())
+		apiKeys.Get("/", middleware.RequirePermission("user:manage"), services.APIKeyService.ListAPIKeys)
+		apiKeys.Get("/:id", middleware.RequirePermission("user:manage"), services.APIKeyService.GetAPIKey)
+		apiKeys.Post("/", middleware.RequirePermission("user:manage"), services.APIKeyService.CreateAPIKey)
+		apiKeys.Delete("/:id", middleware.RequirePermission("user:manage"), services.APIKeyService.RevokeAPIKey)
 	}
 
 	// Achievement routes
@@ -71,12 +178,25 @@ func SetupRoutes(api fiber.Router, services *Services, cfg *config.Config) {
 		
 		// Status history and attachments
 		achievements.Get("/:id/history", middleware.RequirePermission("achievement:read"), services.AchievementService.GetAchievementHistory)
+		achievements.Get("/:id/resubmission-diff", middleware.RequirePermission("achievement:read"), services.AchievementService.GetResubmissionDiff)
+		achievements.Get("/:id/revisions", middleware.RequirePermission("achievement:read"), services.AchievementService.ListAchievementRevisions)
+		achievements.Get("/:id/revisions/diff", middleware.RequirePermission("achievement:read"), services.AchievementService.DiffAchievementRevisions)
+		achievements.Get("/:id/revisions/:rev", middleware.RequirePermission("achievement:read"), services.AchievementService.GetAchievementRevision)
 		achievements.Post("/:id/attachments", middleware.RequirePermission("achievement:create"), services.AchievementService.UploadAttachment)
 		
-		// Submission and verification
+		// Submission and verification; bulk routes come first so that "bulk" is not taken for an :id
+		achievements.Post("/bulk/verify", middleware.RequirePermission("achievement:verify"), services.VerificationService.BulkVerifyAchievements)
+		achievements.Post("/bulk/reject", middleware.RequirePermission("achievement:verify"), services.VerificationService.BulkRejectAchievements)
 		achievements.Post("/:id/submit", middleware.RequirePermission("achievement:update"), services.VerificationService.SubmitForVerification)
+		achievements.Post("/:id/withdraw", middleware.RequirePermission("achievement:update"), services.VerificationService.WithdrawSubmission)
 		achievements.Post("/:id/verify", middleware.RequirePermission("achievement:verify"), services.VerificationService.VerifyAchievement)
 		achievements.Post("/:id/reject", middleware.RequirePermission("achievement:verify"), services.VerificationService.RejectAchievement)
+
+		// Discussion thread between the student and those verifying the achievement
+		achievements.Get("/:id/comments", middleware.RequirePermission("achievement:read"), services.CommentService.ListComments)
+		achievements.Post("/:id/comments", middleware.RequireUser(), middleware.RequirePermission("achievement:read"), services.CommentService.CreateComment)
+		achievements.Put("/:id/comments/:commentId", middleware.RequireUser(), middleware.RequirePermission("achievement:read"), services.CommentService.UpdateComment)
+		achievements.Delete("/:id/comments/:commentId", middleware.RequireUser(), middleware.RequirePermission("achievement:read"), services.CommentService.DeleteComment)
 	}
 
 	// Student routes
@@ -105,6 +225,7 @@ func SetupRoutes(api fiber.Router, services *Services, cfg *config.Config) {
 		reports.Get("/top-students", middleware.RequirePermission("report:read"), services.ReportService.GetTopStudents)
 		reports.Get("/statistics/period", middleware.RequirePermission("report:read"), services.ReportService.GetStatisticsByPeriod)
 		reports.Get("/statistics/competition-levels", middleware.RequirePermission("report:read"), services.ReportService.GetCompetitionLevelDistribution)
+		reports.Get("/verification-sla", middleware.RequirePermission("report:read"), services.ReportService.GetVerificationSLA)
 	}
 
 	// Notification routes
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"student-achievement-system/authz"
+	"student-achievement-system/middleware"
+	"student-achievement-system/utils"
+	"student-achievement-system/workflow"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// BulkVerifyAchievements godoc
+// @Summary      Verify achievements in bulk
+// @Description  Approve up to 100 achievements at once with an optional shared comment. Each achievement is authorized and approved as by POST /achievements/{id}/verify; failures do not stop the others. Each student receives one notification summarizing their verified achievements.
+// @Tags         Verification
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body     BulkVerifyRequest  true  "Achievement IDs and shared comment"
+// @Success      200 {object} map[string]interface{} "Result of each achievement"
+// @Failure      400 {object} map[string]interface{} "Invalid request body or validation failed"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Router       /achievements/bulk/verify [post]
+func (s *verificationService) BulkVerifyAchievements(c *fiber.Ctx) error {
+	var req BulkVerifyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.ValidationErrorResponse(c, err)
+	}
+
+	return s.bulkDecide(c, req.IDs, "verified",
+		func(access *achievementAccess) (*achievementDecision, error) {
+			return s.verify(c, access, req.Comments)
+		},
+		func(decisions []*achievementDecision) {
+			s.notifyVerified(decisions, req.Comments)
+		},
+	)
+}
+
+// BulkRejectAchievements godoc
+// @Summary      Reject achievements in bulk
+// @Description  Reject up to 100 achievements at once with a shared reason. Each achievement is authorized and rejected as by POST /achievements/{id}/reject; failures do not stop the others. Each student receives one notification summarizing their rejected achievements.
+// @Tags         Verification
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body     BulkRejectRequest  true  "Achievement IDs and shared rejection reason"
+// @Success      200 {object} map[string]interface{} "Result of each achievement"
+// @Failure      400 {object} map[string]interface{} "Invalid request body or validation failed"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Router       /achievements/bulk/reject [post]
+func (s *verificationService) BulkRejectAchievements(c *fiber.Ctx) error {
+	var req BulkRejectRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.ValidationErrorResponse(c, err)
+	}
+
+	return s.bulkDecide(c, req.IDs, "rejected",
+		func(access *achievementAccess) (*achievementDecision, error) {
+			return s.reject(c, access, req.Reason)
+		},
+		func(decisions []*achievementDecision) {
+			s.notifyRejected(decisions, req.Reason)
+		},
+	)
+}
+
+// bulkDecide authorizes and decides each achievement in ids in turn, then notifies each
+// student once about all decisions on their achievements. The response holds the result
+// of every achievement in the order requested; one failing does not stop the rest.
+func (s *verificationService) bulkDecide(
+	c *fiber.Ctx,
+	ids []string,
+	outcome string,
+	decide func(access *achievementAccess) (*achievementDecision, error),
+	notify func(decisions []*achievementDecision),
+) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	results := make([]fiber.Map, 0, len(ids))
+	seen := make(map[string]bool, len(ids))
+	byStudent := make(map[uuid.UUID][]*achievementDecision)
+	students := make([]uuid.UUID, 0)
+	succeeded := 0
+
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		// SECURITY CHECK: the same authorization as for deciding the achievement alone
+		access, err := loadAchievementAccess(claims, id, authz.ActionVerify, s.achievementRefRepo, s.studentRepo, s.lecturerRepo, s.delegationRepo)
+		if err != nil {
+			results = append(results, bulkFailure(id, err))
+			continue
+		}
+		decision, err := decide(access)
+		if err != nil {
+			results = append(results, bulkFailure(id, err))
+			continue
+		}
+
+		succeeded++
+		results = append(results, fiber.Map{
+			"id":      id,
+			"success": true,
+			"message": decision.message,
+			"data":    decision.data,
+		})
+
+		studentID := access.student.ID
+		if _, ok := byStudent[studentID]; !ok {
+			students = append(students, studentID)
+		}
+		byStudent[studentID] = append(byStudent[studentID], decision)
+	}
+
+	// One notification per student rather than one per achievement
+	for _, studentID := range students {
+		notify(byStudent[studentID])
+	}
+
+	return utils.SuccessResponse(c, fmt.Sprintf("%d of %d achievements %s", succeeded, len(results), outcome), fiber.Map{
+		"total":     len(results),
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+		"results":   results,
+	})
+}
+
+// bulkFailure describes why an achievement of a bulk request was not decided, with the
+// status code the single-achievement endpoint would have responded with
+func bulkFailure(id string, err error) fiber.Map {
+	result := fiber.Map{
+		"id":      id,
+		"success": false,
+		"error":   err.Error(),
+	}
+
+	var reqErr *requestError
+	var transitionErr *workflow.TransitionError
+	switch {
+	case errors.As(err, &reqErr):
+		result["code"] = reqErr.status
+	case errors.As(err, &transitionErr):
+		result["code"] = fiber.StatusConflict
+		result["transition"] = transitionDetails(transitionErr)
+	default:
+		result["code"] = fiber.StatusForbidden
+		result["error"] = errNotPermittedMessage
+	}
+	return result
+}
@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"student-achievement-system/authz"
+	"student-achievement-system/models"
+	"student-achievement-system/workflow"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// achievementDecision is a verifier's decision taken on one achievement
+type achievementDecision struct {
+	access      *achievementAccess
+	achievement *models.Achievement
+	verifierID  uuid.UUID
+	status      models.AchievementStatus // status the achievement ended up in
+	message     string                   // what happened, for the response
+	data        fiber.Map
+}
+
+// verifierOf returns who is recorded as verifying an achievement: the lecturer profile of
+// the caller, or their user for admins and users without one
+func verifierOf(subject authz.Subject) uuid.UUID {
+	if !authz.Admin(subject, authz.Resource{}) && subject.LecturerID != uuid.Nil {
+		return subject.LecturerID
+	}
+	return subject.UserID
+}
+
+// verify approves an achievement at the approval stage it waits in, verifying it when
+// that completes the final stage. The student is not notified; callers do that, once
+// per student for bulk decisions.
+func (s *verificationService) verify(c *fiber.Ctx, access *achievementAccess, comments string) (*achievementDecision, error) {
+	id := access.ref.MongoAchievementID
+	if err := workflow.Check(access.ref, workflow.EventVerify); err != nil {
+		return nil, err
+	}
+	markViewed(s.achievementRefRepo, access)
+	verifierID := verifierOf(access.subject)
+	advisor := onBehalfOf(access)
+
+	achievement, err := s.achievementRepo.FindByID(context.Background(), id)
+	if err != nil {
+		return nil, failed(fiber.StatusNotFound, "Achievement not found")
+	}
+
+	// Pin the exact content being approved; it cannot change while submitted
+	revision, err := currentRevision(s.revisionRepo, id, achievement)
+	if err != nil {
+		return nil, failed(fiber.StatusInternalServerError, "Failed to record the verified revision")
+	}
+
+	// Only the approval completing the final stage verifies the achievement. The approval
+	// is withdrawn when the status change fails, so that the approver can try again.
+	approval, err := s.approveStage(access, comments)
+	if err != nil {
+		return nil, err
+	}
+	if approval.pending > 0 {
+		decision, err := s.advanceStage(c, access, achievement, approval, comments)
+		if err != nil {
+			s.withdrawDecision(approval.decision)
+			return nil, err
+		}
+		decision.verifierID = verifierID
+		decision.status = access.ref.Status
+		return decision, nil
+	}
+
+	transition, err := applyTransition(s.achievementRefRepo, access, workflow.EventVerify,
+		workflow.Request{Note: approvalNote(approval, comments)}, achievement,
+		func(ref *models.AchievementReference, now time.Time) {
+			ref.VerifiedAt = &now
+			ref.VerifiedBy = &verifierID
+			ref.VerifiedRevision = &revision.Revision
+			ref.CurrentStageID = nil
+		})
+	if err != nil {
+		s.withdrawDecision(approval.decision)
+		return nil, err
+	}
+
+	RecordAudit(c, s.auditRepo, "achievement.verify", "achievement", id, auditChanges(
+		fiber.Map{"status": transition.From},
+		fiber.Map{"status": transition.To, "verified_by": verifierID, "on_behalf_of": advisor, "verified_revision": revision.Revision, "comments": comments},
+	))
+
+	return &achievementDecision{
+		access:      access,
+		achievement: achievement,
+		verifierID:  verifierID,
+		status:      transition.To,
+		message:     "Achievement verified successfully",
+		data: fiber.Map{
+			"id":                id,
+			"status":            transition.To,
+			"verified_by":       verifierID,
+			"on_behalf_of":      advisor,
+			"verified_at":       access.ref.VerifiedAt,
+			"verified_revision": revision.Revision,
+			"comments":          comments,
+		},
+	}, nil
+}
+
+// reject rejects an achievement, recording the decision at the approval stage it waits
+// in. The student is not notified; callers do that, once per student for bulk decisions.
+func (s *verificationService) reject(c *fiber.Ctx, access *achievementAccess, reason string) (*achievementDecision, error) {
+	id := access.ref.MongoAchievementID
+	stage := access.ref.CurrentStage
+	markViewed(s.achievementRefRepo, access)
+	verifierID := verifierOf(access.subject)
+	advisor := onBehalfOf(access)
+
+	achievement, err := s.achievementRepo.FindByID(context.Background(), id)
+	if err != nil {
+		return nil, failed(fiber.StatusNotFound, "Achievement not found")
+	}
+
+	// Record the decision at the stage once the workflow accepts the rejection
+	rejection := workflow.Request{Note: reason}
+	var decision *models.ApprovalDecision
+	if stage != nil {
+		if _, err := fireTransition(access, workflow.EventReject, rejection); err != nil {
+			return nil, err
+		}
+		if decision, err = s.recordDecision(access, models.DecisionReject, reason); err != nil {
+			return nil, err
+		}
+	}
+
+	transition, err := applyTransition(s.achievementRefRepo, access, workflow.EventReject, rejection, achievement,
+		func(ref *models.AchievementReference, now time.Time) {
+			ref.VerifiedAt = &now
+			ref.VerifiedBy = &verifierID
+			ref.RejectionNote = reason
+			ref.CurrentStageID = nil
+		})
+	if err != nil {
+		s.withdrawDecision(decision)
+		return nil, err
+	}
+
+	RecordAudit(c, s.auditRepo, "achievement.reject", "achievement", id, auditChanges(
+		fiber.Map{"status": transition.From},
+		fiber.Map{"status": transition.To, "verified_by": verifierID, "on_behalf_of": advisor, "rejection_note": reason, "stage": stage},
+	))
+
+	return &achievementDecision{
+		access:      access,
+		achievement: achievement,
+		verifierID:  verifierID,
+		status:      transition.To,
+		message:     "Achievement rejected",
+		data: fiber.Map{
+			"id":           id,
+			"status":       transition.To,
+			"verified_by":  verifierID,
+			"on_behalf_of": advisor,
+			"verified_at":  access.ref.VerifiedAt,
+			"reason":       reason,
+			"stage":        stage,
+		},
+	}, nil
+}
+
+// notifyVerified tells a student that achievements of theirs were verified, in a single
+// notification however many there are. Stage approvals that leave the achievement
+// submitted are not reported.
+func (s *verificationService) notifyVerified(decisions []*achievementDecision, comments string) {
+	verified := make([]*achievementDecision, 0, len(decisions))
+	for _, decision := range decisions {
+		if decision.status == models.StatusVerified {
+			verified = append(verified, decision)
+		}
+	}
+	if len(verified) == 0 {
+		return
+	}
+
+	first := verified[0]
+	if len(verified) == 1 {
+		CreateNotification(
+			s.notificationRepo,
+			first.access.student.UserID,
+			models.NotificationTypeAchievementVerified,
+			"Achievement Verified",
+			fmt.Sprintf("Congratulations! Your achievement '%s' has been verified", first.achievement.Title),
+			fiber.Map{
+				"achievement_id": first.access.ref.MongoAchievementID,
+				"verified_by":    first.verifierID,
+				"comments":       comments,
+			},
+		)
+		return
+	}
+
+	CreateNotification(
+		s.notificationRepo,
+		first.access.student.UserID,
+		models.NotificationTypeAchievementVerified,
+		fmt.Sprintf("%d Achievements Verified", len(verified)),
+		fmt.Sprintf("Congratulations! %d of your achievements have been verified: %s", len(verified), decisionTitles(verified)),
+		fiber.Map{
+			"achievement_ids": decisionIDs(verified),
+			"verified_by":     first.verifierID,
+			"comments":        comments,
+		},
+	)
+}
+
+// notifyRejected tells a student that achievements of theirs were rejected, in a single
+// notification however many there are
+func (s *verificationService) notifyRejected(decisions []*achievementDecision, reason string) {
+	if len(decisions) == 0 {
+		return
+	}
+
+	first := decisions[0]
+	if len(decisions) == 1 {
+		CreateNotification(
+			s.notificationRepo,
+			first.access.student.UserID,
+			models.NotificationTypeAchievementRejected,
+			"Achievement Rejected",
+			fmt.Sprintf("Your achievement '%s' has been rejected. Reason: %s", first.achievement.Title, reason),
+			fiber.Map{
+				"achievement_id": first.access.ref.MongoAchievementID,
+				"rejection_note": reason,
+				"verified_by":    first.verifierID,
+			},
+		)
+		return
+	}
+
+	CreateNotification(
+		s.notificationRepo,
+		first.access.student.UserID,
+		models.NotificationTypeAchievementRejected,
+		fmt.Sprintf("%d Achievements Rejected", len(decisions)),
+		fmt.Sprintf("%d of your achievements have been rejected: %s. Reason: %s", len(decisions), decisionTitles(decisions), reason),
+		fiber.Map{
+			"achievement_ids": decisionIDs(decisions),
+			"rejection_note":  reason,
+			"verified_by":     first.verifierID,
+		},
+	)
+}
+
+// decisionTitles lists the titles of the decided achievements for a notification
+func decisionTitles(decisions []*achievementDecision) string {
+	titles := make([]string, 0, len(decisions))
+	for _, decision := range decisions {
+		titles = append(titles, "'"+decision.achievement.Title+"'")
+	}
+	return strings.Join(titles, ", ")
+}
+
+// decisionIDs returns the MongoDB IDs of the decided achievements
+func decisionIDs(decisions []*achievementDecision) []string {
+	ids := make([]string, 0, len(decisions))
+	for _, decision := range decisions {
+		ids = append(ids, decision.access.ref.MongoAchievementID)
+	}
+	return ids
+}
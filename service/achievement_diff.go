@@ -0,0 +1,99 @@
+package service
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"student-achievement-system/models"
+)
+
+// FieldChange is a field of an achievement that differs between two versions. Nested
+// fields are named by their path, e.g. "details.competition_level".
+type FieldChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// diffIgnoredFields are bookkeeping fields that differ between any two versions
+var diffIgnoredFields = map[string]bool{
+	"id":         true,
+	"student_id": true,
+	"created_at": true,
+	"updated_at": true,
+}
+
+// diffFields compares two versions of a document by their JSON form and returns the
+// changed fields sorted by name. Lists such as attachments and tags compare as a whole.
+func diffFields(before, after interface{}) []FieldChange {
+	beforeFields, afterFields := map[string]interface{}{}, map[string]interface{}{}
+	flattenFields("", toJSONMap(before), beforeFields)
+	flattenFields("", toJSONMap(after), afterFields)
+	return compareFields(beforeFields, afterFields)
+}
+
+// compareFields returns the fields that differ between two sets of field values, sorted by
+// name. Fields missing from after are reported with a nil After.
+func compareFields(beforeFields, afterFields map[string]interface{}) []FieldChange {
+	changes := make([]FieldChange, 0)
+	for field, value := range afterFields {
+		if old, ok := beforeFields[field]; !ok || !reflect.DeepEqual(old, value) {
+			changes = append(changes, FieldChange{Field: field, Before: beforeFields[field], After: value})
+		}
+	}
+	for field, old := range beforeFields {
+		if _, ok := afterFields[field]; !ok {
+			changes = append(changes, FieldChange{Field: field, Before: old})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// toJSONMap decodes a value, or a JSON document given as a string, to its JSON fields
+func toJSONMap(value interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	switch v := value.(type) {
+	case nil:
+		return fields
+	case string:
+		_ = json.Unmarshal([]byte(v), &fields)
+	default:
+		data, err := json.Marshal(v)
+		if err == nil {
+			_ = json.Unmarshal(data, &fields)
+		}
+	}
+	return fields
+}
+
+func flattenFields(prefix string, fields map[string]interface{}, out map[string]interface{}) {
+	for name, value := range fields {
+		if prefix == "" && diffIgnoredFields[name] {
+			continue
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			flattenFields(path, nested, out)
+			continue
+		}
+		out[path] = value
+	}
+}
+
+// achievementSnapshot serializes the content of an achievement for its status history
+func achievementSnapshot(achievement *models.Achievement) *string {
+	if achievement == nil {
+		return nil
+	}
+	data, err := json.Marshal(achievement)
+	if err != nil {
+		return nil
+	}
+	snapshot := string(data)
+	return &snapshot
+}
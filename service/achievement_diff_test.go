@@ -0,0 +1,126 @@
+package service
+
+import (
+	"encoding/json"
+	"reflect"
+	"student-achievement-system/models"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func diffAchievement() models.Achievement {
+	rank := 2
+	return models.Achievement{
+		ID:              primitive.NewObjectID(),
+		AchievementType: "competition",
+		Title:           "Programming contest",
+		Details: models.AchievementDetails{
+			CompetitionLevel: "national",
+			Rank:             &rank,
+			CustomFields:     map[string]interface{}{"team": "Alpha", "members": float64(3)},
+		},
+		Attachments: []models.Attachment{{FileName: "certificate.pdf", FileURL: "/uploads/certificate.pdf", FileType: "application/pdf"}},
+		Tags:        []string{"contest"},
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+}
+
+func changesByField(changes []FieldChange) map[string]FieldChange {
+	byField := map[string]FieldChange{}
+	for _, change := range changes {
+		byField[change.Field] = change
+	}
+	return byField
+}
+
+func TestDiffFieldsNested(t *testing.T) {
+	before := diffAchievement()
+	after := before
+	after.Details.CompetitionLevel = "international"
+	after.Details.CustomFields = map[string]interface{}{"team": "Beta", "members": float64(3)}
+
+	changes := diffFields(before, after)
+	want := []FieldChange{
+		{Field: "details.competition_level", Before: "national", After: "international"},
+		{Field: "details.custom_fields.team", Before: "Alpha", After: "Beta"},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("diffFields() = %+v, want %+v", changes, want)
+	}
+}
+
+func TestDiffFieldsRemovedKeys(t *testing.T) {
+	before := diffAchievement()
+	after := before
+	after.Details.Rank = nil
+	after.Details.CustomFields = map[string]interface{}{"team": "Alpha"}
+
+	byField := changesByField(diffFields(before, after))
+	if len(byField) != 2 {
+		t.Fatalf("diffFields() = %+v, want the rank and members removed", byField)
+	}
+	if change := byField["details.rank"]; change.Before != float64(2) || change.After != nil {
+		t.Errorf("details.rank = %+v, want 2 -> nil", change)
+	}
+	if change := byField["details.custom_fields.members"]; change.Before != float64(3) || change.After != nil {
+		t.Errorf("details.custom_fields.members = %+v, want 3 -> nil", change)
+	}
+}
+
+func TestDiffFieldsAttachments(t *testing.T) {
+	before := diffAchievement()
+	after := before
+	after.Attachments = append(append([]models.Attachment{}, before.Attachments...),
+		models.Attachment{FileName: "photo.jpg", FileURL: "/uploads/photo.jpg", FileType: "image/jpeg"})
+
+	changes := diffFields(before, after)
+	if len(changes) != 1 || changes[0].Field != "attachments" {
+		t.Fatalf("diffFields() = %+v, want only attachments", changes)
+	}
+	// Lists compare as a whole, so both versions are reported in full
+	if old, ok := changes[0].Before.([]interface{}); !ok || len(old) != 1 {
+		t.Errorf("attachments before = %v, want the single original attachment", changes[0].Before)
+	}
+	if updated, ok := changes[0].After.([]interface{}); !ok || len(updated) != 2 {
+		t.Errorf("attachments after = %v, want both attachments", changes[0].After)
+	}
+}
+
+func TestDiffFieldsSnapshot(t *testing.T) {
+	// Rejections store their content as a JSON snapshot; it compares like the struct
+	before := diffAchievement()
+	data, err := json.Marshal(before)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	after := before
+	after.ID = primitive.NewObjectID()
+	after.UpdatedAt = before.UpdatedAt.Add(time.Hour)
+	if changes := diffFields(string(data), after); len(changes) != 0 {
+		t.Errorf("diffFields() = %+v, want no changes besides bookkeeping fields", changes)
+	}
+
+	after.Title = "Programming contest finals"
+	changes := diffFields(string(data), after)
+	if len(changes) != 1 || changes[0].Field != "title" {
+		t.Errorf("diffFields() = %+v, want only the title", changes)
+	}
+}
+
+func TestAuditChangesRemovedField(t *testing.T) {
+	before := map[string]interface{}{"name": "Reviewer", "description": "Reviews", "updated_at": "yesterday"}
+	after := map[string]interface{}{"name": "Reviewer", "updated_at": "today"}
+
+	changes := auditChanges(before, after)
+	if got := changes["before"]; !reflect.DeepEqual(got, fiber.Map{"description": "Reviews"}) {
+		t.Errorf("before = %v, want only the removed description", got)
+	}
+	if got := changes["after"]; !reflect.DeepEqual(got, fiber.Map{"description": nil}) {
+		t.Errorf("after = %v, want the description removed", got)
+	}
+}
@@ -2,10 +2,12 @@ package service
 
 import (
 	"context"
+	"student-achievement-system/authz"
 	"student-achievement-system/database"
 	"student-achievement-system/middleware"
 	"student-achievement-system/models"
 	"student-achievement-system/utils"
+	"student-achievement-system/workflow"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -13,7 +15,7 @@ import (
 
 // GetAchievementHistory godoc
 // @Summary      Get achievement status history
-// @Description  Get the status change history of an achievement
+// @Description  Get the status change history of an achievement, with the decisions taken at each approval stage, the rejections it received and the revised versions resubmitted after them. Decisions a lecturer took standing in for the advisor name the advisor under on_behalf_of.
 // @Tags         Achievements
 // @Accept       json
 // @Produce      json
@@ -21,29 +23,35 @@ import (
 // @Param        id  path     string  true  "Achievement ID (MongoDB ObjectID)"
 // @Success      200 {object} map[string]interface{} "Achievement status history"
 // @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
 // @Failure      404 {object} map[string]interface{} "Achievement not found"
 // @Failure      500 {object} map[string]interface{} "Internal server error"
 // @Router       /achievements/{id}/history [get]
 func (s *achievementService) GetAchievementHistory(c *fiber.Ctx) error {
 	id := c.Params("id")
 
-	// Get achievement reference
-	achievementRef, err := s.achievementRefRepo.FindByMongoID(id)
-	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusNotFound, "Achievement not found")
+	access, err := authorizeAchievement(c, authz.ActionRead, s.achievementRefRepo, s.studentRepo, s.lecturerRepo, s.delegationRepo)
+	if access == nil {
+		return err
 	}
+	markViewed(s.achievementRefRepo, access)
+	achievementRef := access.ref
 
 	// Get status history from database
 	type HistoryWithUser struct {
 		models.AchievementStatusHistory
-		ChangedByName  string `json:"changed_by_name"`
-		ChangedByEmail string `json:"changed_by_email"`
+		ChangedByName   string `json:"changed_by_name"`
+		ChangedByEmail  string `json:"changed_by_email"`
+		OnBehalfOfName  string `json:"on_behalf_of_name"`
+		OnBehalfOfEmail string `json:"on_behalf_of_email"`
 	}
 
 	query := `
-		SELECT ash.*, u.full_name as changed_by_name, u.email as changed_by_email
+		SELECT ash.*, u.full_name as changed_by_name, u.email as changed_by_email,
+			ob.full_name as on_behalf_of_name, ob.email as on_behalf_of_email
 		FROM achievement_status_history ash
 		LEFT JOIN users u ON ash.changed_by = u.id
+		LEFT JOIN users ob ON ash.on_behalf_of = ob.id
 		WHERE ash.achievement_ref_id = ?
 		ORDER BY ash.created_at ASC
 	`
@@ -53,30 +61,94 @@ func (s *achievementService) GetAchievementHistory(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to get achievement history")
 	}
 
+	decisions, err := s.approvalRepo.FindDecisions(achievementRef.ID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to get approval decisions")
+	}
+
 	// Format response
 	historyResponse := make([]fiber.Map, 0)
+	rejections := make([]fiber.Map, 0)
+	resubmissions := make([]fiber.Map, 0)
 	for _, h := range historyWithUsers {
+		event := historyEvent(h.AchievementStatusHistory)
+		changedBy := fiber.Map{
+			"id":    h.ChangedBy,
+			"name":  h.ChangedByName,
+			"email": h.ChangedByEmail,
+		}
+		if h.OnBehalfOf != nil {
+			// A lecturer standing in for the advisor took the decision
+			changedBy["on_behalf_of"] = fiber.Map{
+				"id":    h.OnBehalfOf,
+				"name":  h.OnBehalfOfName,
+				"email": h.OnBehalfOfEmail,
+			}
+		}
+
 		historyResponse = append(historyResponse, fiber.Map{
 			"id":         h.ID,
+			"event":      event,
 			"old_status": h.OldStatus,
 			"new_status": h.NewStatus,
-			"changed_by": fiber.Map{
-				"id":    h.ChangedBy,
-				"name":  h.ChangedByName,
-				"email": h.ChangedByEmail,
-			},
+			"changed_by": changedBy,
 			"notes":      h.Notes,
 			"created_at": h.CreatedAt,
 		})
+
+		switch event {
+		case "reject":
+			rejections = append(rejections, fiber.Map{
+				"rejection_note": h.Notes,
+				"rejected_by":    changedBy,
+				"rejected_at":    h.CreatedAt,
+			})
+		case "resubmit":
+			resubmissions = append(resubmissions, fiber.Map{
+				"resubmission":   len(resubmissions) + 1,
+				"resubmitted_by": changedBy,
+				"resubmitted_at": h.CreatedAt,
+			})
+		}
 	}
 
 	return utils.SuccessResponse(c, "Achievement history retrieved successfully", fiber.Map{
-		"achievement_id": id,
-		"current_status": achievementRef.Status,
-		"history":        historyResponse,
+		"achievement_id":     id,
+		"current_status":     achievementRef.Status,
+		"resubmission_count": achievementRef.ResubmissionCount,
+		"current_stage":      achievementRef.CurrentStage,
+		"history":            historyResponse,
+		"approvals":          decisions,
+		"rejections":         rejections,
+		"resubmissions":      resubmissions,
 	})
 }
 
+// historyEvent names the change a status history entry records
+func historyEvent(h models.AchievementStatusHistory) string {
+	switch h.NewStatus {
+	case models.StatusSubmitted:
+		if h.OldStatus == models.StatusRejected {
+			return "resubmit"
+		}
+		if h.OldStatus == models.StatusSubmitted {
+			return string(workflow.EventApprove)
+		}
+		return string(workflow.EventSubmit)
+	case models.StatusVerified:
+		return string(workflow.EventVerify)
+	case models.StatusRejected:
+		return string(workflow.EventReject)
+	case models.StatusDraft:
+		if h.OldStatus == models.StatusSubmitted {
+			return string(workflow.EventWithdraw)
+		}
+	case models.StatusDeleted:
+		return string(workflow.EventDelete)
+	}
+	return string(h.NewStatus)
+}
+
 // UploadAttachment godoc
 // @Summary      Upload attachment to achievement
 // @Description  Upload and attach a file to an existing achievement
@@ -91,6 +163,7 @@ func (s *achievementService) GetAchievementHistory(c *fiber.Ctx) error {
 // @Failure      401 {object} map[string]interface{} "Unauthorized"
 // @Failure      403 {object} map[string]interface{} "Forbidden"
 // @Failure      404 {object} map[string]interface{} "Achievement not found"
+// @Failure      409 {object} map[string]interface{} "Achievement cannot be edited in its current status"
 // @Failure      500 {object} map[string]interface{} "Internal server error"
 // @Router       /achievements/{id}/attachments [post]
 func (s *achievementService) UploadAttachment(c *fiber.Ctx) error {
@@ -100,21 +173,13 @@ func (s *achievementService) UploadAttachment(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not authenticated")
 	}
 
-	// Get achievement reference to check ownership
-	achievementRef, err := s.achievementRefRepo.FindByMongoID(id)
-	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusNotFound, "Achievement not found")
-	}
-
-	// Get student info to verify ownership
-	student, err := s.studentRepo.FindByID(achievementRef.StudentID)
-	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusNotFound, "Student not found")
-	}
-
 	// SECURITY CHECK: Only the owner or admin can upload attachments
-	if claims.RoleName != "Admin" && student.UserID != claims.UserID {
-		return utils.ErrorResponse(c, fiber.StatusForbidden, "You can only upload attachments to your own achievements")
+	access, err := authorizeAchievement(c, authz.ActionAttach, s.achievementRefRepo, s.studentRepo, s.lecturerRepo, s.delegationRepo)
+	if access == nil {
+		return err
+	}
+	if err := workflow.CanEdit(access.ref); err != nil {
+		return transitionFailed(c, err)
 	}
 
 	// Upload file using middleware
@@ -149,6 +214,9 @@ func (s *achievementService) UploadAttachment(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update achievement")
 	}
 
+	recordRevision(c, s.revisionRepo, id, achievement, "attach_file")
+	RecordAudit(c, s.auditRepo, "achievement.attach_file", "achievement", id, auditChanges(nil, newAttachment))
+
 	utils.GlobalLogger.Info("Attachment uploaded successfully", map[string]interface{}{
 		"user_id":        claims.UserID,
 		"achievement_id": id,
@@ -163,3 +231,71 @@ func (s *achievementService) UploadAttachment(c *fiber.Ctx) error {
 		"total_attachments": len(achievement.Attachments),
 	})
 }
+
+// GetResubmissionDiff godoc
+// @Summary      Compare resubmission with rejected version
+// @Description  Get the fields that changed between the version of an achievement that was last rejected and the revised version resubmitted after it. While the student is still revising, the rejected version is compared with the current content.
+// @Tags         Achievements
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path     string  true  "Achievement ID (MongoDB ObjectID)"
+// @Success      200 {object} map[string]interface{} "Field-level diff"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      404 {object} map[string]interface{} "Achievement not found or never rejected"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /achievements/{id}/resubmission-diff [get]
+func (s *achievementService) GetResubmissionDiff(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	access, err := authorizeAchievement(c, authz.ActionRead, s.achievementRefRepo, s.studentRepo, s.lecturerRepo, s.delegationRepo)
+	if access == nil {
+		return err
+	}
+	markViewed(s.achievementRefRepo, access)
+	ref := access.ref
+
+	rejection, err := s.achievementRefRepo.FindLatestHistory(ref.ID, "", models.StatusRejected)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to get achievement history")
+	}
+	if rejection == nil || rejection.Snapshot == nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "No rejected version of this achievement was found")
+	}
+
+	resubmission, err := s.achievementRefRepo.FindLatestHistory(ref.ID, models.StatusRejected, models.StatusSubmitted)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to get achievement history")
+	}
+
+	response := fiber.Map{
+		"achievement_id": id,
+		"rejection": fiber.Map{
+			"rejection_note": rejection.Notes,
+			"rejected_by":    rejection.ChangedBy,
+			"rejected_at":    rejection.CreatedAt,
+		},
+	}
+
+	var revised interface{}
+	if resubmission != nil && resubmission.Snapshot != nil && resubmission.CreatedAt.After(rejection.CreatedAt) {
+		revised = *resubmission.Snapshot
+		response["compared_with"] = "resubmission"
+		response["resubmission"] = fiber.Map{
+			"resubmission":   ref.ResubmissionCount,
+			"resubmitted_at": resubmission.CreatedAt,
+		}
+	} else {
+		// Not resubmitted yet: show the revisions made so far
+		achievement, err := s.achievementRepo.FindByID(context.Background(), id)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "Achievement not found")
+		}
+		revised = achievement
+		response["compared_with"] = "current"
+	}
+
+	response["changes"] = diffFields(*rejection.Snapshot, revised)
+	return utils.SuccessResponse(c, "Resubmission diff retrieved successfully", response)
+}
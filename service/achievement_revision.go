@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"student-achievement-system/authz"
+	"student-achievement-system/middleware"
+	"student-achievement-system/models"
+	"student-achievement-system/repository"
+	"student-achievement-system/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// recordRevision appends the content of an achievement to its revision history after it
+// changed. Failures are logged rather than returned, the change itself has already been
+// made; currentRevision records the content before it is verified.
+func recordRevision(
+	c *fiber.Ctx,
+	revisionRepo repository.AchievementRevisionRepository,
+	achievementID string,
+	achievement *models.Achievement,
+	change string,
+) {
+	revision := &models.AchievementRevision{
+		AchievementID: achievementID,
+		Change:        change,
+		Content:       *achievement,
+	}
+	if claims := middleware.GetUserFromContext(c); claims != nil {
+		revision.AuthorID = claims.UserID.String()
+		revision.AuthorName = claims.Username
+		if claims.Impersonating() {
+			revision.AuthorName = claims.Actor.Username + " as " + claims.Username
+		}
+	}
+
+	if err := revisionRepo.Create(context.Background(), revision); err != nil {
+		utils.GlobalLogger.Error("Failed to record achievement revision", err, map[string]interface{}{
+			"achievement_id": achievementID,
+			"change":         change,
+		})
+	}
+}
+
+// currentRevision returns the revision holding the current content of an achievement.
+// Achievements created before revisions were kept get their content recorded as a
+// baseline first, and content that changed without a revision being recorded, e.g.
+// because recording it failed, is recorded before it is returned.
+func currentRevision(
+	revisionRepo repository.AchievementRevisionRepository,
+	achievementID string,
+	achievement *models.Achievement,
+) (*models.AchievementRevision, error) {
+	change := "baseline"
+	latest, err := revisionRepo.FindLatest(context.Background(), achievementID)
+	if err == nil {
+		if len(diffFields(latest.Content, *achievement)) == 0 {
+			return latest, nil
+		}
+		change = "sync"
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+
+	revision := &models.AchievementRevision{
+		AchievementID: achievementID,
+		Change:        change,
+		Content:       *achievement,
+	}
+	if err := revisionRepo.Create(context.Background(), revision); err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+// ListAchievementRevisions godoc
+// @Summary      List achievement revisions
+// @Description  Get the revisions of an achievement's content, oldest first, with who made each change and which revision was verified
+// @Tags         Achievements
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path     string  true  "Achievement ID (MongoDB ObjectID)"
+// @Success      200 {object} map[string]interface{} "Achievement revisions"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      404 {object} map[string]interface{} "Achievement not found"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /achievements/{id}/revisions [get]
+func (s *achievementService) ListAchievementRevisions(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	access, err := authorizeAchievement(c, authz.ActionRead, s.achievementRefRepo, s.studentRepo, s.lecturerRepo, s.delegationRepo)
+	if access == nil {
+		return err
+	}
+	markViewed(s.achievementRefRepo, access)
+
+	revisions, err := s.revisionRepo.FindByAchievementID(context.Background(), id)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to get achievement revisions")
+	}
+
+	verified := access.ref.VerifiedRevision
+	summaries := make([]fiber.Map, 0, len(revisions))
+	for _, revision := range revisions {
+		summaries = append(summaries, fiber.Map{
+			"revision":    revision.Revision,
+			"change":      revision.Change,
+			"author_id":   revision.AuthorID,
+			"author_name": revision.AuthorName,
+			"created_at":  revision.CreatedAt,
+			"verified":    verified != nil && *verified == revision.Revision,
+		})
+	}
+
+	return utils.SuccessResponse(c, "Achievement revisions retrieved successfully", fiber.Map{
+		"achievement_id":    id,
+		"verified_revision": verified,
+		"revisions":         summaries,
+	})
+}
+
+// GetAchievementRevision godoc
+// @Summary      Get achievement revision
+// @Description  Get the content of an achievement as it was at a given revision
+// @Tags         Achievements
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path     string  true  "Achievement ID (MongoDB ObjectID)"
+// @Param        rev  path     int     true  "Revision number"
+// @Success      200 {object} map[string]interface{} "Achievement revision"
+// @Failure      400 {object} map[string]interface{} "Invalid revision number"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      404 {object} map[string]interface{} "Achievement or revision not found"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /achievements/{id}/revisions/{rev} [get]
+func (s *achievementService) GetAchievementRevision(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	access, err := authorizeAchievement(c, authz.ActionRead, s.achievementRefRepo, s.studentRepo, s.lecturerRepo, s.delegationRepo)
+	if access == nil {
+		return err
+	}
+	markViewed(s.achievementRefRepo, access)
+
+	number, err := strconv.Atoi(c.Params("rev"))
+	if err != nil || number < 1 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid revision number")
+	}
+
+	revision, err := s.findRevision(c, id, number)
+	if revision == nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, "Achievement revision retrieved successfully", revision)
+}
+
+// DiffAchievementRevisions godoc
+// @Summary      Compare achievement revisions
+// @Description  Get the fields that changed between two revisions of an achievement. By default the latest revision is compared with the one before it.
+// @Tags         Achievements
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id    path     string  true   "Achievement ID (MongoDB ObjectID)"
+// @Param        from  query    int     false  "Older revision (default: the one before to)"
+// @Param        to    query    int     false  "Newer revision (default: latest)"
+// @Success      200 {object} map[string]interface{} "Field-level diff"
+// @Failure      400 {object} map[string]interface{} "Invalid revision number"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      404 {object} map[string]interface{} "Achievement or revision not found"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /achievements/{id}/revisions/diff [get]
+func (s *achievementService) DiffAchievementRevisions(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	access, err := authorizeAchievement(c, authz.ActionRead, s.achievementRefRepo, s.studentRepo, s.lecturerRepo, s.delegationRepo)
+	if access == nil {
+		return err
+	}
+	markViewed(s.achievementRefRepo, access)
+
+	to := c.QueryInt("to", 0)
+	if to == 0 {
+		latest, err := s.revisionRepo.FindLatest(context.Background(), id)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "Achievement has no revisions")
+		}
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to get achievement revisions")
+		}
+		to = latest.Revision
+	}
+	from := c.QueryInt("from", to-1)
+	if from < 1 || to < 1 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid revision number")
+	}
+
+	older, err := s.findRevision(c, id, from)
+	if older == nil {
+		return err
+	}
+	newer, err := s.findRevision(c, id, to)
+	if newer == nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, "Achievement revisions compared successfully", fiber.Map{
+		"achievement_id": id,
+		"from":           older.Revision,
+		"to":             newer.Revision,
+		"changes":        diffFields(older.Content, newer.Content),
+	})
+}
+
+// findRevision loads a revision of an achievement.
+// On failure it writes the error response and returns a nil revision.
+func (s *achievementService) findRevision(c *fiber.Ctx, achievementID string, number int) (*models.AchievementRevision, error) {
+	revision, err := s.revisionRepo.FindByRevision(context.Background(), achievementID, number)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, utils.ErrorResponse(c, fiber.StatusNotFound, "Revision "+strconv.Itoa(number)+" not found")
+	}
+	if err != nil {
+		return nil, utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to get achievement revision")
+	}
+	return revision, nil
+}
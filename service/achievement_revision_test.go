@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"student-achievement-system/models"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// fakeRevisionRepo keeps the revisions of a single achievement in memory
+type fakeRevisionRepo struct {
+	revisions []models.AchievementRevision
+}
+
+func (r *fakeRevisionRepo) Create(_ context.Context, revision *models.AchievementRevision) error {
+	revision.Revision = len(r.revisions) + 1
+	r.revisions = append(r.revisions, *revision)
+	return nil
+}
+
+func (r *fakeRevisionRepo) FindByAchievementID(context.Context, string) ([]models.AchievementRevision, error) {
+	return r.revisions, nil
+}
+
+func (r *fakeRevisionRepo) FindByRevision(_ context.Context, _ string, revision int) (*models.AchievementRevision, error) {
+	if revision < 1 || revision > len(r.revisions) {
+		return nil, mongo.ErrNoDocuments
+	}
+	return &r.revisions[revision-1], nil
+}
+
+func (r *fakeRevisionRepo) FindLatest(context.Context, string) (*models.AchievementRevision, error) {
+	if len(r.revisions) == 0 {
+		return nil, mongo.ErrNoDocuments
+	}
+	return &r.revisions[len(r.revisions)-1], nil
+}
+
+func (r *fakeRevisionRepo) FindLatestByAchievementIDs(context.Context, []string) (map[string]*models.AchievementRevision, error) {
+	latest := map[string]*models.AchievementRevision{}
+	if len(r.revisions) > 0 {
+		revision := &r.revisions[len(r.revisions)-1]
+		latest[revision.AchievementID] = revision
+	}
+	return latest, nil
+}
+
+func TestCurrentRevision(t *testing.T) {
+	repo := &fakeRevisionRepo{}
+	achievement := diffAchievement()
+	id := achievement.ID.Hex()
+
+	// Achievements without revisions get a baseline
+	revision, err := currentRevision(repo, id, &achievement)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revision.Revision != 1 || revision.Change != "baseline" {
+		t.Errorf("first revision = %d %q, want 1 baseline", revision.Revision, revision.Change)
+	}
+
+	// Unchanged content keeps the latest revision
+	if revision, err = currentRevision(repo, id, &achievement); err != nil {
+		t.Fatal(err)
+	}
+	if revision.Revision != 1 || len(repo.revisions) != 1 {
+		t.Errorf("unchanged content got revision %d of %d, want the existing one", revision.Revision, len(repo.revisions))
+	}
+
+	// Content changed without a recorded revision is recorded before it is pinned
+	achievement.Title = "Programming contest finals"
+	if revision, err = currentRevision(repo, id, &achievement); err != nil {
+		t.Fatal(err)
+	}
+	if revision.Revision != 2 || revision.Change != "sync" || revision.Content.Title != achievement.Title {
+		t.Errorf("changed content got revision %d %q %q, want 2 sync with the new title", revision.Revision, revision.Change, revision.Content.Title)
+	}
+}
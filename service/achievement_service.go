@@ -3,10 +3,13 @@ package service
 import (
 	"context"
 	"fmt"
+	"student-achievement-system/authz"
 	"student-achievement-system/middleware"
 	"student-achievement-system/models"
+	"student-achievement-system/points"
 	"student-achievement-system/repository"
 	"student-achievement-system/utils"
+	"student-achievement-system/workflow"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -21,13 +24,20 @@ type AchievementService interface {
 	UpdateAchievement(c *fiber.Ctx) error
 	DeleteAchievement(c *fiber.Ctx) error
 	GetAchievementHistory(c *fiber.Ctx) error
+	GetResubmissionDiff(c *fiber.Ctx) error
+	ListAchievementRevisions(c *fiber.Ctx) error
+	GetAchievementRevision(c *fiber.Ctx) error
+	DiffAchievementRevisions(c *fiber.Ctx) error
 	UploadAttachment(c *fiber.Ctx) error
 }
 
 type VerificationService interface {
 	SubmitForVerification(c *fiber.Ctx) error
+	WithdrawSubmission(c *fiber.Ctx) error
 	VerifyAchievement(c *fiber.Ctx) error
 	RejectAchievement(c *fiber.Ctx) error
+	BulkVerifyAchievements(c *fiber.Ctx) error
+	BulkRejectAchievements(c *fiber.Ctx) error
 	GetAdviseeAchievements(c *fiber.Ctx) error
 }
 
@@ -57,6 +67,10 @@ type UpdateAchievementRequest struct {
 	Tags            []string               `json:"tags,omitempty"`
 }
 
+type WithdrawRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
 type VerifyRequest struct {
 	Comments string `json:"comments,omitempty"`
 }
@@ -65,54 +79,94 @@ type RejectRequest struct {
 	Reason string `json:"reason" validate:"required"`
 }
 
+type BulkVerifyRequest struct {
+	IDs      []string `json:"ids" validate:"required,min=1,max=100"`
+	Comments string   `json:"comments,omitempty"`
+}
+
+type BulkRejectRequest struct {
+	IDs    []string `json:"ids" validate:"required,min=1,max=100"`
+	Reason string   `json:"reason" validate:"required"`
+}
+
 type achievementService struct {
 	achievementRepo    repository.AchievementRepository
+	revisionRepo       repository.AchievementRevisionRepository
 	achievementRefRepo repository.AchievementReferenceRepository
 	studentRepo        repository.StudentRepository
 	lecturerRepo       repository.LecturerRepository
+	delegationRepo     repository.AdvisorDelegationRepository
+	approvalRepo       repository.ApprovalRepository
+	pointsRuleRepo     repository.PointsRuleRepository
+	auditRepo          repository.AuditRepository
 }
 
 type verificationService struct {
 	achievementRepo    repository.AchievementRepository
+	revisionRepo       repository.AchievementRevisionRepository
 	achievementRefRepo repository.AchievementReferenceRepository
 	studentRepo        repository.StudentRepository
 	lecturerRepo       repository.LecturerRepository
+	delegationRepo     repository.AdvisorDelegationRepository
+	userRepo           repository.UserRepository
 	notificationRepo   repository.NotificationRepository
+	approvalRepo       repository.ApprovalRepository
+	auditRepo          repository.AuditRepository
 }
 
 func NewAchievementService(
 	achievementRepo repository.AchievementRepository,
+	revisionRepo repository.AchievementRevisionRepository,
 	achievementRefRepo repository.AchievementReferenceRepository,
 	studentRepo repository.StudentRepository,
 	lecturerRepo repository.LecturerRepository,
+	delegationRepo repository.AdvisorDelegationRepository,
+	approvalRepo repository.ApprovalRepository,
+	pointsRuleRepo repository.PointsRuleRepository,
+	auditRepo repository.AuditRepository,
 ) AchievementService {
 	return &achievementService{
 		achievementRepo:    achievementRepo,
+		revisionRepo:       revisionRepo,
 		achievementRefRepo: achievementRefRepo,
 		studentRepo:        studentRepo,
 		lecturerRepo:       lecturerRepo,
+		delegationRepo:     delegationRepo,
+		approvalRepo:       approvalRepo,
+		pointsRuleRepo:     pointsRuleRepo,
+		auditRepo:          auditRepo,
 	}
 }
 
 func NewVerificationService(
 	achievementRepo repository.AchievementRepository,
+	revisionRepo repository.AchievementRevisionRepository,
 	achievementRefRepo repository.AchievementReferenceRepository,
 	studentRepo repository.StudentRepository,
 	lecturerRepo repository.LecturerRepository,
+	delegationRepo repository.AdvisorDelegationRepository,
+	userRepo repository.UserRepository,
 	notificationRepo repository.NotificationRepository,
+	approvalRepo repository.ApprovalRepository,
+	auditRepo repository.AuditRepository,
 ) VerificationService {
 	return &verificationService{
 		achievementRepo:    achievementRepo,
+		revisionRepo:       revisionRepo,
 		achievementRefRepo: achievementRefRepo,
 		studentRepo:        studentRepo,
 		lecturerRepo:       lecturerRepo,
+		delegationRepo:     delegationRepo,
+		userRepo:           userRepo,
 		notificationRepo:   notificationRepo,
+		approvalRepo:       approvalRepo,
+		auditRepo:          auditRepo,
 	}
 }
 
 // ListAchievements godoc
 // @Summary      List all achievements
-// @Description  Get paginated list of achievements with optional status filter
+// @Description  Get paginated list of achievements with optional status filter. Admins see all achievements, students their own and lecturers those of their advisees and department.
 // @Tags         Achievements
 // @Accept       json
 // @Produce      json
@@ -125,14 +179,43 @@ func NewVerificationService(
 // @Failure      500 {object} map[string]interface{} "Internal server error"
 // @Router       /achievements [get]
 func (s *achievementService) ListAchievements(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not authenticated")
+	}
+
 	// Get pagination parameters
 	pagination := utils.GetPaginationParams(c)
 
 	// Get status filter if provided
 	status := c.Query("status", "")
 
-	// Get all achievement references with pagination
-	achievementRefs, total, err := s.achievementRefRepo.FindAll(pagination.Offset, pagination.Limit, status)
+	var achievementRefs []models.AchievementReference
+	var total int64
+	var err error
+
+	subject := authzSubject(claims, s.studentRepo, s.lecturerRepo, s.delegationRepo)
+	if authz.Can(subject, authz.ActionList, authz.Resource{Kind: authz.KindAchievement}) {
+		// Get all achievement references with pagination
+		achievementRefs, total, err = s.achievementRefRepo.FindAll(pagination.Offset, pagination.Limit, status)
+	} else {
+		// Only achievements of students the caller may read
+		var students []models.Student
+		students, err = readableStudents(subject, s.studentRepo)
+		if err == nil {
+			studentIDs := make([]uuid.UUID, len(students))
+			studentsByID := make(map[uuid.UUID]*models.Student, len(students))
+			for i := range students {
+				studentIDs[i] = students[i].ID
+				studentsByID[students[i].ID] = &students[i]
+			}
+
+			achievementRefs, total, err = s.achievementRefRepo.FindByStudentIDs(studentIDs, pagination.Offset, pagination.Limit, status)
+			for i := range achievementRefs {
+				achievementRefs[i].Student = studentsByID[achievementRefs[i].StudentID]
+			}
+		}
+	}
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to retrieve achievements")
 	}
@@ -184,6 +267,7 @@ func (s *achievementService) ListAchievements(c *fiber.Ctx) error {
 			"attachments":      achievement.Attachments,
 			"tags":             achievement.Tags,
 			"points":           achievement.Points,
+			"points_version":   achievement.PointsVersion,
 		}
 		enrichedAchievements = append(enrichedAchievements, enrichedAchievement)
 	}
@@ -195,7 +279,7 @@ func (s *achievementService) ListAchievements(c *fiber.Ctx) error {
 
 // GetAchievement godoc
 // @Summary      Get achievement by ID
-// @Description  Get detailed information of a specific achievement
+// @Description  Get detailed information of a specific achievement. Once one of its verifiers opens a submitted achievement, its student can no longer withdraw it.
 // @Tags         Achievements
 // @Accept       json
 // @Produce      json
@@ -203,11 +287,18 @@ func (s *achievementService) ListAchievements(c *fiber.Ctx) error {
 // @Param        id  path     string  true  "Achievement ID (MongoDB ObjectID)"
 // @Success      200 {object} map[string]interface{} "Achievement details"
 // @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
 // @Failure      404 {object} map[string]interface{} "Achievement not found"
 // @Router       /achievements/{id} [get]
 func (s *achievementService) GetAchievement(c *fiber.Ctx) error {
 	id := c.Params("id")
 
+	access, err := authorizeAchievement(c, authz.ActionRead, s.achievementRefRepo, s.studentRepo, s.lecturerRepo, s.delegationRepo)
+	if access == nil {
+		return err
+	}
+	markViewed(s.achievementRefRepo, access)
+
 	achievement, err := s.achievementRepo.FindByID(context.Background(), id)
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusNotFound, "Achievement not found")
@@ -227,6 +318,7 @@ func (s *achievementService) GetAchievement(c *fiber.Ctx) error {
 // @Success      201 {object} map[string]interface{} "Achievement created"
 // @Failure      400 {object} map[string]interface{} "Invalid input"
 // @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Only students can create achievements"
 // @Failure      500 {object} map[string]interface{} "Internal server error"
 // @Router       /achievements [post]
 //
@@ -339,10 +431,15 @@ func (s *achievementService) CreateAchievement(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not authenticated")
 	}
 
-	// Find student record for this user
-	student, err := s.studentRepo.FindByUserID(claims.UserID)
+	// Achievements are always created by and for the calling student
+	subject := authzSubject(claims, s.studentRepo, s.lecturerRepo, s.delegationRepo)
+	if !authz.Can(subject, authz.ActionCreate, authz.Resource{Kind: authz.KindAchievement}) {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "Only students can create achievements")
+	}
+
+	student, err := s.studentRepo.FindByID(subject.StudentID)
 	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Only students can create achievements")
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to load student")
 	}
 
 	// Parse achievement details from req.Data based on type
@@ -359,9 +456,6 @@ func (s *achievementService) CreateAchievement(c *fiber.Ctx) error {
 		})
 	}
 
-	// Calculate points based on achievement type and level
-	points := calculatePoints(req.AchievementType, req.Data)
-
 	achievement := &models.Achievement{
 		StudentID:       student.ID.String(),
 		AchievementType: models.AchievementType(req.AchievementType),
@@ -370,11 +464,15 @@ func (s *achievementService) CreateAchievement(c *fiber.Ctx) error {
 		Details:         achievementDetails,
 		Attachments:     attachments,
 		Tags:            req.Tags,
-		Points:          points,
 		CreatedAt:       time.Now(),
 		UpdatedAt:       time.Now(),
 	}
 
+	// Score the achievement with the points rules in force
+	if err := scoreAchievement(s.pointsRuleRepo, achievement, time.Now()); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to load points rules")
+	}
+
 	id, err := s.achievementRepo.Create(context.Background(), achievement)
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create achievement")
@@ -397,12 +495,15 @@ func (s *achievementService) CreateAchievement(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create achievement reference")
 	}
 
+	recordRevision(c, s.revisionRepo, id, achievement, "create")
+	RecordAudit(c, s.auditRepo, "achievement.create", "achievement", id, auditChanges(nil, achievement))
+
 	return utils.SuccessResponse(c, "Achievement created successfully", achievement)
 }
 
 // UpdateAchievement godoc
 // @Summary      Update achievement
-// @Description  Update achievement information by ID
+// @Description  Update achievement information by ID. Only draft achievements and rejected ones being revised for resubmission can be edited.
 // @Tags         Achievements
 // @Accept       json
 // @Produce      json
@@ -414,11 +515,22 @@ func (s *achievementService) CreateAchievement(c *fiber.Ctx) error {
 // @Failure      401 {object} map[string]interface{} "Unauthorized"
 // @Failure      403 {object} map[string]interface{} "Forbidden"
 // @Failure      404 {object} map[string]interface{} "Achievement not found"
+// @Failure      409 {object} map[string]interface{} "Achievement cannot be edited in its current status"
 // @Failure      500 {object} map[string]interface{} "Internal server error"
 // @Router       /achievements/{id} [put]
 func (s *achievementService) UpdateAchievement(c *fiber.Ctx) error {
 	id := c.Params("id")
 
+	access, err := authorizeAchievement(c, authz.ActionUpdate, s.achievementRefRepo, s.studentRepo, s.lecturerRepo, s.delegationRepo)
+	if access == nil {
+		return err
+	}
+
+	// Only drafts and rejected achievements being revised can change
+	if err := workflow.CanEdit(access.ref); err != nil {
+		return transitionFailed(c, err)
+	}
+
 	var req UpdateAchievementRequest
 	if err := c.BodyParser(&req); err != nil {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
@@ -429,6 +541,7 @@ func (s *achievementService) UpdateAchievement(c *fiber.Ctx) error {
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusNotFound, "Achievement not found")
 	}
+	before := *achievement
 
 	// Update fields
 	if req.Title != "" {
@@ -448,8 +561,6 @@ func (s *achievementService) UpdateAchievement(c *fiber.Ctx) error {
 			achievementType = req.AchievementType
 		}
 		achievement.Details = parseAchievementDetails(req.Data, achievementType)
-		// Recalculate points
-		achievement.Points = calculatePoints(achievementType, req.Data)
 	}
 
 	// Update attachments
@@ -479,18 +590,28 @@ func (s *achievementService) UpdateAchievement(c *fiber.Ctx) error {
 		}
 	}
 
+	// Rescore with the rules in force now when what the points depend on changed
+	if req.Data != nil || req.AchievementType != "" {
+		if err := scoreAchievement(s.pointsRuleRepo, achievement, time.Now()); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to load points rules")
+		}
+	}
+
 	achievement.UpdatedAt = time.Now()
 
 	if err := s.achievementRepo.Update(context.Background(), id, achievement); err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update achievement")
 	}
 
+	recordRevision(c, s.revisionRepo, id, achievement, "update")
+	RecordAudit(c, s.auditRepo, "achievement.update", "achievement", id, auditChanges(before, achievement))
+
 	return utils.SuccessResponse(c, "Achievement updated successfully", achievement)
 }
 
 // DeleteAchievement godoc
 // @Summary      Delete achievement
-// @Description  Soft delete achievement by ID (changes status to 'deleted')
+// @Description  Soft delete achievement by ID (changes status to 'deleted'). Only draft and rejected achievements can be deleted.
 // @Tags         Achievements
 // @Accept       json
 // @Produce      json
@@ -500,194 +621,245 @@ func (s *achievementService) UpdateAchievement(c *fiber.Ctx) error {
 // @Failure      401 {object} map[string]interface{} "Unauthorized"
 // @Failure      403 {object} map[string]interface{} "Forbidden"
 // @Failure      404 {object} map[string]interface{} "Achievement not found"
+// @Failure      409 {object} map[string]interface{} "Achievement cannot be deleted in its current status"
 // @Failure      500 {object} map[string]interface{} "Internal server error"
 // @Router       /achievements/{id} [delete]
 func (s *achievementService) DeleteAchievement(c *fiber.Ctx) error {
 	id := c.Params("id")
 
-	// Get achievement reference
-	achievementRef, err := s.achievementRefRepo.FindByMongoID(id)
-	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusNotFound, "Achievement not found")
+	access, err := authorizeAchievement(c, authz.ActionDelete, s.achievementRefRepo, s.studentRepo, s.lecturerRepo, s.delegationRepo)
+	if access == nil {
+		return err
 	}
 
-	// Soft delete: Update status to deleted
-	achievementRef.Status = models.StatusDeleted
-	achievementRef.UpdatedAt = time.Now()
-
-	if err := s.achievementRefRepo.Update(achievementRef); err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete achievement")
+	// Soft delete: only drafts and rejected achievements may be deleted
+	transition, err := transitionAchievement(c, s.achievementRefRepo, access, workflow.EventDelete, workflow.Request{}, nil, nil)
+	if transition == nil {
+		return err
 	}
 
+	RecordAudit(c, s.auditRepo, "achievement.delete", "achievement", id,
+		auditChanges(fiber.Map{"status": transition.From}, fiber.Map{"status": transition.To}))
+
 	return utils.SuccessResponse(c, "Achievement deleted successfully", fiber.Map{
 		"id":     id,
-		"status": "deleted",
+		"status": transition.To,
 	})
 }
 
 // Verification Service Methods
 // SubmitForVerification godoc
 // @Summary      Submit achievement for verification
-// @Description  Submit an achievement to advisor for verification
+// @Description  Submit an achievement for verification. It goes through the approval chain configured for its type and competition level, or to the advisor alone if there is none. A rejected achievement is resubmitted the same way once it has been revised, which increments its resubmission count.
 // @Tags         Verification
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
 // @Param        id  path     string  true  "Achievement ID (MongoDB ObjectID)"
 // @Success      200 {object} map[string]interface{} "Achievement submitted for verification"
-// @Failure      400 {object} map[string]interface{} "Achievement already verified/rejected"
 // @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Forbidden - not the owner"
 // @Failure      404 {object} map[string]interface{} "Achievement not found"
+// @Failure      409 {object} map[string]interface{} "Achievement is neither a draft nor rejected"
 // @Failure      500 {object} map[string]interface{} "Internal server error"
 // @Router       /achievements/{id}/submit [post]
 func (s *verificationService) SubmitForVerification(c *fiber.Ctx) error {
 	id := c.Params("id")
 
-	// Get achievement reference
-	achievementRef, err := s.achievementRefRepo.FindByMongoID(id)
+	access, err := authorizeAchievement(c, authz.ActionSubmit, s.achievementRefRepo, s.studentRepo, s.lecturerRepo, s.delegationRepo)
+	if access == nil {
+		return err
+	}
+
+	achievement, err := s.achievementRepo.FindByID(context.Background(), id)
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusNotFound, "Achievement not found")
 	}
 
-	// Update status to submitted
-	achievementRef.Status = models.StatusSubmitted
-	now := time.Now()
-	achievementRef.SubmittedAt = &now
-	achievementRef.UpdatedAt = now
+	// A rejected achievement is resubmitted once the student has revised it
+	note := ""
+	resubmission := access.ref.Status == models.StatusRejected
+	if resubmission {
+		note = fmt.Sprintf("Resubmission %d", access.ref.ResubmissionCount+1)
+	}
 
-	if err := s.achievementRefRepo.Update(achievementRef); err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to submit achievement")
+	// Each submission goes through the approval chain configured at the time it is made
+	chain, err := s.approvalRepo.FindChainFor(achievement.AchievementType, achievement.Details.CompetitionLevel)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to determine the approval chain")
+	}
+	var firstStage *models.ApprovalStage
+	if chain != nil && len(chain.Stages) > 0 {
+		firstStage = &chain.Stages[0]
 	}
 
-	// Get achievement details for notification
-	achievement, _ := s.achievementRepo.FindByID(context.Background(), id)
+	transition, err := transitionAchievement(c, s.achievementRefRepo, access, workflow.EventSubmit, workflow.Request{Note: note}, achievement,
+		func(ref *models.AchievementReference, now time.Time) {
+			ref.SubmittedAt = &now
+			ref.ApprovalChainID = nil
+			ref.CurrentStageID = nil
+			if firstStage != nil {
+				ref.ApprovalChainID = &chain.ID
+				ref.CurrentStageID = &firstStage.ID
+			}
+			if resubmission {
+				ref.ResubmissionCount++
+				ref.VerifiedAt = nil
+				ref.VerifiedBy = nil
+				ref.VerifiedRevision = nil
+				ref.RejectionNote = ""
+			}
+		})
+	if transition == nil {
+		return err
+	}
+	submittedAt := access.ref.SubmittedAt
 
-	// Get student info
-	student, _ := s.studentRepo.FindByID(achievementRef.StudentID)
+	action := "achievement.submit"
+	if resubmission {
+		action = "achievement.resubmit"
+	}
+	RecordAudit(c, s.auditRepo, action, "achievement", id, auditChanges(
+		fiber.Map{"status": transition.From},
+		fiber.Map{
+			"status":             transition.To,
+			"submitted_at":       submittedAt,
+			"resubmission_count": access.ref.ResubmissionCount,
+			"approval_chain_id":  access.ref.ApprovalChainID,
+		},
+	))
 
-	// Create notification for advisor if assigned
-	if student.AdvisorID != nil {
-		advisorLecturer, err := s.lecturerRepo.FindByID(*student.AdvisorID)
-		if err == nil {
-			CreateNotification(
-				s.notificationRepo,
-				advisorLecturer.UserID,
-				models.NotificationTypeAchievementSubmitted,
-				"New Achievement Submitted",
-				fmt.Sprintf("%s submitted a new achievement: %s", student.User.FullName, achievement.Title),
-				fiber.Map{
-					"achievement_id": id,
-					"student_id":     student.ID,
-					"student_name":   student.User.FullName,
-				},
-			)
-		}
+	student := access.student
+	message := fmt.Sprintf("%s submitted a new achievement: %s", student.User.FullName, achievement.Title)
+	if resubmission {
+		message = fmt.Sprintf("%s resubmitted a revised achievement: %s", student.User.FullName, achievement.Title)
 	}
 
+	// Notify whoever decides the first stage, the advisor if there is no approval chain
+	s.notifyApprovers(firstStage, student, models.NotificationTypeAchievementSubmitted, "New Achievement Submitted", message, fiber.Map{
+		"achievement_id":     id,
+		"student_id":         student.ID,
+		"student_name":       student.User.FullName,
+		"resubmission_count": access.ref.ResubmissionCount,
+	})
+
 	return utils.SuccessResponse(c, "Achievement submitted for verification", fiber.Map{
-		"id":           id,
-		"status":       "submitted",
-		"submitted_at": now,
+		"id":                 id,
+		"status":             transition.To,
+		"submitted_at":       submittedAt,
+		"resubmission_count": access.ref.ResubmissionCount,
+		"approval_chain":     chain,
+		"current_stage":      firstStage,
 	})
 }
 
-// VerifyAchievement godoc
-// @Summary      Verify achievement
-// @Description  Advisor verifies an achievement (only for own advisees)
+// WithdrawSubmission godoc
+// @Summary      Withdraw submission
+// @Description  Recall a submitted achievement back to draft, e.g. to fix a typo or add a missing certificate, and take it off the verifiers' queue. Only possible until one of its verifiers has opened or decided on it.
 // @Tags         Verification
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
-// @Param        id       path     string         true  "Achievement ID (MongoDB ObjectID)"
-// @Param        verify   body     VerifyRequest  false "Verification comments"
-// @Success      200 {object} map[string]interface{} "Achievement verified successfully"
-// @Failure      400 {object} map[string]interface{} "Achievement not submitted or already verified"
+// @Param        id        path     string           true   "Achievement ID (MongoDB ObjectID)"
+// @Param        withdraw  body     WithdrawRequest  false  "Why the submission is withdrawn"
+// @Success      200 {object} map[string]interface{} "Submission withdrawn"
 // @Failure      401 {object} map[string]interface{} "Unauthorized"
-// @Failure      403 {object} map[string]interface{} "Forbidden - not student's advisor"
+// @Failure      403 {object} map[string]interface{} "Forbidden - not the owner"
 // @Failure      404 {object} map[string]interface{} "Achievement not found"
+// @Failure      409 {object} map[string]interface{} "Achievement not submitted, or already opened by a verifier"
 // @Failure      500 {object} map[string]interface{} "Internal server error"
-// @Router       /achievements/{id}/verify [post]
-func (s *verificationService) VerifyAchievement(c *fiber.Ctx) error {
+// @Router       /achievements/{id}/withdraw [post]
+func (s *verificationService) WithdrawSubmission(c *fiber.Ctx) error {
 	id := c.Params("id")
-	claims := middleware.GetUserFromContext(c)
-	if claims == nil {
-		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not authenticated")
-	}
 
-	var req VerifyRequest
+	var req WithdrawRequest
 	c.BodyParser(&req)
 
-	// Get achievement reference to check status and get student info
-	achievementRef, err := s.achievementRefRepo.FindByMongoID(id)
+	access, err := authorizeAchievement(c, authz.ActionSubmit, s.achievementRefRepo, s.studentRepo, s.lecturerRepo, s.delegationRepo)
+	if access == nil {
+		return err
+	}
+	stage := access.ref.CurrentStage
+
+	achievement, err := s.achievementRepo.FindByID(context.Background(), id)
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusNotFound, "Achievement not found")
 	}
 
-	// Get student info to check advisor
-	student, err := s.studentRepo.FindByID(achievementRef.StudentID)
-	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusNotFound, "Student not found")
+	transition, err := transitionAchievement(c, s.achievementRefRepo, access, workflow.EventWithdraw, workflow.Request{Note: req.Reason}, achievement,
+		func(ref *models.AchievementReference, now time.Time) {
+			ref.SubmittedAt = nil
+			ref.ApprovalChainID = nil
+			ref.CurrentStageID = nil
+		})
+	if transition == nil {
+		return err
 	}
 
-	// SECURITY CHECK: Admin can verify any achievement, Lecturer can only verify their advisees
-	var verifierID uuid.UUID
+	RecordAudit(c, s.auditRepo, "achievement.withdraw", "achievement", id, auditChanges(
+		fiber.Map{"status": transition.From},
+		fiber.Map{"status": transition.To, "reason": req.Reason},
+	))
 
-	// Check if user is Admin (has admin role in claims)
-	if claims.RoleName == "Admin" {
-		// Admin can verify any achievement
-		verifierID = claims.UserID
-	} else {
-		// For lecturers, verify they are the advisor of this student
-		lecturer, err := s.lecturerRepo.FindByUserID(claims.UserID)
-		if err != nil {
-			return utils.ErrorResponse(c, fiber.StatusForbidden, "Only admins or lecturers can verify achievements")
-		}
+	// Take it off the queue of whoever decides the stage it waited in
+	student := access.student
+	s.notifyApprovers(stage, student, models.NotificationTypeAchievementWithdrawn, "Achievement Withdrawn",
+		fmt.Sprintf("%s withdrew the achievement '%s' from verification", student.User.FullName, achievement.Title),
+		fiber.Map{
+			"achievement_id": id,
+			"student_id":     student.ID,
+			"student_name":   student.User.FullName,
+			"reason":         req.Reason,
+		},
+	)
 
-		if student.AdvisorID == nil || *student.AdvisorID != lecturer.ID {
-			return utils.ErrorResponse(c, fiber.StatusForbidden, "You can only verify achievements from your advisees")
-		}
-		verifierID = lecturer.ID
-	}
+	return utils.SuccessResponse(c, "Submission withdrawn", fiber.Map{
+		"id":     id,
+		"status": transition.To,
+		"reason": req.Reason,
+	})
+}
 
-	// Update achievement reference status
-	achievementRef.Status = models.StatusVerified
-	now := time.Now()
-	achievementRef.VerifiedAt = &now
-	achievementRef.VerifiedBy = &verifierID
+// VerifyAchievement godoc
+// @Summary      Verify achievement
+// @Description  Approve an achievement at the approval stage it waits in, as the advisor or whoever else decides that stage. Approvals that leave stages to go keep it submitted; the one completing the final stage verifies it and pins the current revision of its content as the verified one.
+// @Tags         Verification
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path     string         true  "Achievement ID (MongoDB ObjectID)"
+// @Param        verify   body     VerifyRequest  false "Verification comments"
+// @Success      200 {object} map[string]interface{} "Achievement verified, or approval recorded at its stage"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Forbidden - does not decide the current stage"
+// @Failure      404 {object} map[string]interface{} "Achievement not found"
+// @Failure      409 {object} map[string]interface{} "Achievement not submitted, or stage already decided"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /achievements/{id}/verify [post]
+func (s *verificationService) VerifyAchievement(c *fiber.Ctx) error {
+	var req VerifyRequest
+	c.BodyParser(&req)
 
-	if err := s.achievementRefRepo.Update(achievementRef); err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to verify achievement")
+	// SECURITY CHECK: Admin can verify any achievement, others only while it waits in a stage they decide
+	access, err := authorizeAchievement(c, authz.ActionVerify, s.achievementRefRepo, s.studentRepo, s.lecturerRepo, s.delegationRepo)
+	if access == nil {
+		return err
 	}
 
-	// Get achievement details for notification
-	achievement, _ := s.achievementRepo.FindByID(context.Background(), id)
+	decision, err := s.verify(c, access, req.Comments)
+	if err != nil {
+		return respondError(c, err)
+	}
 
 	// Create notification for student
-	CreateNotification(
-		s.notificationRepo,
-		student.UserID,
-		models.NotificationTypeAchievementVerified,
-		"Achievement Verified",
-		fmt.Sprintf("Congratulations! Your achievement '%s' has been verified", achievement.Title),
-		fiber.Map{
-			"achievement_id": id,
-			"verified_by":    verifierID,
-			"comments":       req.Comments,
-		},
-	)
+	s.notifyVerified([]*achievementDecision{decision}, req.Comments)
 
-	return utils.SuccessResponse(c, "Achievement verified successfully", fiber.Map{
-		"id":          id,
-		"status":      "verified",
-		"verified_by": verifierID,
-		"verified_at": now,
-		"comments":    req.Comments,
-	})
+	return utils.SuccessResponse(c, decision.message, decision.data)
 }
 
 // RejectAchievement godoc
 // @Summary      Reject achievement
-// @Description  Advisor rejects an achievement with reason (only for own advisees)
+// @Description  Reject an achievement with a reason, as the advisor or whoever else decides the approval stage it waits in. A rejection at any stage ends the approval chain.
 // @Tags         Verification
 // @Accept       json
 // @Produce      json
@@ -695,90 +867,34 @@ func (s *verificationService) VerifyAchievement(c *fiber.Ctx) error {
 // @Param        id      path     string         true  "Achievement ID (MongoDB ObjectID)"
 // @Param        reject  body     RejectRequest  true  "Rejection reason"
 // @Success      200 {object} map[string]interface{} "Achievement rejected successfully"
-// @Failure      400 {object} map[string]interface{} "Achievement not submitted or already verified"
+// @Failure      400 {object} map[string]interface{} "Invalid request body"
 // @Failure      401 {object} map[string]interface{} "Unauthorized"
-// @Failure      403 {object} map[string]interface{} "Forbidden - not student's advisor"
+// @Failure      403 {object} map[string]interface{} "Forbidden - does not decide the current stage"
 // @Failure      404 {object} map[string]interface{} "Achievement not found"
+// @Failure      409 {object} map[string]interface{} "Achievement not submitted, rejection without reason, or stage already decided"
 // @Failure      500 {object} map[string]interface{} "Internal server error"
 // @Router       /achievements/{id}/reject [post]
 func (s *verificationService) RejectAchievement(c *fiber.Ctx) error {
-	id := c.Params("id")
-	claims := middleware.GetUserFromContext(c)
-	if claims == nil {
-		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not authenticated")
-	}
-
 	var req RejectRequest
 	if err := c.BodyParser(&req); err != nil {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	// Get achievement reference to check status and get student info
-	achievementRef, err := s.achievementRefRepo.FindByMongoID(id)
-	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusNotFound, "Achievement not found")
+	// SECURITY CHECK: Admin can reject any achievement, others only while it waits in a stage they decide
+	access, err := authorizeAchievement(c, authz.ActionVerify, s.achievementRefRepo, s.studentRepo, s.lecturerRepo, s.delegationRepo)
+	if access == nil {
+		return err
 	}
 
-	// Get student info to check advisor
-	student, err := s.studentRepo.FindByID(achievementRef.StudentID)
+	decision, err := s.reject(c, access, req.Reason)
 	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusNotFound, "Student not found")
+		return respondError(c, err)
 	}
 
-	// SECURITY CHECK: Admin can reject any achievement, Lecturer can only reject their advisees
-	var verifierID uuid.UUID
-
-	if claims.RoleName == "Admin" {
-		// Admin can reject any achievement
-		verifierID = claims.UserID
-	} else {
-		// For lecturers, verify they are the advisor of this student
-		lecturer, err := s.lecturerRepo.FindByUserID(claims.UserID)
-		if err != nil {
-			return utils.ErrorResponse(c, fiber.StatusForbidden, "Only admins or lecturers can reject achievements")
-		}
-
-		if student.AdvisorID == nil || *student.AdvisorID != lecturer.ID {
-			return utils.ErrorResponse(c, fiber.StatusForbidden, "You can only reject achievements from your advisees")
-		}
-		verifierID = lecturer.ID
-	}
-
-	// Update achievement reference status
-	achievementRef.Status = models.StatusRejected
-	now := time.Now()
-	achievementRef.VerifiedAt = &now
-	achievementRef.VerifiedBy = &verifierID
-	achievementRef.RejectionNote = req.Reason
-
-	if err := s.achievementRefRepo.Update(achievementRef); err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to reject achievement")
-	}
-
-	// Get achievement details for notification
-	achievement, _ := s.achievementRepo.FindByID(context.Background(), id)
-
 	// Create notification for student
-	CreateNotification(
-		s.notificationRepo,
-		student.UserID,
-		models.NotificationTypeAchievementRejected,
-		"Achievement Rejected",
-		fmt.Sprintf("Your achievement '%s' has been rejected. Reason: %s", achievement.Title, req.Reason),
-		fiber.Map{
-			"achievement_id": id,
-			"rejection_note": req.Reason,
-			"verified_by":    verifierID,
-		},
-	)
+	s.notifyRejected([]*achievementDecision{decision}, req.Reason)
 
-	return utils.SuccessResponse(c, "Achievement rejected", fiber.Map{
-		"id":          id,
-		"status":      "rejected",
-		"verified_by": verifierID,
-		"verified_at": now,
-		"reason":      req.Reason,
-	})
+	return utils.SuccessResponse(c, decision.message, decision.data)
 }
 
 // Helper functions for achievement parsing and point calculation
@@ -896,61 +1012,27 @@ func parseAchievementDetails(data map[string]interface{}, achievementType string
 	return details
 }
 
-func calculatePoints(achievementType string, data map[string]interface{}) int {
-	basePoints := map[string]int{
-		"competition":   100,
-		"publication":   150,
-		"organization":  50,
-		"certification": 75,
-		"academic":      25,
-		"other":         10,
-	}
-
-	points := basePoints[achievementType]
-
-	// Bonus points based on level for competitions
-	if achievementType == "competition" {
-		if level, ok := data["competition_level"].(string); ok {
-			levelBonus := map[string]int{
-				"international": 200,
-				"national":      100,
-				"regional":      50,
-				"local":         25,
-			}
-			points += levelBonus[level]
-		}
-
-		// Bonus points for rank
-		if rank, ok := data["rank"].(float64); ok {
-			rankInt := int(rank)
-			if rankInt == 1 {
-				points += 100
-			} else if rankInt == 2 {
-				points += 75
-			} else if rankInt == 3 {
-				points += 50
-			}
-		}
+// scoreAchievement sets the points of the achievement from its details under the rule set
+// in force at the given time, recording the version used. While no rule set is in force
+// the default rules apply, recorded as version 0.
+func scoreAchievement(pointsRuleRepo repository.PointsRuleRepository, achievement *models.Achievement, at time.Time) error {
+	set, err := pointsRuleRepo.FindInForce(at)
+	if err != nil {
+		return err
 	}
 
-	// Bonus points for publications
-	if achievementType == "publication" {
-		if pubType, ok := data["publication_type"].(string); ok {
-			pubBonus := map[string]int{
-				"journal":    100,
-				"conference": 75,
-				"book":       150,
-			}
-			points += pubBonus[pubType]
-		}
+	rules, version := points.DefaultRules(), 0
+	if set != nil {
+		rules, version = set.Rules, set.Version
 	}
-
-	return points
+	achievement.Points = points.Score(rules, achievement).Points
+	achievement.PointsVersion = version
+	return nil
 }
 
 // GetAdviseeAchievements godoc
 // @Summary      Get advisee achievements
-// @Description  Get all achievements from students under advisor's supervision
+// @Description  Get all achievements from students under advisor's supervision, including the advisees of advisors who delegated to the caller while the delegation is in force
 // @Tags         Verification
 // @Accept       json
 // @Produce      json
@@ -976,8 +1058,9 @@ func (s *verificationService) GetAdviseeAchievements(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusForbidden, "Only lecturers can access advisee achievements")
 	}
 
-	// Get all students under this advisor using lecturer.ID (not user_id)
-	students, err := s.studentRepo.FindByAdvisorID(lecturer.ID)
+	// Get all students under this advisor using lecturer.ID (not user_id), and those of
+	// the advisors they stand in for
+	students, delegatedBy, err := s.adviseesOf(lecturer.ID)
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch advisees")
 	}
@@ -1040,6 +1123,7 @@ func (s *verificationService) GetAdviseeAchievements(c *fiber.Ctx) error {
 			"attachments":      achievement.Attachments,
 			"tags":             achievement.Tags,
 			"points":           achievement.Points,
+			"points_version":   achievement.PointsVersion,
 		}
 
 		// Add student info if found
@@ -1051,6 +1135,13 @@ func (s *verificationService) GetAdviseeAchievements(c *fiber.Ctx) error {
 				"email":      studentInfo.User.Email,
 				"program":    studentInfo.ProgramStudy,
 			}
+			if delegation, ok := delegatedBy[studentInfo.ID]; ok {
+				enrichedAchievement["delegation"] = fiber.Map{
+					"id":           delegation.ID,
+					"on_behalf_of": delegation.DelegatorID,
+					"ends_at":      delegation.EndsAt,
+				}
+			}
 		}
 
 		enrichedAchievements = append(enrichedAchievements, enrichedAchievement)
@@ -1060,3 +1151,38 @@ func (s *verificationService) GetAdviseeAchievements(c *fiber.Ctx) error {
 		"achievements": enrichedAchievements,
 	}, total, pagination.Page, pagination.Limit)
 }
+
+// adviseesOf returns the students a lecturer advises, followed by those they advise in
+// place of another advisor under a delegation in force, which the map gives per student
+func (s *verificationService) adviseesOf(lecturerID uuid.UUID) ([]models.Student, map[uuid.UUID]models.AdvisorDelegation, error) {
+	students, err := s.studentRepo.FindByAdvisorID(lecturerID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	delegations, err := s.delegationRepo.FindActiveByDelegate(lecturerID, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seen := make(map[uuid.UUID]bool, len(students))
+	for _, student := range students {
+		seen[student.ID] = true
+	}
+	delegatedBy := make(map[uuid.UUID]models.AdvisorDelegation)
+	for _, delegation := range delegations {
+		advisees, err := s.studentRepo.FindByAdvisorID(delegation.DelegatorID)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, student := range advisees {
+			if seen[student.ID] || (delegation.StudentID != nil && *delegation.StudentID != student.ID) {
+				continue
+			}
+			seen[student.ID] = true
+			delegatedBy[student.ID] = delegation
+			students = append(students, student)
+		}
+	}
+	return students, delegatedBy, nil
+}
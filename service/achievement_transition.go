@@ -0,0 +1,188 @@
+package service
+
+import (
+	"errors"
+	"student-achievement-system/authz"
+	"student-achievement-system/models"
+	"student-achievement-system/repository"
+	"student-achievement-system/utils"
+	"student-achievement-system/workflow"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// transitionAchievement fires event on an authorized achievement. The workflow decides
+// whether the transition is valid for req, whose subject and resource are filled in
+// here; effect then fills in the fields that go with it, and the new status is saved
+// together with a status history entry holding snapshot, the content of the
+// achievement, if given. When it returns nil the error response has already been written.
+func transitionAchievement(
+	c *fiber.Ctx,
+	achievementRefRepo repository.AchievementReferenceRepository,
+	access *achievementAccess,
+	event workflow.Event,
+	req workflow.Request,
+	snapshot *models.Achievement,
+	effect func(ref *models.AchievementReference, now time.Time),
+) (*workflow.Transition, error) {
+	transition, err := applyTransition(achievementRefRepo, access, event, req, snapshot, effect)
+	if err != nil {
+		return nil, respondError(c, err)
+	}
+	return transition, nil
+}
+
+// applyTransition is transitionAchievement for callers that report failures themselves,
+// such as bulk actions. It returns the workflow's error or a requestError.
+func applyTransition(
+	achievementRefRepo repository.AchievementReferenceRepository,
+	access *achievementAccess,
+	event workflow.Event,
+	req workflow.Request,
+	snapshot *models.Achievement,
+	effect func(ref *models.AchievementReference, now time.Time),
+) (*workflow.Transition, error) {
+	ref := access.ref
+
+	transition, err := fireTransition(access, event, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decided by a delegate for the advisor; worked out before effect leaves the stage
+	advisor := onBehalfOf(access)
+
+	// The stage the achievement waited in when it was loaded; approvals must not race
+	var fromStage *uuid.UUID
+	if ref.CurrentStageID != nil {
+		stageID := *ref.CurrentStageID
+		fromStage = &stageID
+	}
+
+	now := time.Now()
+	ref.Status = transition.To
+	ref.UpdatedAt = now
+	if effect != nil {
+		effect(ref, now)
+	}
+
+	history := &models.AchievementStatusHistory{
+		AchievementRefID: ref.ID,
+		OldStatus:        transition.From,
+		NewStatus:        transition.To,
+		ChangedBy:        access.subject.UserID,
+		OnBehalfOf:       advisor,
+		Notes:            req.Note,
+		Snapshot:         achievementSnapshot(snapshot),
+	}
+	if err := achievementRefRepo.Transition(ref, transition.From, fromStage, transition.Unopened, history); err != nil {
+		if errors.Is(err, repository.ErrStatusChanged) {
+			// Someone else moved the achievement on or opened it since it was loaded
+			current, findErr := achievementRefRepo.FindByMongoID(ref.MongoAchievementID)
+			if findErr == nil {
+				reason := "The achievement was changed by someone else and is now " + string(current.Status)
+				switch {
+				case current.Status == transition.From && transition.Unopened && current.FirstViewedAt != nil:
+					reason = "A verifier has opened the achievement in the meantime"
+				case current.Status == transition.From:
+					reason = "The achievement has moved on to another approval stage in the meantime"
+				}
+				return nil, &workflow.TransitionError{
+					Event:   event,
+					From:    current.Status,
+					Allowed: workflow.Allowed(current.Status),
+					Reason:  reason,
+				}
+			}
+		}
+		return nil, failed(fiber.StatusInternalServerError, "Failed to "+string(event)+" achievement")
+	}
+
+	return transition, nil
+}
+
+// markViewed records that a verifier opened a submitted achievement or acted on it, after
+// which the student can no longer withdraw it. Only callers who may decide the achievement
+// at its current stage count as verifiers. Failures are logged, the view goes on.
+func markViewed(achievementRefRepo repository.AchievementReferenceRepository, access *achievementAccess) {
+	ref := access.ref
+	if ref.Status != models.StatusSubmitted || ref.FirstViewedAt != nil {
+		return
+	}
+	if !authz.Can(access.subject, authz.ActionVerify, achievementResource(ref, access.student)) {
+		return
+	}
+
+	if err := achievementRefRepo.MarkViewed(ref.ID, access.subject.UserID); err != nil {
+		utils.GlobalLogger.Error("Failed to record that the achievement was opened", err, map[string]interface{}{
+			"achievement_ref_id": ref.ID,
+		})
+		return
+	}
+	now := time.Now()
+	ref.FirstViewedAt = &now
+	ref.FirstViewedBy = &access.subject.UserID
+}
+
+// fireTransition asks the workflow whether the caller may fire event with req without
+// firing it, for callers that record something before the status changes
+func fireTransition(access *achievementAccess, event workflow.Event, req workflow.Request) (*workflow.Transition, error) {
+	req.Subject = access.subject
+	req.Resource = achievementResource(access.ref, access.student)
+	return workflow.Fire(access.ref, event, req)
+}
+
+// requestError is a failure of one step of a request, reported to the client with status
+type requestError struct {
+	status  int
+	message string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+// failed returns a requestError
+func failed(status int, message string) error {
+	return &requestError{status: status, message: message}
+}
+
+// respondError writes the response for an error returned by applyTransition and the
+// other helpers that leave reporting to their caller
+func respondError(c *fiber.Ctx, err error) error {
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		return utils.ErrorResponse(c, reqErr.status, reqErr.message)
+	}
+	return transitionFailed(c, err)
+}
+
+// transitionFailed writes the response for a transition the workflow refused. Invalid
+// transitions are conflicts with the achievement's current status and tell the client
+// which events that status does allow.
+func transitionFailed(c *fiber.Ctx, err error) error {
+	var transitionErr *workflow.TransitionError
+	if !errors.As(err, &transitionErr) {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, errNotPermittedMessage)
+	}
+
+	return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+		"status":     "error",
+		"error":      transitionErr.Reason,
+		"transition": transitionDetails(transitionErr),
+	})
+}
+
+// errNotPermittedMessage is reported when the workflow refuses the caller an event
+const errNotPermittedMessage = "You are not allowed to change the status of this achievement"
+
+// transitionDetails tells the client which events the achievement's status does allow
+func transitionDetails(transitionErr *workflow.TransitionError) fiber.Map {
+	return fiber.Map{
+		"event":          transitionErr.Event,
+		"current_status": transitionErr.From,
+		"allowed_events": transitionErr.Allowed,
+	}
+}
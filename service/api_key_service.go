@@ -0,0 +1,245 @@
+package service
+
+import (
+	"student-achievement-system/config"
+	"student-achievement-system/middleware"
+	"student-achievement-system/models"
+	"student-achievement-system/repository"
+	"student-achievement-system/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// apiKeyPrefix marks API keys so they are recognizable in logs and secret scanners
+const apiKeyPrefix = "sak_"
+
+type APIKeyService interface {
+	CreateAPIKey(c *fiber.Ctx) error
+	ListAPIKeys(c *fiber.Ctx) error
+	GetAPIKey(c *fiber.Ctx) error
+	RevokeAPIKey(c *fiber.Ctx) error
+}
+
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Description   string   `json:"description,omitempty"`
+	Scopes        []string `json:"scopes" validate:"required,min=1,dive,required"`
+	ExpiresInDays int      `json:"expires_in_days" validate:"required,min=1"`
+}
+
+type apiKeyService struct {
+	apiKeyRepo repository.APIKeyRepository
+	auditRepo  repository.AuditRepository
+	cfg        *config.Config
+}
+
+func NewAPIKeyService(apiKeyRepo repository.APIKeyRepository, auditRepo repository.AuditRepository, cfg *config.Config) APIKeyService {
+	return &apiKeyService{
+		apiKeyRepo: apiKeyRepo,
+		auditRepo:  auditRepo,
+		cfg:        cfg,
+	}
+}
+
+// CreateAPIKey godoc
+// @Summary      Create API key
+// @Description  Issue an API key for a service integration. The key is granted exactly the listed permissions, which the creating admin must hold themselves. The key is only shown in this response; send it in the X-API-Key header.
+// @Tags         API Keys
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body CreateAPIKeyRequest true "Key name, permissions and lifetime"
+// @Success      200 {object} map[string]interface{} "API key created"
+// @Failure      400 {object} map[string]interface{} "Invalid request, unknown permission or lifetime too long"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Permission not held by the creator"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /api-keys [post]
+func (s *apiKeyService) CreateAPIKey(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	var req CreateAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.ValidationErrorResponse(c, err)
+	}
+
+	lifetime := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	if lifetime > s.cfg.APIKeyMaxTTL {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "API key lifetime exceeds the maximum of "+s.cfg.APIKeyMaxTTL.String())
+	}
+
+	scopes := uniqueStrings(req.Scopes)
+	permissionIDs, err := s.apiKeyRepo.FindPermissionIDs(scopes)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to resolve permissions")
+	}
+
+	grants := make([]uuid.UUID, 0, len(scopes))
+	for _, scope := range scopes {
+		permissionID, ok := permissionIDs[scope]
+		if !ok {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Unknown permission: "+scope)
+		}
+		// A key can never do more than the admin who created it
+		if !hasPermission(claims.Permissions, scope) {
+			return utils.ErrorResponse(c, fiber.StatusForbidden, "Cannot grant a permission you do not hold: "+scope)
+		}
+		grants = append(grants, permissionID)
+	}
+
+	secret, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to generate API key")
+	}
+	plainKey := apiKeyPrefix + secret
+
+	apiKey := &models.APIKey{
+		Name:        req.Name,
+		Description: req.Description,
+		Prefix:      plainKey[:len(apiKeyPrefix)+8],
+		KeyHash:     utils.HashToken(plainKey),
+		CreatedBy:   claims.UserID,
+		ExpiresAt:   time.Now().Add(lifetime),
+	}
+	if err := s.apiKeyRepo.Create(apiKey, grants); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create API key")
+	}
+
+	created, err := s.apiKeyRepo.FindByID(apiKey.ID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to load API key")
+	}
+
+	utils.GlobalLogger.LogAuth("api_key_created", claims.UserID.String(), true, map[string]interface{}{
+		"api_key_id": apiKey.ID,
+		"name":       apiKey.Name,
+		"scopes":     scopes,
+	})
+	RecordAudit(c, s.auditRepo, "api_key.create", "api_key", apiKey.ID.String(), auditChanges(nil, fiber.Map{
+		"name":       apiKey.Name,
+		"prefix":     apiKey.Prefix,
+		"scopes":     scopes,
+		"expires_at": apiKey.ExpiresAt,
+	}))
+
+	return utils.SuccessResponse(c, "API key created. Store it now, it will not be shown again", fiber.Map{
+		"api_key": plainKey,
+		"key":     created,
+	})
+}
+
+// ListAPIKeys godoc
+// @Summary      List API keys
+// @Description  List all API keys with their permissions, expiry and last use. Key secrets are never returned.
+// @Tags         API Keys
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200 {object} map[string]interface{} "API keys retrieved successfully"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /api-keys [get]
+func (s *apiKeyService) ListAPIKeys(c *fiber.Ctx) error {
+	keys, err := s.apiKeyRepo.FindAll()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to retrieve API keys")
+	}
+
+	return utils.SuccessResponse(c, "API keys retrieved successfully", keys)
+}
+
+// GetAPIKey godoc
+// @Summary      Get API key
+// @Description  Get one API key by ID
+// @Tags         API Keys
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path     string  true  "API key ID (UUID)"
+// @Success      200 {object} map[string]interface{} "API key retrieved successfully"
+// @Failure      400 {object} map[string]interface{} "Invalid API key ID"
+// @Failure      404 {object} map[string]interface{} "API key not found"
+// @Router       /api-keys/{id} [get]
+func (s *apiKeyService) GetAPIKey(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid API key ID")
+	}
+
+	apiKey, err := s.apiKeyRepo.FindByID(id)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "API key not found")
+	}
+
+	return utils.SuccessResponse(c, "API key retrieved successfully", apiKey)
+}
+
+// RevokeAPIKey godoc
+// @Summary      Revoke API key
+// @Description  Permanently disable an API key. Requests using it are rejected immediately.
+// @Tags         API Keys
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path     string  true  "API key ID (UUID)"
+// @Success      200 {object} map[string]interface{} "API key revoked"
+// @Failure      400 {object} map[string]interface{} "Invalid API key ID"
+// @Failure      404 {object} map[string]interface{} "API key not found or already revoked"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /api-keys/{id} [delete]
+func (s *apiKeyService) RevokeAPIKey(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid API key ID")
+	}
+
+	now := time.Now()
+	revoked, err := s.apiKeyRepo.Revoke(id, now)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to revoke API key")
+	}
+	if !revoked {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "API key not found or already revoked")
+	}
+
+	utils.GlobalLogger.LogAuth("api_key_revoked", claims.UserID.String(), true, map[string]interface{}{
+		"api_key_id": id,
+	})
+	RecordAudit(c, s.auditRepo, "api_key.revoke", "api_key", id.String(), auditChanges(
+		fiber.Map{"revoked_at": nil},
+		fiber.Map{"revoked_at": now},
+	))
+	return utils.SuccessResponse(c, "API key revoked", nil)
+}
+
+func hasPermission(permissions []string, permission string) bool {
+	for _, p := range permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
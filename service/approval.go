@@ -0,0 +1,304 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"student-achievement-system/authz"
+	"student-achievement-system/models"
+	"student-achievement-system/repository"
+	"student-achievement-system/utils"
+	"student-achievement-system/workflow"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// stageOf describes who decides an approval stage for authorization. A nil stage, as for
+// achievements without an approval chain, is decided by the advisor alone.
+func stageOf(stage *models.ApprovalStage) *authz.Stage {
+	if stage == nil {
+		return nil
+	}
+	switch stage.ApproverType {
+	case models.ApproverRole:
+		if stage.Role == nil {
+			// The role was removed; only admins can still decide the stage
+			return &authz.Stage{}
+		}
+		return &authz.Stage{Role: stage.Role.Name}
+	case models.ApproverUser:
+		if stage.UserID == nil {
+			return &authz.Stage{}
+		}
+		return &authz.Stage{UserID: *stage.UserID}
+	}
+	return &authz.Stage{Advisor: true}
+}
+
+// stageLabel names a stage in notes and notifications, e.g. "Stage 2 (Kemahasiswaan)"
+func stageLabel(stage *models.ApprovalStage) string {
+	return fmt.Sprintf("Stage %d (%s)", stage.Position, stage.Name)
+}
+
+// stageApproval is an approval counted at the stage an achievement waits in
+type stageApproval struct {
+	stage     *models.ApprovalStage    // nil when the advisor alone verifies
+	decision  *models.ApprovalDecision // the caller's approval, nil when there is no stage
+	approvals int64                    // approvals the stage has received in this round
+	next      *models.ApprovalStage    // stage the achievement moves on to, nil after the last one
+	pending   int                      // stages still incomplete after this approval
+}
+
+// complete reports whether the stage reached its quorum
+func (a *stageApproval) complete() bool {
+	return a.stage == nil || a.approvals >= int64(a.stage.Quorum)
+}
+
+// approveStage records the caller's approval at the stage the achievement waits in and
+// works out where that leaves the chain
+func (s *verificationService) approveStage(access *achievementAccess, comments string) (*stageApproval, error) {
+	stage := access.ref.CurrentStage
+	if stage == nil {
+		return &stageApproval{}, nil
+	}
+
+	decision, err := s.recordDecision(access, models.DecisionApprove, comments)
+	if err != nil {
+		return nil, err
+	}
+
+	approvals, err := s.approvalRepo.CountApprovals(access.ref.ID, stage.ID, access.ref.ResubmissionCount)
+	if err != nil {
+		s.withdrawDecision(decision)
+		return nil, failed(fiber.StatusInternalServerError, "Failed to count stage approvals")
+	}
+	approval := &stageApproval{stage: stage, decision: decision, approvals: approvals}
+
+	chain, err := s.approvalRepo.FindChainByID(stage.ChainID)
+	if err != nil {
+		s.withdrawDecision(decision)
+		return nil, failed(fiber.StatusInternalServerError, "Failed to load the approval chain")
+	}
+	if chain != nil {
+		for i := range chain.Stages {
+			if chain.Stages[i].Position <= stage.Position {
+				continue
+			}
+			if approval.next == nil {
+				approval.next = &chain.Stages[i]
+			}
+			approval.pending++
+		}
+	}
+	if !approval.complete() {
+		approval.pending++
+	}
+
+	return approval, nil
+}
+
+// recordDecision saves the caller's decision at the stage the achievement waits in. An
+// approver decides a stage once per submission; callers withdraw the decision again when
+// the status change it goes with fails.
+func (s *verificationService) recordDecision(
+	access *achievementAccess,
+	decision string,
+	comments string,
+) (*models.ApprovalDecision, error) {
+	stage := access.ref.CurrentStage
+	record := &models.ApprovalDecision{
+		AchievementRefID: access.ref.ID,
+		StageID:          stage.ID,
+		StageName:        stage.Name,
+		Position:         stage.Position,
+		Round:            access.ref.ResubmissionCount,
+		DecidedBy:        access.subject.UserID,
+		OnBehalfOf:       onBehalfOf(access),
+		Decision:         decision,
+		Comments:         comments,
+	}
+	err := s.approvalRepo.RecordDecision(record)
+	if errors.Is(err, repository.ErrAlreadyDecided) {
+		return nil, failed(fiber.StatusConflict, "You have already decided "+stageLabel(stage))
+	}
+	if err != nil {
+		return nil, failed(fiber.StatusInternalServerError, "Failed to record the stage decision")
+	}
+	return record, nil
+}
+
+// withdrawDecision removes a decision whose status change failed, so that the approver
+// can decide again. A nil decision is ignored; failures are logged.
+func (s *verificationService) withdrawDecision(decision *models.ApprovalDecision) {
+	if decision == nil {
+		return
+	}
+	if err := s.approvalRepo.DeleteDecision(decision.ID); err != nil {
+		utils.GlobalLogger.Error("Failed to withdraw the stage decision", err, map[string]interface{}{
+			"decision_id":        decision.ID,
+			"achievement_ref_id": decision.AchievementRefID,
+		})
+	}
+}
+
+// notifyApprovers tells whoever decides stage about an achievement waiting there, or no
+// longer waiting. A nil stage is decided by the student's advisor.
+func (s *verificationService) notifyApprovers(
+	stage *models.ApprovalStage,
+	student *models.Student,
+	notifType models.NotificationType,
+	title, message string,
+	data fiber.Map,
+) {
+	if stage != nil {
+		data["stage"] = stageLabel(stage)
+	}
+	for _, userID := range stageApprovers(stage, student, s.userRepo, s.lecturerRepo, s.delegationRepo) {
+		CreateNotification(s.notificationRepo, userID, notifType, title, message, data)
+	}
+}
+
+// stageApprovers returns the users who decide stage for student's achievement. A nil
+// stage is decided by the student's advisor, together with whoever stands in for them.
+func stageApprovers(
+	stage *models.ApprovalStage,
+	student *models.Student,
+	userRepo repository.UserRepository,
+	lecturerRepo repository.LecturerRepository,
+	delegationRepo repository.AdvisorDelegationRepository,
+) []uuid.UUID {
+	approvers := make([]uuid.UUID, 0)
+	switch {
+	case stage != nil && stage.ApproverType == models.ApproverRole:
+		if stage.RoleID == nil {
+			return approvers
+		}
+		users, err := userRepo.FindByRoleID(*stage.RoleID)
+		if err != nil {
+			utils.GlobalLogger.Error("Failed to load stage approvers", err, map[string]interface{}{
+				"stage_id": stage.ID,
+			})
+			return approvers
+		}
+		for _, user := range users {
+			approvers = append(approvers, user.ID)
+		}
+	case stage != nil && stage.ApproverType == models.ApproverUser:
+		if stage.UserID != nil {
+			approvers = append(approvers, *stage.UserID)
+		}
+	case student.AdvisorID != nil:
+		advisor, err := lecturerRepo.FindByID(*student.AdvisorID)
+		if err == nil && advisor.ID != uuid.Nil {
+			approvers = append(approvers, advisor.UserID)
+		}
+		approvers = append(approvers, advisorDelegates(student, lecturerRepo, delegationRepo)...)
+	}
+	return approvers
+}
+
+// advisorDelegates returns the users of the lecturers standing in for student's advisor
+func advisorDelegates(
+	student *models.Student,
+	lecturerRepo repository.LecturerRepository,
+	delegationRepo repository.AdvisorDelegationRepository,
+) []uuid.UUID {
+	delegations, err := delegationRepo.FindActiveByDelegator(*student.AdvisorID, time.Now())
+	if err != nil {
+		utils.GlobalLogger.Error("Failed to load advisor delegations", err, map[string]interface{}{
+			"lecturer_id": *student.AdvisorID,
+		})
+		return nil
+	}
+
+	delegates := make([]uuid.UUID, 0, len(delegations))
+	for _, delegation := range delegations {
+		if delegation.StudentID != nil && *delegation.StudentID != student.ID {
+			continue
+		}
+		delegate, err := lecturerRepo.FindByID(delegation.DelegateID)
+		if err == nil && delegate.ID != uuid.Nil {
+			delegates = append(delegates, delegate.UserID)
+		}
+	}
+	return delegates
+}
+
+// approvalNote records a stage approval in the status history
+func approvalNote(approval *stageApproval, comments string) string {
+	if approval.stage == nil {
+		return comments
+	}
+	note := fmt.Sprintf("%s approved (%d/%d)", stageLabel(approval.stage), approval.approvals, approval.stage.Quorum)
+	if comments != "" {
+		note += ": " + comments
+	}
+	return note
+}
+
+// advanceStage saves an approval that leaves stages to go. The achievement stays
+// submitted and, once its stage reached the quorum, moves on to the next stage.
+func (s *verificationService) advanceStage(
+	c *fiber.Ctx,
+	access *achievementAccess,
+	achievement *models.Achievement,
+	approval *stageApproval,
+	comments string,
+) (*achievementDecision, error) {
+	id := access.ref.MongoAchievementID
+	stage := approval.stage
+
+	transition, err := applyTransition(s.achievementRefRepo, access, workflow.EventApprove,
+		workflow.Request{Note: approvalNote(approval, comments), PendingStages: approval.pending}, nil,
+		func(ref *models.AchievementReference, now time.Time) {
+			if approval.complete() {
+				ref.CurrentStageID = &approval.next.ID
+			}
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	current := stage
+	if approval.complete() {
+		current = approval.next
+		RecordAudit(c, s.auditRepo, "achievement.approve_stage", "achievement", id, auditChanges(
+			fiber.Map{"current_stage": stageLabel(stage)},
+			fiber.Map{"current_stage": stageLabel(current), "comments": comments},
+		))
+
+		student := access.student
+		s.notifyApprovers(current, student, models.NotificationTypeAchievementSubmitted, "Achievement Awaiting Your Approval",
+			fmt.Sprintf("%s's achievement '%s' passed %s and awaits your approval", student.User.FullName, achievement.Title, stageLabel(stage)),
+			fiber.Map{
+				"achievement_id": id,
+				"student_id":     student.ID,
+				"student_name":   student.User.FullName,
+			},
+		)
+	} else {
+		RecordAudit(c, s.auditRepo, "achievement.approve_stage", "achievement", id, fiber.Map{
+			"stage":     stageLabel(stage),
+			"approvals": approval.approvals,
+			"quorum":    stage.Quorum,
+			"comments":  comments,
+		})
+	}
+
+	return &achievementDecision{
+		access:      access,
+		achievement: achievement,
+		message:     "Approval recorded",
+		data: fiber.Map{
+			"id":             id,
+			"status":         transition.To,
+			"stage":          stage,
+			"approvals":      approval.approvals,
+			"quorum":         stage.Quorum,
+			"stage_complete": approval.complete(),
+			"current_stage":  current,
+			"pending_stages": approval.pending,
+		},
+	}, nil
+}
@@ -0,0 +1,344 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"student-achievement-system/middleware"
+	"student-achievement-system/models"
+	"student-achievement-system/repository"
+	"student-achievement-system/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type ApprovalService interface {
+	ListApprovalChains(c *fiber.Ctx) error
+	GetApprovalChain(c *fiber.Ctx) error
+	CreateApprovalChain(c *fiber.Ctx) error
+	UpdateApprovalChain(c *fiber.Ctx) error
+	DeleteApprovalChain(c *fiber.Ctx) error
+	ListPendingApprovals(c *fiber.Ctx) error
+}
+
+type ApprovalChainRequest struct {
+	Name             string                 `json:"name" validate:"required,max=100"`
+	AchievementType  string                 `json:"achievement_type" validate:"required,oneof=academic competition organization publication certification other"`
+	CompetitionLevel string                 `json:"competition_level,omitempty" validate:"max=50"`
+	Stages           []ApprovalStageRequest `json:"stages" validate:"required,min=1,dive"`
+}
+
+type ApprovalStageRequest struct {
+	Name         string `json:"name" validate:"required,max=100"`
+	ApproverType string `json:"approver_type" validate:"required,oneof=advisor role user"`
+	RoleID       string `json:"role_id,omitempty"`
+	UserID       string `json:"user_id,omitempty"`
+	Quorum       int    `json:"quorum,omitempty" validate:"min=0"`
+}
+
+type approvalService struct {
+	approvalRepo       repository.ApprovalRepository
+	achievementRefRepo repository.AchievementReferenceRepository
+	roleRepo           repository.RoleRepository
+	userRepo           repository.UserRepository
+	auditRepo          repository.AuditRepository
+}
+
+func NewApprovalService(
+	approvalRepo repository.ApprovalRepository,
+	achievementRefRepo repository.AchievementReferenceRepository,
+	roleRepo repository.RoleRepository,
+	userRepo repository.UserRepository,
+	auditRepo repository.AuditRepository,
+) ApprovalService {
+	return &approvalService{
+		approvalRepo:       approvalRepo,
+		achievementRefRepo: achievementRefRepo,
+		roleRepo:           roleRepo,
+		userRepo:           userRepo,
+		auditRepo:          auditRepo,
+	}
+}
+
+// ListApprovalChains godoc
+// @Summary      List approval chains
+// @Description  Get the approval chains with their stages. A submitted achievement goes through the chain of its type and competition level, else the chain of its type, else to its advisor alone.
+// @Tags         Approval Chains
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200 {object} map[string]interface{} "Approval chains"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /approval-chains [get]
+func (s *approvalService) ListApprovalChains(c *fiber.Ctx) error {
+	chains, err := s.approvalRepo.FindChains()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to get approval chains")
+	}
+	return utils.SuccessResponse(c, "Approval chains retrieved successfully", chains)
+}
+
+// GetApprovalChain godoc
+// @Summary      Get approval chain
+// @Description  Get an approval chain with its stages and the number of achievements under review through it
+// @Tags         Approval Chains
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path     string  true  "Approval chain ID (UUID)"
+// @Success      200 {object} map[string]interface{} "Approval chain"
+// @Failure      400 {object} map[string]interface{} "Invalid approval chain ID"
+// @Failure      404 {object} map[string]interface{} "Approval chain not found"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /approval-chains/{id} [get]
+func (s *approvalService) GetApprovalChain(c *fiber.Ctx) error {
+	chain, err := s.findChain(c)
+	if chain == nil {
+		return err
+	}
+
+	inReview, err := s.approvalRepo.CountInReview(chain.ID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to count achievements under review")
+	}
+
+	return utils.SuccessResponse(c, "Approval chain retrieved successfully", fiber.Map{
+		"chain":     chain,
+		"in_review": inReview,
+	})
+}
+
+// CreateApprovalChain godoc
+// @Summary      Create approval chain
+// @Description  Configure the ordered approval stages for an achievement type, optionally only for one competition level. Each stage is decided by the advisor, the members of a role or one user; a role stage completes once quorum members approved it.
+// @Tags         Approval Chains
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body ApprovalChainRequest true "Approval chain with its stages in order"
+// @Success      200 {object} map[string]interface{} "Approval chain created successfully"
+// @Failure      400 {object} map[string]interface{} "Invalid request body or validation failed"
+// @Failure      409 {object} map[string]interface{} "A chain already exists for this type and competition level"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /approval-chains [post]
+func (s *approvalService) CreateApprovalChain(c *fiber.Ctx) error {
+	chain, err := s.buildChain(c, uuid.Nil)
+	if chain == nil {
+		return err
+	}
+
+	if err := s.approvalRepo.CreateChain(chain); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create approval chain")
+	}
+
+	RecordAudit(c, s.auditRepo, "approval_chain.create", "approval_chain", chain.ID.String(), auditChanges(nil, chain))
+
+	chain, _ = s.approvalRepo.FindChainByID(chain.ID)
+	return utils.SuccessResponse(c, "Approval chain created successfully", chain)
+}
+
+// UpdateApprovalChain godoc
+// @Summary      Update approval chain
+// @Description  Replace the scope and stages of an approval chain. Chains cannot change while achievements are under review through them.
+// @Tags         Approval Chains
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path     string                true  "Approval chain ID (UUID)"
+// @Param        request  body     ApprovalChainRequest  true  "Approval chain with its stages in order"
+// @Success      200 {object} map[string]interface{} "Approval chain updated successfully"
+// @Failure      400 {object} map[string]interface{} "Invalid request body or validation failed"
+// @Failure      404 {object} map[string]interface{} "Approval chain not found"
+// @Failure      409 {object} map[string]interface{} "Chain in use, or another chain exists for the type and competition level"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /approval-chains/{id} [put]
+func (s *approvalService) UpdateApprovalChain(c *fiber.Ctx) error {
+	existing, err := s.findChain(c)
+	if existing == nil {
+		return err
+	}
+	if ok, err := s.checkNotInReview(c, existing); !ok {
+		return err
+	}
+
+	chain, err := s.buildChain(c, existing.ID)
+	if chain == nil {
+		return err
+	}
+	chain.ID = existing.ID
+
+	if err := s.approvalRepo.UpdateChain(chain); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update approval chain")
+	}
+
+	RecordAudit(c, s.auditRepo, "approval_chain.update", "approval_chain", chain.ID.String(), auditChanges(existing, chain))
+
+	chain, _ = s.approvalRepo.FindChainByID(chain.ID)
+	return utils.SuccessResponse(c, "Approval chain updated successfully", chain)
+}
+
+// DeleteApprovalChain godoc
+// @Summary      Delete approval chain
+// @Description  Delete an approval chain; achievements of its scope are then verified by the next matching chain or the advisor alone. Chains cannot be deleted while achievements are under review through them.
+// @Tags         Approval Chains
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path     string  true  "Approval chain ID (UUID)"
+// @Success      200 {object} map[string]interface{} "Approval chain deleted successfully"
+// @Failure      400 {object} map[string]interface{} "Invalid approval chain ID"
+// @Failure      404 {object} map[string]interface{} "Approval chain not found"
+// @Failure      409 {object} map[string]interface{} "Achievements are under review through the chain"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /approval-chains/{id} [delete]
+func (s *approvalService) DeleteApprovalChain(c *fiber.Ctx) error {
+	chain, err := s.findChain(c)
+	if chain == nil {
+		return err
+	}
+	if ok, err := s.checkNotInReview(c, chain); !ok {
+		return err
+	}
+
+	if err := s.approvalRepo.DeleteChain(chain.ID); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete approval chain")
+	}
+
+	RecordAudit(c, s.auditRepo, "approval_chain.delete", "approval_chain", chain.ID.String(), auditChanges(chain, nil))
+
+	return utils.SuccessResponse(c, "Approval chain deleted successfully", nil)
+}
+
+// ListPendingApprovals godoc
+// @Summary      List achievements awaiting my approval
+// @Description  Get the submitted achievements waiting in an approval stage decided by the caller's role or by the caller, that the caller has not decided yet. Advisors find the stages they decide under /lecturers/advisees/achievements.
+// @Tags         Verification
+// @Produce      json
+// @Security     BearerAuth
+// @Param        page   query    int  false  "Page number (default 1)"
+// @Param        limit  query    int  false  "Items per page (default 10, max 100)"
+// @Success      200 {object} map[string]interface{} "Achievements awaiting approval with pagination"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /approvals/pending [get]
+func (s *approvalService) ListPendingApprovals(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	pagination := utils.GetPaginationParams(c)
+
+	refs, total, err := s.achievementRefRepo.FindAwaitingApproval(claims.RoleID, claims.UserID, pagination.Offset, pagination.Limit)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch pending approvals")
+	}
+
+	return utils.PaginatedResponse(c, fiber.Map{
+		"achievements": refs,
+	}, total, pagination.Page, pagination.Limit)
+}
+
+// findChain loads the approval chain named by the :id route parameter.
+// On failure it writes the error response and returns a nil chain.
+func (s *approvalService) findChain(c *fiber.Ctx) (*models.ApprovalChain, error) {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid approval chain ID")
+	}
+
+	chain, err := s.approvalRepo.FindChainByID(id)
+	if err != nil {
+		return nil, utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to get approval chain")
+	}
+	if chain == nil {
+		return nil, utils.ErrorResponse(c, fiber.StatusNotFound, "Approval chain not found")
+	}
+	return chain, nil
+}
+
+// checkNotInReview refuses changes to a chain achievements are being reviewed through,
+// whose stages they refer to. When it returns false the error response has already been written.
+func (s *approvalService) checkNotInReview(c *fiber.Ctx, chain *models.ApprovalChain) (bool, error) {
+	inReview, err := s.approvalRepo.CountInReview(chain.ID)
+	if err != nil {
+		return false, utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to count achievements under review")
+	}
+	if inReview > 0 {
+		return false, utils.ErrorResponse(c, fiber.StatusConflict,
+			fmt.Sprintf("%d achievement(s) are under review through this chain; change it once they are decided", inReview))
+	}
+	return true, nil
+}
+
+// buildChain parses and validates the chain in the request body. id is the chain being
+// updated, uuid.Nil when creating one. On failure it writes the error response and
+// returns a nil chain.
+func (s *approvalService) buildChain(c *fiber.Ctx, id uuid.UUID) (*models.ApprovalChain, error) {
+	var req ApprovalChainRequest
+	if err := c.BodyParser(&req); err != nil {
+		return nil, utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return nil, utils.ValidationErrorResponse(c, err)
+	}
+
+	chain := &models.ApprovalChain{
+		Name:             strings.TrimSpace(req.Name),
+		AchievementType:  models.AchievementType(req.AchievementType),
+		CompetitionLevel: strings.ToLower(strings.TrimSpace(req.CompetitionLevel)),
+		Stages:           make([]models.ApprovalStage, 0, len(req.Stages)),
+	}
+
+	existing, err := s.approvalRepo.FindChainByScope(chain.AchievementType, chain.CompetitionLevel)
+	if err != nil {
+		return nil, utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to check existing approval chains")
+	}
+	if existing != nil && existing.ID != id {
+		return nil, utils.ErrorResponse(c, fiber.StatusConflict, "An approval chain already exists for this achievement type and competition level")
+	}
+
+	for i, stageReq := range req.Stages {
+		stage := models.ApprovalStage{
+			Name:         strings.TrimSpace(stageReq.Name),
+			ApproverType: stageReq.ApproverType,
+			Quorum:       stageReq.Quorum,
+		}
+		if stage.Quorum == 0 {
+			stage.Quorum = 1
+		}
+		position := i + 1
+
+		switch stage.ApproverType {
+		case models.ApproverRole:
+			roleID, err := uuid.Parse(stageReq.RoleID)
+			if err != nil {
+				return nil, utils.ErrorResponse(c, fiber.StatusBadRequest, fmt.Sprintf("Stage %d: invalid role_id", position))
+			}
+			if role, err := s.roleRepo.FindByID(roleID); err != nil || role.ID == uuid.Nil {
+				return nil, utils.ErrorResponse(c, fiber.StatusBadRequest, fmt.Sprintf("Stage %d: role not found", position))
+			}
+			stage.RoleID = &roleID
+		case models.ApproverUser:
+			userID, err := uuid.Parse(stageReq.UserID)
+			if err != nil {
+				return nil, utils.ErrorResponse(c, fiber.StatusBadRequest, fmt.Sprintf("Stage %d: invalid user_id", position))
+			}
+			if user, err := s.userRepo.FindByID(userID); err != nil || user.ID == uuid.Nil {
+				return nil, utils.ErrorResponse(c, fiber.StatusBadRequest, fmt.Sprintf("Stage %d: user not found", position))
+			}
+			stage.UserID = &userID
+		}
+
+		// Only a role has several members to reach a quorum
+		if stage.ApproverType != models.ApproverRole && stage.Quorum > 1 {
+			return nil, utils.ErrorResponse(c, fiber.StatusBadRequest,
+				fmt.Sprintf("Stage %d: a quorum above 1 needs a role stage", position))
+		}
+
+		chain.Stages = append(chain.Stages, stage)
+	}
+
+	return chain, nil
+}
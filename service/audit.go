@@ -0,0 +1,81 @@
+package service
+
+import (
+	"encoding/json"
+	"student-achievement-system/middleware"
+	"student-achievement-system/models"
+	"student-achievement-system/repository"
+	"student-achievement-system/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RecordAudit stores an audit event for a change made by the authenticated caller.
+// Failures are logged rather than returned, the change itself has already been made.
+func RecordAudit(
+	c *fiber.Ctx,
+	auditRepo repository.AuditRepository,
+	action string,
+	resourceType string,
+	resourceID string,
+	changes interface{},
+) {
+	changesJSON, _ := json.Marshal(changes)
+
+	event := &models.AuditEvent{
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Changes:      string(changesJSON),
+		IPAddress:    c.IP(),
+		RequestID:    middleware.GetRequestID(c),
+	}
+
+	if claims := middleware.GetUserFromContext(c); claims != nil {
+		event.ActorName = claims.Username
+		if claims.Impersonating() {
+			// Changes made while impersonating are attributed to the admin
+			actorID := claims.Actor.UserID
+			event.ActorID = &actorID
+			event.ActorName = claims.Actor.Username + " as " + claims.Username
+		} else if claims.APIKeyID == "" {
+			actorID := claims.UserID
+			event.ActorID = &actorID
+		}
+	}
+
+	if err := auditRepo.Create(event); err != nil {
+		utils.GlobalLogger.Error("Failed to record audit event", err, map[string]interface{}{
+			"action":        action,
+			"resource_type": resourceType,
+			"resource_id":   resourceID,
+		})
+	}
+}
+
+// auditChanges describes a change for the audit trail. Pass nil as before when the
+// resource was created and nil as after when it was deleted; for updates only the fields
+// that differ are kept. Structs are compared by their JSON form.
+func auditChanges(before, after interface{}) fiber.Map {
+	if before == nil {
+		return fiber.Map{"after": after}
+	}
+	if after == nil {
+		return fiber.Map{"before": before}
+	}
+
+	changedBefore, changedAfter := fiber.Map{}, fiber.Map{}
+	for _, change := range compareFields(auditFields(before), auditFields(after)) {
+		changedBefore[change.Field] = change.Before
+		changedAfter[change.Field] = change.After
+	}
+	return fiber.Map{"before": changedBefore, "after": changedAfter}
+}
+
+// auditFields flattens a snapshot to its top-level JSON fields, leaving out timestamps
+// that change on every write
+func auditFields(state interface{}) map[string]interface{} {
+	fields := toJSONMap(state)
+	delete(fields, "updated_at")
+	return fields
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"student-achievement-system/repository"
+	"student-achievement-system/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type AuditService interface {
+	ListAuditEvents(c *fiber.Ctx) error
+}
+
+type auditService struct {
+	auditRepo repository.AuditRepository
+}
+
+func NewAuditService(auditRepo repository.AuditRepository) AuditService {
+	return &auditService{auditRepo: auditRepo}
+}
+
+// ListAuditEvents godoc
+// @Summary      List audit events
+// @Description  Get a paginated, newest-first list of changes made through the API: who made them, what changed and from which request. action accepts a prefix ending in ".*", e.g. "user.*". from and to accept RFC 3339 timestamps or YYYY-MM-DD dates; a date in to includes that whole day.
+// @Tags         Audit
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        actor_id       query    string  false  "Actor user ID (UUID)"
+// @Param        action         query    string  false  "Action, e.g. achievement.verify or user.*"
+// @Param        resource_type  query    string  false  "Resource type, e.g. user, student, achievement, file, role"
+// @Param        resource_id    query    string  false  "Resource ID"
+// @Param        request_id     query    string  false  "Request ID from the X-Request-ID header"
+// @Param        from           query    string  false  "Earliest time (RFC 3339 or YYYY-MM-DD)"
+// @Param        to             query    string  false  "Latest time (RFC 3339 or YYYY-MM-DD)"
+// @Param        page           query    int     false  "Page number (default 1)"
+// @Param        limit          query    int     false  "Items per page (default 10, max 100)"
+// @Success      200 {object} map[string]interface{} "List of audit events with pagination"
+// @Failure      400 {object} map[string]interface{} "Invalid filter"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /audit [get]
+func (s *auditService) ListAuditEvents(c *fiber.Ctx) error {
+	filter := repository.AuditFilter{
+		Action:       c.Query("action"),
+		ResourceType: c.Query("resource_type"),
+		ResourceID:   c.Query("resource_id"),
+		RequestID:    c.Query("request_id"),
+	}
+
+	if actor := c.Query("actor_id"); actor != "" {
+		actorID, err := uuid.Parse(actor)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid actor_id")
+		}
+		filter.ActorID = &actorID
+	}
+
+	if from := c.Query("from"); from != "" {
+		t, _, err := parseAuditTime(from)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid from. Use RFC 3339 or YYYY-MM-DD")
+		}
+		filter.From = &t
+	}
+
+	if to := c.Query("to"); to != "" {
+		t, dateOnly, err := parseAuditTime(to)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid to. Use RFC 3339 or YYYY-MM-DD")
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		filter.To = &t
+	}
+
+	pagination := utils.GetPaginationParams(c)
+
+	events, total, err := s.auditRepo.FindAll(filter, pagination.Offset, pagination.Limit)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch audit events")
+	}
+
+	return utils.PaginatedResponse(c, fiber.Map{
+		"events": events,
+	}, total, pagination.Page, pagination.Limit)
+}
+
+// parseAuditTime accepts an RFC 3339 timestamp or a plain date, reporting which it was
+func parseAuditTime(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	return t, true, err
+}
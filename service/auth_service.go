@@ -3,10 +3,12 @@ package service
 import (
 	"student-achievement-system/config"
 	"student-achievement-system/middleware"
+	"student-achievement-system/models"
 	"student-achievement-system/repository"
 	"student-achievement-system/utils"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 )
 
 type AuthService interface {
@@ -14,6 +16,14 @@ type AuthService interface {
 	RefreshToken(c *fiber.Ctx) error
 	GetProfile(c *fiber.Ctx) error
 	Logout(c *fiber.Ctx) error
+	ChangePassword(c *fiber.Ctx) error
+	ForgotPassword(c *fiber.Ctx) error
+	ResetPassword(c *fiber.Ctx) error
+	EnrollMFA(c *fiber.Ctx) error
+	ConfirmMFAEnrollment(c *fiber.Ctx) error
+	VerifyMFA(c *fiber.Ctx) error
+	DisableMFA(c *fiber.Ctx) error
+	ResetUserMFA(c *fiber.Ctx) error
 }
 
 type LoginRequest struct {
@@ -32,20 +42,44 @@ type LoginResponse struct {
 }
 
 type authService struct {
-	userRepo repository.UserRepository
-	cfg      *config.Config
+	userRepo          repository.UserRepository
+	sessionRepo       repository.SessionRepository
+	passwordResetRepo repository.PasswordResetRepository
+	loginAttemptRepo  repository.LoginAttemptRepository
+	mfaRepo           repository.MFARepository
+	auditRepo         repository.AuditRepository
+	tokenService      TokenService
+	mailer            utils.Mailer
+	cfg               *config.Config
 }
 
-func NewAuthService(userRepo repository.UserRepository, cfg *config.Config) AuthService {
+func NewAuthService(
+	userRepo repository.UserRepository,
+	sessionRepo repository.SessionRepository,
+	passwordResetRepo repository.PasswordResetRepository,
+	loginAttemptRepo repository.LoginAttemptRepository,
+	mfaRepo repository.MFARepository,
+	auditRepo repository.AuditRepository,
+	tokenService TokenService,
+	mailer utils.Mailer,
+	cfg *config.Config,
+) AuthService {
 	return &authService{
-		userRepo: userRepo,
-		cfg:      cfg,
+		userRepo:          userRepo,
+		sessionRepo:       sessionRepo,
+		passwordResetRepo: passwordResetRepo,
+		loginAttemptRepo:  loginAttemptRepo,
+		mfaRepo:           mfaRepo,
+		auditRepo:         auditRepo,
+		tokenService:      tokenService,
+		mailer:            mailer,
+		cfg:               cfg,
 	}
 }
 
 // Login godoc
 // @Summary      User login
-// @Description  Authenticate user with username/email and password. Returns token (access token) and refresh_token. Use the 'token' field value (without 'Bearer' prefix) in Swagger Authorize.
+// @Description  Authenticate user with username/email and password. Returns token (access token) and refresh_token. Use the 'token' field value (without 'Bearer' prefix) in Swagger Authorize. Accounts with two-factor authentication receive an mfa_token instead, to be completed at /auth/mfa/verify.
 // @Tags         Authentication
 // @Accept       json
 // @Produce      json
@@ -54,6 +88,8 @@ func NewAuthService(userRepo repository.UserRepository, cfg *config.Config) Auth
 // @Failure      400 {object} map[string]interface{} "Invalid request body or validation failed"
 // @Failure      401 {object} map[string]interface{} "Invalid credentials"
 // @Failure      403 {object} map[string]interface{} "Account is inactive"
+// @Failure      423 {object} map[string]interface{} "Account temporarily locked"
+// @Failure      429 {object} map[string]interface{} "Too many failed login attempts"
 // @Router       /auth/login [post]
 func (s *authService) Login(c *fiber.Ctx) error {
 	var req LoginRequest
@@ -72,53 +108,76 @@ func (s *authService) Login(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Invalid credentials")
 	}
 
-	// Check if user is active
-	if !user.IsActive {
-		return utils.ErrorResponse(c, fiber.StatusForbidden, "Account is inactive")
+	// Failed attempts are tracked per account, or per identifier when no account matches
+	attemptKey := repository.IdentifierLoginKey(req.Username)
+	var attemptUserID *uuid.UUID
+	if user.ID != uuid.Nil {
+		attemptKey = repository.UserLoginKey(user.ID)
+		attemptUserID = &user.ID
+	}
+
+	if ok, err := s.checkLoginThrottle(c, attemptKey, attemptUserID); !ok {
+		return err
+	}
+
+	if user.ID == uuid.Nil {
+		s.recordLoginFailure(c, attemptKey, nil)
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Invalid credentials")
 	}
 
 	// Verify password
 	if !utils.CheckPassword(req.Password, user.PasswordHash) {
+		s.recordLoginFailure(c, attemptKey, attemptUserID)
 		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Invalid credentials")
 	}
 
+	// The password is right, which ends the attempt whether or not the account may sign in here
+	if err := s.loginAttemptRepo.Reset(attemptKey); err != nil {
+		utils.GlobalLogger.Error("Failed to reset login attempts", err, map[string]interface{}{
+			"identifier": attemptKey,
+		})
+	}
+
+	// Federated accounts sign in at the identity provider only; this is told only to
+	// callers that already know the password, so it does not reveal how an account signs in
+	if user.AuthProvider == models.AuthProviderOIDC {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "This account signs in with single sign-on")
+	}
+
+	// Check if user is active
+	if !user.IsActive {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "Account is inactive")
+	}
+
+	return s.finishLogin(c, user)
+}
+
+// finishLogin issues tokens, or an MFA challenge for accounts with two-factor authentication
+func (s *authService) finishLogin(c *fiber.Ctx, user *models.User) error {
+	// Accounts with two-factor authentication finish the login at /auth/mfa/verify
+	if user.MFAEnabled || s.mfaRequired(user) {
+		return s.mfaChallenge(c, user)
+	}
+
+	return s.completeLogin(c, user, "Login successful", nil)
+}
+
+// completeLogin starts a session for an authenticated user and writes the login response.
+// Entries in extra are added to the response data.
+func (s *authService) completeLogin(c *fiber.Ctx, user *models.User, message string, extra fiber.Map) error {
 	// Get user permissions
 	permissions, err := s.userRepo.GetUserPermissions(user.RoleID)
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to load permissions")
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateToken(
-		user.ID,
-		user.Username,
-		user.Email,
-		user.RoleID,
-		user.Role.Name,
-		permissions,
-		s.cfg.JWTSecret,
-		s.cfg.JWTExpiresIn,
-	)
+	// Start a new refresh token family for this login
+	token, refreshToken, err := s.startSession(c, user)
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to generate token")
 	}
 
-	// Generate refresh token
-	refreshToken, err := utils.GenerateToken(
-		user.ID,
-		user.Username,
-		user.Email,
-		user.RoleID,
-		user.Role.Name,
-		permissions,
-		s.cfg.JWTRefreshSecret,
-		s.cfg.JWTRefreshExpiresIn,
-	)
-	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to generate refresh token")
-	}
-
-	return utils.SuccessResponse(c, "Login successful", fiber.Map{
+	data := fiber.Map{
 		"token":         token,
 		"refresh_token": refreshToken,
 		"user": fiber.Map{
@@ -129,17 +188,22 @@ func (s *authService) Login(c *fiber.Ctx) error {
 			"role":        user.Role.Name,
 			"permissions": permissions,
 		},
-	})
+	}
+	for key, value := range extra {
+		data[key] = value
+	}
+
+	return utils.SuccessResponse(c, message, data)
 }
 
 // RefreshToken godoc
 // @Summary      Refresh access token
-// @Description  Generate new access token using refresh token
+// @Description  Rotate the refresh token and generate a new access token for the user's current role. Reusing an already-rotated refresh token revokes the whole session.
 // @Tags         Authentication
 // @Accept       json
 // @Produce      json
 // @Param        request body RefreshTokenRequest true "Refresh token"
-// @Success      200 {object} map[string]interface{} "New access token generated"
+// @Success      200 {object} map[string]interface{} "New access token and rotated refresh token generated"
 // @Failure      400 {object} map[string]interface{} "Invalid request body"
 // @Failure      401 {object} map[string]interface{} "Invalid or expired refresh token"
 // @Router       /auth/refresh [post]
@@ -154,30 +218,123 @@ func (s *authService) RefreshToken(c *fiber.Ctx) error {
 		return utils.ValidationErrorResponse(c, err)
 	}
 
-	// Validate refresh token
-	claims, err := utils.ValidateToken(req.RefreshToken, s.cfg.JWTRefreshSecret)
+	// Validate refresh token and make sure it has not been revoked
+	claims, err := s.tokenService.VerifyRefreshToken(req.RefreshToken)
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Invalid refresh token")
 	}
 
-	// Generate new access token
-	token, err := utils.GenerateToken(
-		claims.UserID,
-		claims.Username,
-		claims.Email,
-		claims.RoleID,
-		claims.RoleName,
-		claims.Permissions,
-		s.cfg.JWTSecret,
-		s.cfg.JWTExpiresIn,
-	)
+	// Refresh tokens must belong to a known session
+	sessionID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Refresh token is not bound to a session, please log in again")
+	}
+
+	session, err := s.sessionRepo.FindByID(sessionID)
+	if err != nil || session.UserID != claims.UserID {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Session not found, please log in again")
+	}
+	if session.RevokedAt != nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Session has been revoked")
+	}
+
+	// An already-rotated refresh token is being replayed: revoke the whole family
+	if session.RefreshJTI != claims.ID {
+		return s.handleRefreshReuse(c, session)
+	}
+
+	// Build the new tokens from the user's current state, not from the old claims
+	user, err := s.userRepo.FindByID(claims.UserID)
+	if err != nil || user.ID == uuid.Nil || !user.IsActive {
+		s.tokenService.RevokeSession(session.ID, session.UserID, "account_inactive")
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Account is inactive or no longer exists")
+	}
+
+	token, refreshToken, refreshClaims, err := s.issueTokenPair(user, session.ID)
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to generate token")
 	}
 
+	// Rotate only if no concurrent refresh got there first
+	rotated, err := s.sessionRepo.Rotate(session.ID, claims.ID, refreshClaims.ID, refreshClaims.ExpiresAt.Time)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to rotate refresh token")
+	}
+	if !rotated {
+		return s.handleRefreshReuse(c, session)
+	}
+
 	return utils.SuccessResponse(c, "Token refreshed", fiber.Map{
-		"token": token,
+		"token":         token,
+		"refresh_token": refreshToken,
+	})
+}
+
+// handleRefreshReuse revokes a refresh token family after a replayed refresh token
+func (s *authService) handleRefreshReuse(c *fiber.Ctx, session *models.Session) error {
+	utils.GlobalLogger.LogAuth("refresh_token_reuse", session.UserID.String(), false, map[string]interface{}{
+		"session_id": session.ID,
+		"ip":         c.IP(),
 	})
+
+	if err := s.tokenService.RevokeSession(session.ID, session.UserID, "refresh_token_reuse"); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to revoke session")
+	}
+
+	return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Refresh token reuse detected, session has been revoked")
+}
+
+// startSession creates a new refresh token family for the requesting device and returns its first token pair
+func (s *authService) startSession(c *fiber.Ctx, user *models.User) (string, string, error) {
+	sessionID := uuid.New()
+
+	token, refreshToken, refreshClaims, err := s.issueTokenPair(user, sessionID)
+	if err != nil {
+		return "", "", err
+	}
+
+	session := &models.Session{
+		ID:         sessionID,
+		UserID:     user.ID,
+		RefreshJTI: refreshClaims.ID,
+		UserAgent:  truncate(c.Get("User-Agent"), 255),
+		IPAddress:  c.IP(),
+		ExpiresAt:  refreshClaims.ExpiresAt.Time,
+	}
+	if err := s.sessionRepo.Create(session); err != nil {
+		return "", "", err
+	}
+
+	return token, refreshToken, nil
+}
+
+// issueTokenPair signs an access token and a refresh token bound to the given session
+func (s *authService) issueTokenPair(user *models.User, sessionID uuid.UUID) (string, string, *utils.JWTClaims, error) {
+	accessClaims := buildUserClaims(user, sessionID)
+	token, err := s.tokenService.GenerateAccessToken(accessClaims)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	refreshClaims := buildUserClaims(user, sessionID)
+	refreshToken, err := s.tokenService.GenerateRefreshToken(refreshClaims)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	return token, refreshToken, refreshClaims, nil
+}
+
+// buildUserClaims creates token claims for the user's identity and the permission version of their role.
+// Permissions themselves are resolved per request and never embedded in the token.
+func buildUserClaims(user *models.User, sessionID uuid.UUID) *utils.JWTClaims {
+	return &utils.JWTClaims{
+		UserID:            user.ID,
+		Username:          user.Username,
+		Email:             user.Email,
+		PermissionVersion: user.Role.PermissionVersion,
+		SessionID:         sessionID.String(),
+	}
 }
 
 // GetProfile godoc
@@ -204,27 +361,63 @@ func (s *authService) GetProfile(c *fiber.Ctx) error {
 	// Get permissions
 	permissions, _ := s.userRepo.GetUserPermissions(user.RoleID)
 
-	return utils.SuccessResponse(c, "Profile retrieved", fiber.Map{
+	profile := fiber.Map{
 		"id":          user.ID.String(),
 		"username":    user.Username,
 		"full_name":   user.FullName,
 		"email":       user.Email,
 		"role":        user.Role.Name,
 		"permissions": permissions,
-	})
+	}
+	if claims.Impersonating() {
+		profile["impersonated_by"] = claims.Actor
+	}
+
+	return utils.SuccessResponse(c, "Profile retrieved", profile)
 }
 
 // Logout godoc
 // @Summary      Logout user
-// @Description  Logout authenticated user
+// @Description  Logout authenticated user and revoke all of their outstanding access and refresh tokens. With an impersonation token only that token is revoked, ending the impersonation.
 // @Tags         Authentication
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
 // @Success      200 {object} map[string]interface{} "Logged out successfully"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      500 {object} map[string]interface{} "Failed to revoke tokens"
 // @Router       /auth/logout [post]
 func (s *authService) Logout(c *fiber.Ctx) error {
-	// For JWT, logout is handled on client-side
-	// You can implement token blacklisting here if needed
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	// Ending an impersonation must not log the impersonated user out
+	if claims.Impersonating() {
+		if err := s.tokenService.RevokeToken(claims); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to revoke token")
+		}
+
+		utils.GlobalLogger.LogAuth("impersonation_ended", claims.Actor.UserID.String(), true, map[string]interface{}{
+			"target_user_id": claims.UserID,
+			"token_id":       claims.ID,
+		})
+		return utils.SuccessResponse(c, "Impersonation ended", nil)
+	}
+
+	if err := s.tokenService.RevokeUserTokens(claims.UserID); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to revoke tokens")
+	}
+
+	utils.GlobalLogger.LogAuth("logout", claims.UserID.String(), true)
 	return utils.SuccessResponse(c, "Logged out successfully", nil)
 }
+
+// truncate shortens s to at most max bytes
+func truncate(s string, max int) string {
+	if len(s) > max {
+		return s[:max]
+	}
+	return s
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"fmt"
+	"student-achievement-system/authz"
+	"student-achievement-system/middleware"
+	"student-achievement-system/models"
+	"student-achievement-system/repository"
+	"student-achievement-system/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// achievementAccess is an achievement the caller has been authorized to act on
+type achievementAccess struct {
+	ref     *models.AchievementReference
+	student *models.Student
+	subject authz.Subject
+}
+
+// authzSubject describes the authenticated caller, with their student or lecturer profile
+// and the advisor delegations in force that they act on
+func authzSubject(
+	claims *utils.JWTClaims,
+	studentRepo repository.StudentRepository,
+	lecturerRepo repository.LecturerRepository,
+	delegationRepo repository.AdvisorDelegationRepository,
+) authz.Subject {
+	subject := authz.Subject{
+		UserID:   claims.UserID,
+		RoleName: claims.RoleName,
+		Service:  claims.APIKeyID != "",
+	}
+	if subject.Service {
+		return subject
+	}
+
+	if student, err := studentRepo.FindByUserID(claims.UserID); err == nil && student.ID != uuid.Nil {
+		subject.StudentID = student.ID
+	}
+	if lecturer, err := lecturerRepo.FindByUserID(claims.UserID); err == nil && lecturer.ID != uuid.Nil {
+		subject.LecturerID = lecturer.ID
+		subject.Department = lecturer.Department
+
+		delegations, err := delegationRepo.FindActiveByDelegate(lecturer.ID, time.Now())
+		if err != nil {
+			utils.GlobalLogger.Error("Failed to load advisor delegations", err, map[string]interface{}{
+				"lecturer_id": lecturer.ID,
+			})
+		}
+		for _, delegation := range delegations {
+			scope := uuid.Nil
+			if delegation.StudentID != nil {
+				scope = *delegation.StudentID
+			}
+			subject.Delegations = append(subject.Delegations, authz.Delegation{AdvisorID: delegation.DelegatorID, OwnerID: scope})
+		}
+	}
+	return subject
+}
+
+// ownedBy describes a resource belonging to a student
+func ownedBy(kind authz.ResourceKind, student *models.Student) authz.Resource {
+	return authz.Resource{
+		Kind:       kind,
+		OwnerID:    student.ID,
+		AdvisorID:  student.AdvisorID,
+		Department: student.ProgramStudy,
+	}
+}
+
+// achievementResource describes an achievement, with the approval stage it waits in
+func achievementResource(ref *models.AchievementReference, student *models.Student) authz.Resource {
+	resource := ownedBy(authz.KindAchievement, student)
+	resource.Stage = stageOf(ref.CurrentStage)
+	return resource
+}
+
+// onBehalfOf returns the user of the advisor for whom the caller decides the achievement
+// because the advisor delegated to them, nil when they decide it in their own right
+func onBehalfOf(access *achievementAccess) *uuid.UUID {
+	_, delegated := authz.OnBehalfOf(access.subject, achievementResource(access.ref, access.student))
+	if !delegated || access.student.Advisor == nil || access.student.Advisor.UserID == uuid.Nil {
+		return nil
+	}
+	advisorUserID := access.student.Advisor.UserID
+	return &advisorUserID
+}
+
+// forbidden writes the response for a denied authorization decision
+func forbidden(c *fiber.Ctx, action authz.Action, kind authz.ResourceKind) error {
+	return utils.ErrorResponse(c, fiber.StatusForbidden, forbiddenMessage(action, kind))
+}
+
+// forbiddenMessage explains a denied authorization decision
+func forbiddenMessage(action authz.Action, kind authz.ResourceKind) string {
+	return fmt.Sprintf("You are not allowed to %s this %s", action, kind)
+}
+
+// authorizeAchievement loads the achievement named by the id route parameter and checks
+// that the caller may perform action on it. When it returns nil the error response has
+// already been written.
+func authorizeAchievement(
+	c *fiber.Ctx,
+	action authz.Action,
+	achievementRefRepo repository.AchievementReferenceRepository,
+	studentRepo repository.StudentRepository,
+	lecturerRepo repository.LecturerRepository,
+	delegationRepo repository.AdvisorDelegationRepository,
+) (*achievementAccess, error) {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return nil, utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	access, err := loadAchievementAccess(claims, c.Params("id"), action, achievementRefRepo, studentRepo, lecturerRepo, delegationRepo)
+	if err != nil {
+		return nil, respondError(c, err)
+	}
+	return access, nil
+}
+
+// loadAchievementAccess is authorizeAchievement for the achievement with the given
+// MongoDB ID, returning a requestError instead of writing the response
+func loadAchievementAccess(
+	claims *utils.JWTClaims,
+	id string,
+	action authz.Action,
+	achievementRefRepo repository.AchievementReferenceRepository,
+	studentRepo repository.StudentRepository,
+	lecturerRepo repository.LecturerRepository,
+	delegationRepo repository.AdvisorDelegationRepository,
+) (*achievementAccess, error) {
+	ref, err := achievementRefRepo.FindByMongoID(id)
+	if err != nil || ref.ID == uuid.Nil || ref.Status == models.StatusDeleted {
+		return nil, failed(fiber.StatusNotFound, "Achievement not found")
+	}
+
+	student, err := studentRepo.FindByID(ref.StudentID)
+	if err != nil || student.ID == uuid.Nil {
+		return nil, failed(fiber.StatusNotFound, "Student not found")
+	}
+
+	subject := authzSubject(claims, studentRepo, lecturerRepo, delegationRepo)
+	if !authz.Can(subject, action, achievementResource(ref, student)) {
+		return nil, failed(fiber.StatusForbidden, forbiddenMessage(action, authz.KindAchievement))
+	}
+
+	return &achievementAccess{ref: ref, student: student, subject: subject}, nil
+}
+
+// readableStudents returns the students whose achievements a caller who may not list all
+// achievements can read: their own profile, their advisees, those of the advisors they
+// stand in for and their department.
+func readableStudents(
+	subject authz.Subject,
+	studentRepo repository.StudentRepository,
+) ([]models.Student, error) {
+	candidates := make([]models.Student, 0)
+	if subject.StudentID != uuid.Nil {
+		student, err := studentRepo.FindByID(subject.StudentID)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, *student)
+	}
+	if subject.LecturerID != uuid.Nil {
+		advisees, err := studentRepo.FindByAdvisorID(subject.LecturerID)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, advisees...)
+	}
+	for _, delegation := range subject.Delegations {
+		advisees, err := studentRepo.FindByAdvisorID(delegation.AdvisorID)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, advisees...)
+	}
+	if subject.Department != "" {
+		departmentStudents, err := studentRepo.FindByProgramStudy(subject.Department)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, departmentStudents...)
+	}
+
+	seen := make(map[uuid.UUID]bool, len(candidates))
+	students := make([]models.Student, 0, len(candidates))
+	for _, student := range candidates {
+		if seen[student.ID] || !authz.Can(subject, authz.ActionRead, ownedBy(authz.KindAchievement, &student)) {
+			continue
+		}
+		seen[student.ID] = true
+		students = append(students, student)
+	}
+	return students, nil
+}
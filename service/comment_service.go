@@ -0,0 +1,384 @@
+package service
+
+import (
+	"context"
+	"student-achievement-system/authz"
+	"student-achievement-system/config"
+	"student-achievement-system/middleware"
+	"student-achievement-system/models"
+	"student-achievement-system/repository"
+	"student-achievement-system/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type CommentService interface {
+	ListComments(c *fiber.Ctx) error
+	CreateComment(c *fiber.Ctx) error
+	UpdateComment(c *fiber.Ctx) error
+	DeleteComment(c *fiber.Ctx) error
+}
+
+type CommentRequest struct {
+	Body          string `json:"body" validate:"required,max=5000"`
+	AttachmentURL string `json:"attachment_url,omitempty" validate:"max=255"`
+	Internal      bool   `json:"internal"`
+}
+
+type UpdateCommentRequest struct {
+	Body          string `json:"body" validate:"required,max=5000"`
+	AttachmentURL string `json:"attachment_url,omitempty" validate:"max=255"`
+}
+
+type commentService struct {
+	achievementRepo    repository.AchievementRepository
+	achievementRefRepo repository.AchievementReferenceRepository
+	studentRepo        repository.StudentRepository
+	lecturerRepo       repository.LecturerRepository
+	delegationRepo     repository.AdvisorDelegationRepository
+	userRepo           repository.UserRepository
+	commentRepo        repository.AchievementCommentRepository
+	notificationRepo   repository.NotificationRepository
+	auditRepo          repository.AuditRepository
+	cfg                *config.Config
+}
+
+func NewCommentService(
+	achievementRepo repository.AchievementRepository,
+	achievementRefRepo repository.AchievementReferenceRepository,
+	studentRepo repository.StudentRepository,
+	lecturerRepo repository.LecturerRepository,
+	delegationRepo repository.AdvisorDelegationRepository,
+	userRepo repository.UserRepository,
+	commentRepo repository.AchievementCommentRepository,
+	notificationRepo repository.NotificationRepository,
+	auditRepo repository.AuditRepository,
+	cfg *config.Config,
+) CommentService {
+	return &commentService{
+		achievementRepo:    achievementRepo,
+		achievementRefRepo: achievementRefRepo,
+		studentRepo:        studentRepo,
+		lecturerRepo:       lecturerRepo,
+		delegationRepo:     delegationRepo,
+		userRepo:           userRepo,
+		commentRepo:        commentRepo,
+		notificationRepo:   notificationRepo,
+		auditRepo:          auditRepo,
+		cfg:                cfg,
+	}
+}
+
+// seesInternal reports whether the caller may read internal comments, which are kept
+// between the staff reviewing an achievement. API keys are not staff.
+func seesInternal(subject authz.Subject) bool {
+	return subject.StudentID == uuid.Nil && !subject.Service
+}
+
+// ListComments godoc
+// @Summary      List achievement comments
+// @Description  Get the discussion thread of an achievement, oldest first. Internal comments are only shown to staff.
+// @Tags         Achievements
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path     string  true  "Achievement ID (MongoDB ObjectID)"
+// @Success      200 {object} map[string]interface{} "Achievement comments"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      404 {object} map[string]interface{} "Achievement not found"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /achievements/{id}/comments [get]
+func (s *commentService) ListComments(c *fiber.Ctx) error {
+	access, err := authorizeAchievement(c, authz.ActionRead, s.achievementRefRepo, s.studentRepo, s.lecturerRepo, s.delegationRepo)
+	if access == nil {
+		return err
+	}
+
+	comments, err := s.commentRepo.FindByRefID(access.ref.ID, seesInternal(access.subject))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to get comments")
+	}
+
+	return utils.SuccessResponse(c, "Comments retrieved successfully", fiber.Map{
+		"achievement_id": c.Params("id"),
+		"comments":       comments,
+	})
+}
+
+// CreateComment godoc
+// @Summary      Comment on achievement
+// @Description  Add a comment to the discussion thread of an achievement, optionally referring to one of its attachments. The student, their advisor and the approvers of the current stage can comment; staff can mark a comment internal to hide it from the student. The other participants are notified.
+// @Tags         Achievements
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path     string          true  "Achievement ID (MongoDB ObjectID)"
+// @Param        request  body     CommentRequest  true  "Comment"
+// @Success      200 {object} map[string]interface{} "Comment created successfully"
+// @Failure      400 {object} map[string]interface{} "Invalid request body, validation failed or unknown attachment"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      404 {object} map[string]interface{} "Achievement not found"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /achievements/{id}/comments [post]
+func (s *commentService) CreateComment(c *fiber.Ctx) error {
+	id := c.Params("id")
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	var req CommentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.ValidationErrorResponse(c, err)
+	}
+
+	access, err := authorizeAchievement(c, authz.ActionComment, s.achievementRefRepo, s.studentRepo, s.lecturerRepo, s.delegationRepo)
+	if access == nil {
+		return err
+	}
+	if req.Internal && !seesInternal(access.subject) {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "Students cannot post internal comments")
+	}
+
+	achievement, err := s.achievementRepo.FindByID(context.Background(), id)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Achievement not found")
+	}
+	if !hasAttachment(achievement, req.AttachmentURL) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "attachment_url must refer to an attachment of the achievement")
+	}
+
+	comment := &models.AchievementComment{
+		AchievementRefID: access.ref.ID,
+		AuthorID:         claims.UserID,
+		AuthorName:       claims.Username,
+		AuthorRole:       claims.RoleName,
+		Body:             req.Body,
+		AttachmentURL:    req.AttachmentURL,
+		Internal:         req.Internal,
+		CreatedAt:        time.Now(),
+	}
+	if claims.Impersonating() {
+		comment.AuthorName = claims.Actor.Username + " as " + claims.Username
+	}
+
+	if err := s.commentRepo.Create(comment); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create comment")
+	}
+	markViewed(s.achievementRefRepo, access)
+
+	RecordAudit(c, s.auditRepo, "achievement.comment", "achievement", id, fiber.Map{
+		"comment_id": comment.ID,
+		"internal":   comment.Internal,
+	})
+
+	for _, userID := range s.commentRecipients(access, comment) {
+		CreateNotification(
+			s.notificationRepo,
+			userID,
+			models.NotificationTypeAchievementComment,
+			"New Comment on Achievement",
+			comment.AuthorName+" commented on '"+achievement.Title+"'",
+			fiber.Map{
+				"achievement_id": id,
+				"comment_id":     comment.ID,
+				"author_name":    comment.AuthorName,
+				"internal":       comment.Internal,
+			},
+		)
+	}
+
+	return utils.SuccessResponse(c, "Comment created successfully", comment)
+}
+
+// UpdateComment godoc
+// @Summary      Edit achievement comment
+// @Description  Change the text or attachment reference of a comment. Only its author can edit a comment, within COMMENT_EDIT_WINDOW of writing it.
+// @Tags         Achievements
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id         path     string                true  "Achievement ID (MongoDB ObjectID)"
+// @Param        commentId  path     string                true  "Comment ID (UUID)"
+// @Param        request    body     UpdateCommentRequest  true  "New comment text"
+// @Success      200 {object} map[string]interface{} "Comment updated successfully"
+// @Failure      400 {object} map[string]interface{} "Invalid request body, validation failed or unknown attachment"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Not the author, or the edit window has passed"
+// @Failure      404 {object} map[string]interface{} "Achievement or comment not found"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /achievements/{id}/comments/{commentId} [put]
+func (s *commentService) UpdateComment(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	var req UpdateCommentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.ValidationErrorResponse(c, err)
+	}
+
+	access, err := authorizeAchievement(c, authz.ActionComment, s.achievementRefRepo, s.studentRepo, s.lecturerRepo, s.delegationRepo)
+	if access == nil {
+		return err
+	}
+
+	comment, err := s.findComment(c, access)
+	if comment == nil {
+		return err
+	}
+	if comment.AuthorID != access.subject.UserID {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "Only the author can edit a comment")
+	}
+	if time.Since(comment.CreatedAt) > s.cfg.CommentEditWindow {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "Comments can only be edited within "+s.cfg.CommentEditWindow.String()+" of writing them")
+	}
+
+	achievement, err := s.achievementRepo.FindByID(context.Background(), id)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Achievement not found")
+	}
+	if !hasAttachment(achievement, req.AttachmentURL) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "attachment_url must refer to an attachment of the achievement")
+	}
+
+	before := *comment
+	now := time.Now()
+	comment.Body = req.Body
+	comment.AttachmentURL = req.AttachmentURL
+	comment.EditedAt = &now
+
+	if err := s.commentRepo.Update(comment); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update comment")
+	}
+
+	changes := auditChanges(
+		fiber.Map{"body": before.Body, "attachment_url": before.AttachmentURL},
+		fiber.Map{"body": comment.Body, "attachment_url": comment.AttachmentURL},
+	)
+	changes["comment_id"] = comment.ID
+	RecordAudit(c, s.auditRepo, "achievement.edit_comment", "achievement", id, changes)
+
+	return utils.SuccessResponse(c, "Comment updated successfully", comment)
+}
+
+// DeleteComment godoc
+// @Summary      Delete achievement comment
+// @Description  Remove a comment from the discussion thread. Authors can delete their own comments and admins any comment.
+// @Tags         Achievements
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id         path     string  true  "Achievement ID (MongoDB ObjectID)"
+// @Param        commentId  path     string  true  "Comment ID (UUID)"
+// @Success      200 {object} map[string]interface{} "Comment deleted successfully"
+// @Failure      400 {object} map[string]interface{} "Invalid comment ID"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      404 {object} map[string]interface{} "Achievement or comment not found"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /achievements/{id}/comments/{commentId} [delete]
+func (s *commentService) DeleteComment(c *fiber.Ctx) error {
+	access, err := authorizeAchievement(c, authz.ActionComment, s.achievementRefRepo, s.studentRepo, s.lecturerRepo, s.delegationRepo)
+	if access == nil {
+		return err
+	}
+
+	comment, err := s.findComment(c, access)
+	if comment == nil {
+		return err
+	}
+	if comment.AuthorID != access.subject.UserID && !authz.Admin(access.subject, authz.Resource{}) {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "Only the author or an admin can delete a comment")
+	}
+
+	if err := s.commentRepo.Delete(comment.ID); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete comment")
+	}
+
+	RecordAudit(c, s.auditRepo, "achievement.delete_comment", "achievement", c.Params("id"), fiber.Map{
+		"comment_id": comment.ID,
+		"author_id":  comment.AuthorID,
+		"body":       comment.Body,
+	})
+
+	return utils.SuccessResponse(c, "Comment deleted successfully", nil)
+}
+
+// findComment loads the comment named by the :commentId route parameter on the
+// achievement. Internal comments are not found for students. On failure it writes the
+// error response and returns a nil comment.
+func (s *commentService) findComment(c *fiber.Ctx, access *achievementAccess) (*models.AchievementComment, error) {
+	commentID, err := uuid.Parse(c.Params("commentId"))
+	if err != nil {
+		return nil, utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid comment ID")
+	}
+
+	comment, err := s.commentRepo.FindByID(commentID)
+	if err != nil {
+		return nil, utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to get comment")
+	}
+	if comment == nil || comment.AchievementRefID != access.ref.ID || (comment.Internal && !seesInternal(access.subject)) {
+		return nil, utils.ErrorResponse(c, fiber.StatusNotFound, "Comment not found")
+	}
+	return comment, nil
+}
+
+// hasAttachment reports whether url is empty or names an attachment of the achievement
+func hasAttachment(achievement *models.Achievement, url string) bool {
+	if url == "" {
+		return true
+	}
+	for _, attachment := range achievement.Attachments {
+		if attachment.FileURL == url {
+			return true
+		}
+	}
+	return false
+}
+
+// commentRecipients returns the users to notify of a new comment: the advisor, the
+// approvers of the stage the achievement waits in and earlier commenters, plus the
+// student unless the comment is internal. The author is left out.
+func (s *commentService) commentRecipients(access *achievementAccess, comment *models.AchievementComment) []uuid.UUID {
+	candidates := make([]uuid.UUID, 0)
+	if access.student.AdvisorID != nil {
+		advisor, err := s.lecturerRepo.FindByID(*access.student.AdvisorID)
+		if err == nil && advisor.ID != uuid.Nil {
+			candidates = append(candidates, advisor.UserID)
+		}
+	}
+	if access.ref.CurrentStage != nil {
+		candidates = append(candidates, stageApprovers(access.ref.CurrentStage, access.student, s.userRepo, s.lecturerRepo, s.delegationRepo)...)
+	}
+	participants, err := s.commentRepo.FindParticipants(access.ref.ID)
+	if err != nil {
+		utils.GlobalLogger.Error("Failed to load comment participants", err, map[string]interface{}{
+			"achievement_ref_id": access.ref.ID,
+		})
+	}
+	candidates = append(candidates, participants...)
+	if !comment.Internal {
+		candidates = append(candidates, access.student.UserID)
+	}
+
+	seen := map[uuid.UUID]bool{comment.AuthorID: true}
+	if comment.Internal {
+		seen[access.student.UserID] = true
+	}
+	recipients := make([]uuid.UUID, 0, len(candidates))
+	for _, userID := range candidates {
+		if seen[userID] {
+			continue
+		}
+		seen[userID] = true
+		recipients = append(recipients, userID)
+	}
+	return recipients
+}
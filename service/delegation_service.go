@@ -0,0 +1,306 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"student-achievement-system/authz"
+	"student-achievement-system/middleware"
+	"student-achievement-system/models"
+	"student-achievement-system/repository"
+	"student-achievement-system/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type DelegationService interface {
+	ListDelegations(c *fiber.Ctx) error
+	CreateDelegation(c *fiber.Ctx) error
+	RevokeDelegation(c *fiber.Ctx) error
+}
+
+type DelegationRequest struct {
+	DelegatorID string `json:"delegator_id,omitempty"` // admins only; lecturers delegate their own advisees
+	DelegateID  string `json:"delegate_id" validate:"required,uuid"`
+	StudentID   string `json:"student_id,omitempty" validate:"omitempty,uuid"`
+	StartDate   string `json:"start_date" validate:"required"`
+	EndDate     string `json:"end_date" validate:"required"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+type delegationService struct {
+	delegationRepo   repository.AdvisorDelegationRepository
+	studentRepo      repository.StudentRepository
+	lecturerRepo     repository.LecturerRepository
+	notificationRepo repository.NotificationRepository
+	auditRepo        repository.AuditRepository
+}
+
+func NewDelegationService(
+	delegationRepo repository.AdvisorDelegationRepository,
+	studentRepo repository.StudentRepository,
+	lecturerRepo repository.LecturerRepository,
+	notificationRepo repository.NotificationRepository,
+	auditRepo repository.AuditRepository,
+) DelegationService {
+	return &delegationService{
+		delegationRepo:   delegationRepo,
+		studentRepo:      studentRepo,
+		lecturerRepo:     lecturerRepo,
+		notificationRepo: notificationRepo,
+		auditRepo:        auditRepo,
+	}
+}
+
+// ListDelegations godoc
+// @Summary      List advisor delegations
+// @Description  Get the advisor delegations given or received by the caller, newest first. Admins see every delegation, or those of one lecturer with lecturer_id.
+// @Tags         Delegations
+// @Produce      json
+// @Security     BearerAuth
+// @Param        lecturer_id  query    string  false  "Lecturer ID (UUID), admins only"
+// @Param        page         query    int     false  "Page number (default 1)"
+// @Param        limit        query    int     false  "Items per page (default 10, max 100)"
+// @Success      200 {object} map[string]interface{} "Delegations with pagination"
+// @Failure      400 {object} map[string]interface{} "Invalid lecturer ID"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Forbidden - not a lecturer"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /delegations [get]
+func (s *delegationService) ListDelegations(c *fiber.Ctx) error {
+	subject, err := s.caller(c)
+	if subject == nil {
+		return err
+	}
+
+	lecturerID := subject.LecturerID
+	if authz.Admin(*subject, authz.Resource{}) {
+		lecturerID = uuid.Nil
+		if filter := c.Query("lecturer_id"); filter != "" {
+			if lecturerID, err = uuid.Parse(filter); err != nil {
+				return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid lecturer ID")
+			}
+		}
+	}
+
+	pagination := utils.GetPaginationParams(c)
+	delegations, total, err := s.delegationRepo.FindAll(lecturerID, pagination.Offset, pagination.Limit)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to get delegations")
+	}
+
+	now := time.Now()
+	items := make([]fiber.Map, 0, len(delegations))
+	for i := range delegations {
+		items = append(items, fiber.Map{
+			"delegation": delegations[i],
+			"active":     delegations[i].Active(now),
+		})
+	}
+
+	return utils.PaginatedResponse(c, fiber.Map{
+		"delegations": items,
+	}, total, pagination.Page, pagination.Limit)
+}
+
+// CreateDelegation godoc
+// @Summary      Delegate advisees to another lecturer
+// @Description  Let another lecturer act as advisor of the caller's advisees from start_date to end_date inclusive (YYYY-MM-DD), e.g. during leave. The delegate may then read, comment on and verify or reject their achievements, and decisions record that they were taken on behalf of the advisor. A student_id limits the delegation to one advisee. Admins may delegate for any lecturer with delegator_id.
+// @Tags         Delegations
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body     DelegationRequest  true  "Delegate, period and optional scope"
+// @Success      200 {object} map[string]interface{} "Delegation created successfully"
+// @Failure      400 {object} map[string]interface{} "Invalid request body or validation failed"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Forbidden - not a lecturer"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /delegations [post]
+func (s *delegationService) CreateDelegation(c *fiber.Ctx) error {
+	subject, err := s.caller(c)
+	if subject == nil {
+		return err
+	}
+
+	var req DelegationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.ValidationErrorResponse(c, err)
+	}
+
+	// Lecturers delegate their own advisees; admins name the advisor
+	delegatorID := subject.LecturerID
+	if authz.Admin(*subject, authz.Resource{}) && req.DelegatorID != "" {
+		if delegatorID, err = uuid.Parse(req.DelegatorID); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid delegator ID")
+		}
+	}
+	if delegatorID == uuid.Nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "delegator_id is required without a lecturer profile")
+	}
+	delegator, err := s.lecturerRepo.FindByID(delegatorID)
+	if err != nil || delegator.ID == uuid.Nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Delegating lecturer not found")
+	}
+
+	delegateID := uuid.MustParse(req.DelegateID)
+	if delegateID == delegatorID {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "A lecturer cannot delegate to themselves")
+	}
+	delegate, err := s.lecturerRepo.FindByID(delegateID)
+	if err != nil || delegate.ID == uuid.Nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Delegate lecturer not found")
+	}
+
+	startsAt, err := time.ParseInLocation("2006-01-02", req.StartDate, time.Local)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid start_date, expected YYYY-MM-DD")
+	}
+	endDate, err := time.ParseInLocation("2006-01-02", req.EndDate, time.Local)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid end_date, expected YYYY-MM-DD")
+	}
+	// The end date is the last day of the delegation
+	endsAt := endDate.AddDate(0, 0, 1)
+	if !endsAt.After(startsAt) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "end_date must not be before start_date")
+	}
+	now := time.Now()
+	if !endsAt.After(now) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "end_date must not be in the past")
+	}
+
+	delegation := &models.AdvisorDelegation{
+		DelegatorID: delegator.ID,
+		DelegateID:  delegate.ID,
+		StartsAt:    startsAt,
+		EndsAt:      endsAt,
+		Reason:      strings.TrimSpace(req.Reason),
+		CreatedBy:   subject.UserID,
+		CreatedAt:   now,
+	}
+
+	scope := "their advisees"
+	if req.StudentID != "" {
+		studentID := uuid.MustParse(req.StudentID)
+		student, err := s.studentRepo.FindByID(studentID)
+		if err != nil || student.ID == uuid.Nil || student.AdvisorID == nil || *student.AdvisorID != delegator.ID {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "The student is not an advisee of the delegating lecturer")
+		}
+		delegation.StudentID = &studentID
+		scope = "their advisee " + student.User.FullName
+	}
+
+	if err := s.delegationRepo.Create(delegation); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create delegation")
+	}
+
+	RecordAudit(c, s.auditRepo, "delegation.create", "advisor_delegation", delegation.ID.String(), auditChanges(nil, delegation))
+
+	CreateNotification(
+		s.notificationRepo,
+		delegate.UserID,
+		models.NotificationTypeAdvisorDelegation,
+		"Advisees Delegated to You",
+		fmt.Sprintf("%s delegated %s to you from %s to %s", delegator.User.FullName, scope, req.StartDate, req.EndDate),
+		fiber.Map{
+			"delegation_id": delegation.ID,
+			"delegator_id":  delegator.ID,
+			"student_id":    delegation.StudentID,
+			"starts_at":     delegation.StartsAt,
+			"ends_at":       delegation.EndsAt,
+		},
+	)
+
+	created, _ := s.delegationRepo.FindByID(delegation.ID)
+	if created == nil {
+		created = delegation
+	}
+	return utils.SuccessResponse(c, "Delegation created successfully", created)
+}
+
+// RevokeDelegation godoc
+// @Summary      Revoke advisor delegation
+// @Description  End a delegation early, e.g. when the advisor returns. Only the delegating lecturer or an admin may revoke it.
+// @Tags         Delegations
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path     string  true  "Delegation ID (UUID)"
+// @Success      200 {object} map[string]interface{} "Delegation revoked successfully"
+// @Failure      400 {object} map[string]interface{} "Invalid delegation ID"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      404 {object} map[string]interface{} "Delegation not found"
+// @Failure      409 {object} map[string]interface{} "Delegation already revoked"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /delegations/{id} [delete]
+func (s *delegationService) RevokeDelegation(c *fiber.Ctx) error {
+	subject, err := s.caller(c)
+	if subject == nil {
+		return err
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid delegation ID")
+	}
+	delegation, err := s.delegationRepo.FindByID(id)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to get delegation")
+	}
+	if delegation == nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Delegation not found")
+	}
+	if delegation.DelegatorID != subject.LecturerID && !authz.Admin(*subject, authz.Resource{}) {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "Only the delegating lecturer or an admin can revoke this delegation")
+	}
+
+	now := time.Now()
+	revoked, err := s.delegationRepo.Revoke(delegation.ID, subject.UserID, now)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to revoke delegation")
+	}
+	if !revoked {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Delegation already revoked")
+	}
+
+	RecordAudit(c, s.auditRepo, "delegation.revoke", "advisor_delegation", delegation.ID.String(), auditChanges(
+		fiber.Map{"revoked_at": nil},
+		fiber.Map{"revoked_at": now, "revoked_by": subject.UserID},
+	))
+
+	if delegation.Delegate != nil && delegation.Delegator != nil {
+		CreateNotification(
+			s.notificationRepo,
+			delegation.Delegate.UserID,
+			models.NotificationTypeAdvisorDelegation,
+			"Delegation Revoked",
+			fmt.Sprintf("You no longer act for %s as advisor", delegation.Delegator.User.FullName),
+			fiber.Map{
+				"delegation_id": delegation.ID,
+				"delegator_id":  delegation.DelegatorID,
+			},
+		)
+	}
+
+	return utils.SuccessResponse(c, "Delegation revoked successfully", nil)
+}
+
+// caller describes the authenticated caller, who must be a lecturer or an admin. When it
+// returns nil the error response has already been written.
+func (s *delegationService) caller(c *fiber.Ctx) (*authz.Subject, error) {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return nil, utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	subject := authzSubject(claims, s.studentRepo, s.lecturerRepo, s.delegationRepo)
+	if subject.LecturerID == uuid.Nil && !authz.Admin(subject, authz.Resource{}) {
+		return nil, utils.ErrorResponse(c, fiber.StatusForbidden, "Only lecturers and admins can manage delegations")
+	}
+	return &subject, nil
+}
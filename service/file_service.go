@@ -1,10 +1,20 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"strings"
+	"student-achievement-system/authz"
 	"student-achievement-system/middleware"
+	"student-achievement-system/models"
+	"student-achievement-system/repository"
 	"student-achievement-system/utils"
+	"student-achievement-system/workflow"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type FileService interface {
@@ -12,10 +22,34 @@ type FileService interface {
 	DeleteAchievementFile(c *fiber.Ctx) error
 }
 
-type fileService struct{}
+type fileService struct {
+	achievementRepo    repository.AchievementRepository
+	revisionRepo       repository.AchievementRevisionRepository
+	achievementRefRepo repository.AchievementReferenceRepository
+	studentRepo        repository.StudentRepository
+	lecturerRepo       repository.LecturerRepository
+	delegationRepo     repository.AdvisorDelegationRepository
+	auditRepo          repository.AuditRepository
+}
 
-func NewFileService() FileService {
-	return &fileService{}
+func NewFileService(
+	achievementRepo repository.AchievementRepository,
+	revisionRepo repository.AchievementRevisionRepository,
+	achievementRefRepo repository.AchievementReferenceRepository,
+	studentRepo repository.StudentRepository,
+	lecturerRepo repository.LecturerRepository,
+	delegationRepo repository.AdvisorDelegationRepository,
+	auditRepo repository.AuditRepository,
+) FileService {
+	return &fileService{
+		achievementRepo:    achievementRepo,
+		revisionRepo:       revisionRepo,
+		achievementRefRepo: achievementRefRepo,
+		studentRepo:        studentRepo,
+		lecturerRepo:       lecturerRepo,
+		delegationRepo:     delegationRepo,
+		auditRepo:          auditRepo,
+	}
 }
 
 // UploadAchievementFile godoc
@@ -29,9 +63,20 @@ func NewFileService() FileService {
 // @Success      200 {object} map[string]interface{} "File uploaded successfully"
 // @Failure      400 {object} map[string]interface{} "Invalid file or file too large"
 // @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
 // @Failure      500 {object} map[string]interface{} "Internal server error"
 // @Router       /files/upload [post]
 func (s *fileService) UploadAchievementFile(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	subject := authzSubject(claims, s.studentRepo, s.lecturerRepo, s.delegationRepo)
+	if !authz.Can(subject, authz.ActionCreate, authz.Resource{Kind: authz.KindFile}) {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "Only students and admins can upload files")
+	}
+
 	filename, err := middleware.UploadFile(c, "file")
 	if err != nil {
 		utils.GlobalLogger.Error("File upload failed", err, map[string]interface{}{
@@ -40,6 +85,10 @@ func (s *fileService) UploadAchievementFile(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
 	}
 
+	RecordAudit(c, s.auditRepo, "file.upload", "file", filename, auditChanges(nil, fiber.Map{
+		"filename": filename,
+	}))
+
 	return utils.SuccessResponse(c, "File uploaded successfully", fiber.Map{
 		"filename": filename,
 		"url":      "/uploads/" + filename,
@@ -48,7 +97,7 @@ func (s *fileService) UploadAchievementFile(c *fiber.Ctx) error {
 
 // DeleteAchievementFile godoc
 // @Summary      Delete achievement attachment
-// @Description  Delete an uploaded file attachment
+// @Description  Delete an uploaded file attachment. Students can delete files attached to their own achievements while they can still edit them, which also removes the attachment from the achievement; files not attached to any achievement can only be deleted by admins.
 // @Tags         Files
 // @Accept       json
 // @Produce      json
@@ -57,13 +106,72 @@ func (s *fileService) UploadAchievementFile(c *fiber.Ctx) error {
 // @Success      200 {object} map[string]interface{} "File deleted successfully"
 // @Failure      400 {object} map[string]interface{} "Failed to delete file"
 // @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      409 {object} map[string]interface{} "Achievement can no longer be edited"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
 // @Router       /files/{filename} [delete]
 func (s *fileService) DeleteAchievementFile(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not authenticated")
+	}
+
 	filename := c.Params("filename")
-	
+
+	// A file belongs to the owner of the achievement it is attached to
+	resource := authz.Resource{Kind: authz.KindFile}
+	var ref *models.AchievementReference
+	achievement, err := s.achievementRepo.FindByAttachment(context.Background(), filename)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to look up file owner")
+	}
+	if achievement != nil {
+		found, err := s.achievementRefRepo.FindByMongoID(achievement.ID.Hex())
+		if err == nil && found.ID != uuid.Nil {
+			ref = found
+			if student, err := s.studentRepo.FindByID(ref.StudentID); err == nil && student.ID != uuid.Nil {
+				resource = ownedBy(authz.KindFile, student)
+			}
+		}
+	}
+
+	subject := authzSubject(claims, s.studentRepo, s.lecturerRepo, s.delegationRepo)
+	if !authz.Can(subject, authz.ActionDelete, resource) {
+		return forbidden(c, authz.ActionDelete, authz.KindFile)
+	}
+
+	// Attached files are part of the achievement's content, which only changes while it is editable
+	if ref != nil {
+		if err := workflow.CanEdit(ref); err != nil {
+			return transitionFailed(c, err)
+		}
+	}
+
+	if achievement != nil {
+		id := achievement.ID.Hex()
+		attachments := []models.Attachment{}
+		for _, attachment := range achievement.Attachments {
+			if !strings.HasSuffix(attachment.FileURL, "/"+filename) {
+				attachments = append(attachments, attachment)
+			}
+		}
+		achievement.Attachments = attachments
+		achievement.UpdatedAt = time.Now()
+		if err := s.achievementRepo.Update(context.Background(), id, achievement); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update achievement")
+		}
+		recordRevision(c, s.revisionRepo, id, achievement, "delete_file")
+	}
+
 	if err := middleware.DeleteFile(filename); err != nil {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to delete file")
 	}
 
+	before := fiber.Map{"filename": filename}
+	if achievement != nil {
+		before["achievement_id"] = achievement.ID.Hex()
+	}
+	RecordAudit(c, s.auditRepo, "file.delete", "file", filename, auditChanges(before, nil))
+
 	return utils.SuccessResponse(c, "File deleted successfully", nil)
 }
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"student-achievement-system/middleware"
+	"student-achievement-system/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type ImpersonateRequest struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+// ImpersonateUser godoc
+// @Summary      Impersonate user
+// @Description  Issue a short-lived access token acting as another user, to see exactly what they see when debugging a complaint. The token names the admin in its "act" claim and cannot be refreshed, change credentials or permanently delete data. Every request made with it is logged with both identities, and it stops working once the admin logs out or loses the user:impersonate permission.
+// @Tags         User Management
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path     string              true  "User ID (UUID)"
+// @Param        request  body     ImpersonateRequest  true  "Reason, recorded in the audit trail"
+// @Success      200 {object} map[string]interface{} "Impersonation token issued"
+// @Failure      400 {object} map[string]interface{} "Invalid user ID or request, or user inactive"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "User cannot be impersonated"
+// @Failure      404 {object} map[string]interface{} "User not found"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /users/{id}/impersonate [post]
+func (s *userService) ImpersonateUser(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	var req ImpersonateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.ValidationErrorResponse(c, err)
+	}
+
+	if id == claims.UserID {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "You cannot impersonate yourself")
+	}
+
+	user, err := s.userRepo.FindByID(id)
+	if err != nil || user.ID == uuid.Nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "User not found")
+	}
+	if !user.IsActive {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Inactive users cannot be impersonated")
+	}
+
+	// Acting as another admin would hand over their powers, including impersonation itself
+	permissions, _, err := s.permissions.RolePermissions(user.RoleID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to load permissions")
+	}
+	if hasPermission(permissions, impersonatePermission) {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "Users who can impersonate others cannot be impersonated")
+	}
+
+	// Impersonation tokens belong to no session, so they can be neither refreshed nor listed
+	tokenClaims := buildUserClaims(user, uuid.Nil)
+	tokenClaims.SessionID = ""
+	tokenClaims.Actor = &utils.Actor{UserID: claims.UserID, Username: claims.Username}
+
+	token, expiresAt, err := s.tokenService.GenerateImpersonationToken(tokenClaims)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to generate token")
+	}
+
+	utils.GlobalLogger.LogAuth("impersonation_started", claims.UserID.String(), true, map[string]interface{}{
+		"target_user_id":  user.ID,
+		"target_username": user.Username,
+		"reason":          req.Reason,
+		"token_id":        tokenClaims.ID,
+		"expires_at":      expiresAt,
+	})
+	RecordAudit(c, s.auditRepo, "user.impersonate", "user", user.ID.String(), fiber.Map{
+		"reason":     req.Reason,
+		"token_id":   tokenClaims.ID,
+		"expires_at": expiresAt,
+	})
+
+	return utils.SuccessResponse(c, "Impersonation token issued", fiber.Map{
+		"token":      token,
+		"expires_at": expiresAt,
+		"user": fiber.Map{
+			"id":          user.ID.String(),
+			"username":    user.Username,
+			"full_name":   user.FullName,
+			"email":       user.Email,
+			"role":        user.Role.Name,
+			"permissions": permissions,
+		},
+	})
+}
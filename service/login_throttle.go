@@ -0,0 +1,131 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"student-achievement-system/config"
+	"student-achievement-system/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// maxLoginBackoff caps the exponential delay between failed attempts before lockout
+const maxLoginBackoff = 5 * time.Minute
+
+// loginReservation bounds how long one attempt holds the identity in case it never
+// records its outcome
+const loginReservation = 10 * time.Second
+
+// checkLoginThrottle reserves the identity for this attempt, rejecting the request while
+// the identity is backing off, locked out or checked by another attempt. It returns true
+// when the login may proceed, and the attempt must then end in recordLoginFailure or a
+// reset; when it returns false the error response has already been written.
+func (s *authService) checkLoginThrottle(c *fiber.Ctx, key string, userID *uuid.UUID) (bool, error) {
+	reserved, err := s.loginAttemptRepo.Reserve(key, userID, time.Now().Add(loginReservation))
+	if err != nil {
+		utils.GlobalLogger.Error("Failed to reserve login attempt", err, map[string]interface{}{
+			"identifier": key,
+		})
+		return false, utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to process login")
+	}
+	if reserved {
+		return true, nil
+	}
+
+	attempt, err := s.loginAttemptRepo.Find(key)
+	if err != nil {
+		return false, utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to process login")
+	}
+
+	locked := attempt.LockedUntil != nil && time.Until(*attempt.LockedUntil) > 0
+	until := attempt.ReservedUntil
+	if locked {
+		until = attempt.LockedUntil
+	}
+	seconds := 1
+	if until != nil {
+		seconds = max(int(math.Ceil(time.Until(*until).Seconds())), 1)
+	}
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+
+	utils.GlobalLogger.LogAuth("login_blocked", userIDString(userID), false, map[string]interface{}{
+		"identifier":   key,
+		"failed_count": attempt.FailedCount,
+		"ip":           c.IP(),
+	})
+
+	if !locked {
+		return false, utils.ErrorResponse(c, fiber.StatusTooManyRequests,
+			fmt.Sprintf("Another login attempt is in progress, try again in %d seconds", seconds))
+	}
+	if attempt.FailedCount >= s.cfg.LoginMaxAttempts {
+		return false, utils.ErrorResponse(c, fiber.StatusLocked,
+			fmt.Sprintf("Account temporarily locked after too many failed attempts, try again in %d seconds", seconds))
+	}
+	return false, utils.ErrorResponse(c, fiber.StatusTooManyRequests,
+		fmt.Sprintf("Too many failed login attempts, try again in %d seconds", seconds))
+}
+
+// recordLoginFailure counts a failed login and applies backoff or lockout
+func (s *authService) recordLoginFailure(c *fiber.Ctx, key string, userID *uuid.UUID) {
+	failed, err := s.loginAttemptRepo.RecordFailure(key, userID, time.Now().Add(-s.cfg.LoginAttemptWindow))
+	if err != nil {
+		utils.GlobalLogger.Error("Failed to record login failure", err, map[string]interface{}{
+			"identifier": key,
+		})
+		return
+	}
+
+	utils.GlobalLogger.LogAuth("login_failed", userIDString(userID), false, map[string]interface{}{
+		"identifier":   key,
+		"failed_count": failed,
+		"ip":           c.IP(),
+	})
+
+	delay, lockout := loginDelay(s.cfg, failed)
+	if lockout {
+		utils.GlobalLogger.LogAuth("account_locked", userIDString(userID), false, map[string]interface{}{
+			"identifier":   key,
+			"failed_count": failed,
+			"locked_for":   delay.String(),
+			"ip":           c.IP(),
+		})
+	}
+
+	if err := s.loginAttemptRepo.Lock(key, time.Now().Add(delay)); err != nil {
+		utils.GlobalLogger.Error("Failed to apply login backoff", err, map[string]interface{}{
+			"identifier": key,
+		})
+	}
+}
+
+// loginDelay returns how long the identity is blocked after its failed-th failure in the
+// window, and whether that is the lockout rather than a backoff
+func loginDelay(cfg *config.Config, failed int) (time.Duration, bool) {
+	if failed >= cfg.LoginMaxAttempts {
+		return cfg.LoginLockoutDuration, true
+	}
+	return loginBackoff(cfg.LoginBackoffBase, failed), false
+}
+
+// loginBackoff returns base doubled for every failure after the first, capped at maxLoginBackoff
+func loginBackoff(base time.Duration, failed int) time.Duration {
+	delay := base
+	for i := 1; i < failed && delay < maxLoginBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxLoginBackoff {
+		delay = maxLoginBackoff
+	}
+	return delay
+}
+
+func userIDString(userID *uuid.UUID) string {
+	if userID == nil {
+		return ""
+	}
+	return userID.String()
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"net/http/httptest"
+	"student-achievement-system/config"
+	"student-achievement-system/models"
+	"student-achievement-system/repository"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// fakeLoginAttemptRepo keeps login attempts in memory with the semantics of the SQL
+type fakeLoginAttemptRepo struct {
+	attempts map[string]*models.LoginAttempt
+}
+
+func (r *fakeLoginAttemptRepo) Find(identifier string) (*models.LoginAttempt, error) {
+	attempt, ok := r.attempts[identifier]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *attempt
+	return &copied, nil
+}
+
+func (r *fakeLoginAttemptRepo) Reserve(identifier string, userID *uuid.UUID, until time.Time) (bool, error) {
+	attempt, ok := r.attempts[identifier]
+	if !ok {
+		attempt = &models.LoginAttempt{Identifier: identifier, UserID: userID, LastFailedAt: time.Now()}
+		r.attempts[identifier] = attempt
+	} else if (attempt.LockedUntil != nil && attempt.LockedUntil.After(time.Now())) ||
+		(attempt.ReservedUntil != nil && attempt.ReservedUntil.After(time.Now())) {
+		return false, nil
+	}
+	attempt.ReservedUntil = &until
+	return true, nil
+}
+
+func (r *fakeLoginAttemptRepo) RecordFailure(identifier string, userID *uuid.UUID, windowStart time.Time) (int, error) {
+	attempt, ok := r.attempts[identifier]
+	if !ok {
+		attempt = &models.LoginAttempt{Identifier: identifier, UserID: userID}
+		r.attempts[identifier] = attempt
+	}
+	if attempt.LastFailedAt.Before(windowStart) {
+		attempt.FailedCount = 0
+	}
+	attempt.FailedCount++
+	attempt.LastFailedAt = time.Now()
+	return attempt.FailedCount, nil
+}
+
+func (r *fakeLoginAttemptRepo) Lock(identifier string, until time.Time) error {
+	if attempt, ok := r.attempts[identifier]; ok {
+		if attempt.LockedUntil == nil || until.After(*attempt.LockedUntil) {
+			attempt.LockedUntil = &until
+		}
+		attempt.ReservedUntil = nil
+	}
+	return nil
+}
+
+func (r *fakeLoginAttemptRepo) Reset(identifier string) error {
+	delete(r.attempts, identifier)
+	return nil
+}
+
+var _ repository.LoginAttemptRepository = (*fakeLoginAttemptRepo)(nil)
+
+func TestLoginBackoff(t *testing.T) {
+	tests := []struct {
+		failed int
+		want   time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{9, 256 * time.Second},
+		{10, maxLoginBackoff},
+		{50, maxLoginBackoff},
+	}
+	for _, tt := range tests {
+		if got := loginBackoff(time.Second, tt.failed); got != tt.want {
+			t.Errorf("loginBackoff(1s, %d) = %v, want %v", tt.failed, got, tt.want)
+		}
+	}
+}
+
+func TestLoginDelayLockout(t *testing.T) {
+	cfg := &config.Config{LoginMaxAttempts: 3, LoginBackoffBase: time.Second, LoginLockoutDuration: 15 * time.Minute}
+
+	for failed := 1; failed < cfg.LoginMaxAttempts; failed++ {
+		if delay, lockout := loginDelay(cfg, failed); lockout || delay != loginBackoff(cfg.LoginBackoffBase, failed) {
+			t.Errorf("loginDelay(%d) = %v, %v, want the backoff", failed, delay, lockout)
+		}
+	}
+	for _, failed := range []int{cfg.LoginMaxAttempts, cfg.LoginMaxAttempts + 1} {
+		if delay, lockout := loginDelay(cfg, failed); !lockout || delay != cfg.LoginLockoutDuration {
+			t.Errorf("loginDelay(%d) = %v, %v, want the lockout", failed, delay, lockout)
+		}
+	}
+}
+
+// throttleStatus runs one throttled attempt against s and returns the response status;
+// a failed attempt is recorded when fail is set and the attempt got through
+func throttleStatus(t *testing.T, s *authService, key string, fail bool) int {
+	t.Helper()
+	app := fiber.New()
+	app.Post("/", func(c *fiber.Ctx) error {
+		if ok, err := s.checkLoginThrottle(c, key, nil); !ok {
+			return err
+		}
+		if fail {
+			s.recordLoginFailure(c, key, nil)
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode
+}
+
+func TestLoginThrottleLocksOutAtThreshold(t *testing.T) {
+	repo := &fakeLoginAttemptRepo{attempts: map[string]*models.LoginAttempt{}}
+	// Without a backoff base only the lockout blocks attempts
+	s := &authService{
+		loginAttemptRepo: repo,
+		cfg: &config.Config{
+			LoginMaxAttempts:     3,
+			LoginLockoutDuration: 15 * time.Minute,
+			LoginAttemptWindow:   15 * time.Minute,
+		},
+	}
+	key := repository.IdentifierLoginKey("guess@example.com")
+
+	for i := 1; i <= s.cfg.LoginMaxAttempts; i++ {
+		if status := throttleStatus(t, s, key, true); status != fiber.StatusUnauthorized {
+			t.Fatalf("attempt %d got %d, want it checked and rejected with 401", i, status)
+		}
+	}
+	if status := throttleStatus(t, s, key, false); status != fiber.StatusLocked {
+		t.Errorf("attempt after %d failures got %d, want 423", s.cfg.LoginMaxAttempts, status)
+	}
+
+	// A shorter backoff recorded later does not cut the lockout short
+	lockedUntil := *repo.attempts[key].LockedUntil
+	if err := repo.Lock(key, time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if got := *repo.attempts[key].LockedUntil; !got.Equal(lockedUntil) {
+		t.Errorf("locked until %v after a shorter lock, want %v", got, lockedUntil)
+	}
+}
+
+func TestLoginThrottleRejectsConcurrentAttempt(t *testing.T) {
+	repo := &fakeLoginAttemptRepo{attempts: map[string]*models.LoginAttempt{}}
+	s := &authService{loginAttemptRepo: repo, cfg: &config.Config{LoginMaxAttempts: 3}}
+	key := repository.IdentifierLoginKey("guess@example.com")
+
+	// Another attempt holds the identity while its password is checked
+	if ok, err := repo.Reserve(key, nil, time.Now().Add(loginReservation)); err != nil || !ok {
+		t.Fatalf("Reserve() = %v, %v, want the reservation", ok, err)
+	}
+	if status := throttleStatus(t, s, key, false); status != fiber.StatusTooManyRequests {
+		t.Errorf("concurrent attempt got %d, want 429", status)
+	}
+
+	// Once the first attempt ends the next one gets through
+	if err := repo.Reset(key); err != nil {
+		t.Fatal(err)
+	}
+	if status := throttleStatus(t, s, key, false); status != fiber.StatusOK {
+		t.Errorf("attempt after the reset got %d, want 200", status)
+	}
+}
@@ -0,0 +1,409 @@
+package service
+
+import (
+	"strings"
+	"student-achievement-system/middleware"
+	"student-achievement-system/models"
+	"student-achievement-system/repository"
+	"student-achievement-system/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// recoveryCodeCount is the number of recovery codes issued at enrollment
+const recoveryCodeCount = 10
+
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type VerifyMFARequest struct {
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+// EnrollMFA godoc
+// @Summary      Start two-factor enrollment
+// @Description  Generate a TOTP secret and provisioning URI for an authenticator app. Accepts a normal access token or the mfa_token from login when enrollment is required for the user's role.
+// @Tags         Authentication
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200 {object} map[string]interface{} "Secret and otpauth URI generated"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      409 {object} map[string]interface{} "Two-factor authentication is already enabled"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /auth/mfa/enroll [post]
+func (s *authService) EnrollMFA(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	user, err := s.userRepo.FindByID(claims.UserID)
+	if err != nil || user.ID == uuid.Nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "User not found")
+	}
+
+	if user.MFAEnabled {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Two-factor authentication is already enabled")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to generate secret")
+	}
+
+	if err := s.mfaRepo.SetSecret(user.ID, secret); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to start enrollment")
+	}
+
+	return utils.SuccessResponse(c, "Scan the provisioning URI with an authenticator app, then confirm with a code", fiber.Map{
+		"secret":      secret,
+		"otpauth_uri": utils.TOTPProvisioningURI(s.cfg.MFAIssuer, user.Email, secret),
+	})
+}
+
+// ConfirmMFAEnrollment godoc
+// @Summary      Confirm two-factor enrollment
+// @Description  Confirm enrollment with a code from the authenticator app. Returns single-use recovery codes, which are shown only once. When called with an mfa_token the login is completed and a token pair is returned as well.
+// @Tags         Authentication
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body MFACodeRequest true "TOTP code"
+// @Success      200 {object} map[string]interface{} "Two-factor authentication enabled"
+// @Failure      400 {object} map[string]interface{} "Enrollment has not been started"
+// @Failure      401 {object} map[string]interface{} "Invalid verification code"
+// @Failure      409 {object} map[string]interface{} "Two-factor authentication is already enabled"
+// @Failure      429 {object} map[string]interface{} "Too many failed attempts"
+// @Router       /auth/mfa/enroll/verify [post]
+func (s *authService) ConfirmMFAEnrollment(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	var req MFACodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.ValidationErrorResponse(c, err)
+	}
+
+	user, err := s.userRepo.FindByID(claims.UserID)
+	if err != nil || user.ID == uuid.Nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "User not found")
+	}
+
+	if user.MFAEnabled {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Two-factor authentication is already enabled")
+	}
+	if user.MFASecret == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Enrollment has not been started")
+	}
+
+	attemptKey := repository.UserLoginKey(user.ID)
+	if ok, err := s.checkLoginThrottle(c, attemptKey, &user.ID); !ok {
+		return err
+	}
+
+	step, ok := utils.ValidateTOTP(user.MFASecret, req.Code, time.Now())
+	if !ok {
+		s.recordLoginFailure(c, attemptKey, &user.ID)
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Invalid verification code")
+	}
+
+	if err := s.mfaRepo.Enable(user.ID, step); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to enable two-factor authentication")
+	}
+
+	recoveryCodes, err := s.issueRecoveryCodes(user.ID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to generate recovery codes")
+	}
+
+	s.loginAttemptRepo.Reset(attemptKey)
+	utils.GlobalLogger.LogAuth("mfa_enrolled", user.ID.String(), true)
+
+	// Enrollment forced at login completes the login
+	if claims.Scope == utils.ScopeMFAPending {
+		return s.completeLogin(c, user, "Two-factor authentication enabled", fiber.Map{
+			"recovery_codes": recoveryCodes,
+		})
+	}
+
+	return utils.SuccessResponse(c, "Two-factor authentication enabled", fiber.Map{
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// VerifyMFA godoc
+// @Summary      Complete two-factor login
+// @Description  Exchange the mfa_token from login plus a TOTP code or a recovery code for an access token and refresh token. Send the mfa_token as the Bearer token.
+// @Tags         Authentication
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body VerifyMFARequest true "TOTP code or recovery code"
+// @Success      200 {object} map[string]interface{} "Login successful"
+// @Failure      400 {object} map[string]interface{} "Invalid request"
+// @Failure      401 {object} map[string]interface{} "Invalid verification code"
+// @Failure      429 {object} map[string]interface{} "Too many failed attempts"
+// @Router       /auth/mfa/verify [post]
+func (s *authService) VerifyMFA(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not authenticated")
+	}
+	if claims.Scope != utils.ScopeMFAPending {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Two-factor verification is only required during login")
+	}
+
+	var req VerifyMFARequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if req.Code == "" && req.RecoveryCode == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Either code or recovery_code is required")
+	}
+
+	user, err := s.userRepo.FindByID(claims.UserID)
+	if err != nil || user.ID == uuid.Nil || !user.IsActive {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Invalid credentials")
+	}
+	if !user.MFAEnabled {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Two-factor authentication is not enrolled")
+	}
+
+	attemptKey := repository.UserLoginKey(user.ID)
+	if ok, err := s.checkLoginThrottle(c, attemptKey, &user.ID); !ok {
+		return err
+	}
+
+	method := "totp"
+	var ok bool
+	if req.Code != "" {
+		ok, err = s.verifyTOTP(user, req.Code)
+	} else {
+		method = "recovery_code"
+		ok, err = s.mfaRepo.UseRecoveryCode(user.ID, hashRecoveryCode(req.RecoveryCode))
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to verify code")
+	}
+	if !ok {
+		s.recordLoginFailure(c, attemptKey, &user.ID)
+		utils.GlobalLogger.LogAuth("mfa_failed", user.ID.String(), false, map[string]interface{}{
+			"method": method,
+		})
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Invalid verification code")
+	}
+
+	s.loginAttemptRepo.Reset(attemptKey)
+	utils.GlobalLogger.LogAuth("mfa_verified", user.ID.String(), true, map[string]interface{}{
+		"method": method,
+	})
+
+	var extra fiber.Map
+	if method == "recovery_code" {
+		remaining, _ := s.mfaRepo.CountRecoveryCodes(user.ID)
+		extra = fiber.Map{"recovery_codes_remaining": remaining}
+	}
+
+	return s.completeLogin(c, user, "Login successful", extra)
+}
+
+// DisableMFA godoc
+// @Summary      Disable two-factor authentication
+// @Description  Turn off two-factor authentication after confirming a current TOTP code. Not allowed for roles where it is mandatory.
+// @Tags         Authentication
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body MFACodeRequest true "TOTP code"
+// @Success      200 {object} map[string]interface{} "Two-factor authentication disabled"
+// @Failure      400 {object} map[string]interface{} "Two-factor authentication is not enabled"
+// @Failure      401 {object} map[string]interface{} "Invalid verification code"
+// @Failure      403 {object} map[string]interface{} "Two-factor authentication is required for this role"
+// @Router       /auth/mfa/disable [post]
+func (s *authService) DisableMFA(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	var req MFACodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.ValidationErrorResponse(c, err)
+	}
+
+	user, err := s.userRepo.FindByID(claims.UserID)
+	if err != nil || user.ID == uuid.Nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "User not found")
+	}
+
+	if !user.MFAEnabled {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Two-factor authentication is not enabled")
+	}
+	if s.mfaRequired(user) {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "Two-factor authentication is required for this role")
+	}
+
+	attemptKey := repository.UserLoginKey(user.ID)
+	if ok, err := s.checkLoginThrottle(c, attemptKey, &user.ID); !ok {
+		return err
+	}
+
+	ok, err := s.verifyTOTP(user, req.Code)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to verify code")
+	}
+	if !ok {
+		s.recordLoginFailure(c, attemptKey, &user.ID)
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Invalid verification code")
+	}
+
+	if err := s.mfaRepo.Disable(user.ID); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to disable two-factor authentication")
+	}
+
+	s.loginAttemptRepo.Reset(attemptKey)
+
+	utils.GlobalLogger.LogAuth("mfa_disabled", user.ID.String(), true)
+	return utils.SuccessResponse(c, "Two-factor authentication disabled", nil)
+}
+
+// ResetUserMFA godoc
+// @Summary      Reset user two-factor authentication
+// @Description  Remove a user's authenticator and recovery codes, e.g. after a lost device, and sign out all of their sessions. The user enrolls again at next login.
+// @Tags         User Management
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path     string  true  "User ID (UUID)"
+// @Success      200 {object} map[string]interface{} "Two-factor authentication reset"
+// @Failure      400 {object} map[string]interface{} "Invalid user ID"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      404 {object} map[string]interface{} "User not found"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /users/{id}/mfa [delete]
+func (s *authService) ResetUserMFA(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	user, err := s.userRepo.FindByID(id)
+	if err != nil || user.ID == uuid.Nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "User not found")
+	}
+
+	if err := s.mfaRepo.Disable(user.ID); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to reset two-factor authentication")
+	}
+
+	if err := s.tokenService.RevokeUserTokens(user.ID); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Two-factor authentication reset but failed to revoke tokens")
+	}
+
+	actorID := ""
+	if claims := middleware.GetUserFromContext(c); claims != nil {
+		actorID = claims.UserID.String()
+	}
+	utils.GlobalLogger.LogAuth("mfa_reset", user.ID.String(), true, map[string]interface{}{
+		"reset_by": actorID,
+	})
+	RecordAudit(c, s.auditRepo, "user.reset_mfa", "user", user.ID.String(), auditChanges(
+		fiber.Map{"mfa_enabled": user.MFAEnabled},
+		fiber.Map{"mfa_enabled": false},
+	))
+
+	return utils.SuccessResponse(c, "Two-factor authentication reset", nil)
+}
+
+// mfaRequired reports whether the user's role must use two-factor authentication
+func (s *authService) mfaRequired(user *models.User) bool {
+	for _, role := range s.cfg.MFARequiredRoles {
+		if strings.EqualFold(role, user.Role.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// mfaChallenge answers the password step of a login with a short-lived mfa_pending token
+func (s *authService) mfaChallenge(c *fiber.Ctx, user *models.User) error {
+	claims := &utils.JWTClaims{
+		UserID:   user.ID,
+		Username: user.Username,
+		Email:    user.Email,
+	}
+
+	mfaToken, err := s.tokenService.GenerateScopedToken(claims, utils.ScopeMFAPending, s.cfg.MFAPendingTTL)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to generate token")
+	}
+
+	message := "Two-factor authentication required"
+	if !user.MFAEnabled {
+		message = "Two-factor authentication must be set up for this account"
+	}
+
+	utils.GlobalLogger.LogAuth("mfa_challenge", user.ID.String(), true, map[string]interface{}{
+		"enrolled": user.MFAEnabled,
+	})
+
+	return utils.SuccessResponse(c, message, fiber.Map{
+		"mfa_required": true,
+		"mfa_enrolled": user.MFAEnabled,
+		"mfa_token":    mfaToken,
+		"expires_in":   int(s.cfg.MFAPendingTTL.Seconds()),
+	})
+}
+
+// verifyTOTP checks a TOTP code and rejects codes whose time step was already used
+func (s *authService) verifyTOTP(user *models.User, code string) (bool, error) {
+	step, ok := utils.ValidateTOTP(user.MFASecret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	return s.mfaRepo.UseStep(user.ID, step)
+}
+
+// issueRecoveryCodes replaces the user's recovery codes and returns the new plain codes
+func (s *authService) issueRecoveryCodes(userID uuid.UUID) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw, err := utils.GenerateSecureToken(5)
+		if err != nil {
+			return nil, err
+		}
+		code := raw[:5] + "-" + raw[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	if err := s.mfaRepo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// hashRecoveryCode normalizes a recovery code as typed by the user and hashes it
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	code = strings.ReplaceAll(code, " ", "")
+	return utils.HashToken(code)
+}
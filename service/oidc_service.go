@@ -0,0 +1,437 @@
+package service
+
+import (
+	"crypto/subtle"
+	"strings"
+	"student-achievement-system/authz"
+	"student-achievement-system/config"
+	"student-achievement-system/models"
+	"student-achievement-system/repository"
+	"student-achievement-system/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// oidcStateTTL bounds how long a user may take at the identity provider
+const oidcStateTTL = 10 * time.Minute
+
+// oidcBindingCookie ties a login state to the browser that started the login, so a state
+// from someone else's login cannot be redeemed in a victim's browser
+const oidcBindingCookie = "oidc_binding"
+
+type OIDCService interface {
+	Login(c *fiber.Ctx) error
+	Callback(c *fiber.Ctx) error
+}
+
+type OIDCCallbackRequest struct {
+	Code  string `json:"code" validate:"required"`
+	State string `json:"state" validate:"required"`
+}
+
+// oidcLoginError is a login failure with the HTTP status to report
+type oidcLoginError struct {
+	status  int
+	message string
+}
+
+func (e *oidcLoginError) Error() string {
+	return e.message
+}
+
+// loginFinisher completes a login once the user's primary credential has been verified
+type loginFinisher interface {
+	finishLogin(c *fiber.Ctx, user *models.User) error
+}
+
+type oidcService struct {
+	authService  loginFinisher
+	userRepo     repository.UserRepository
+	studentRepo  repository.StudentRepository
+	lecturerRepo repository.LecturerRepository
+	roleRepo     repository.RoleRepository
+	stateRepo    repository.OIDCStateRepository
+	provider     *utils.OIDCProvider
+	cfg          *config.Config
+}
+
+// NewOIDCService creates the single sign-on service. provider is nil when SSO is not configured.
+// authService must be the one created by NewAuthService; logins finish there.
+func NewOIDCService(
+	authService AuthService,
+	userRepo repository.UserRepository,
+	studentRepo repository.StudentRepository,
+	lecturerRepo repository.LecturerRepository,
+	roleRepo repository.RoleRepository,
+	stateRepo repository.OIDCStateRepository,
+	provider *utils.OIDCProvider,
+	cfg *config.Config,
+) OIDCService {
+	finisher, ok := authService.(loginFinisher)
+	if !ok {
+		panic("service: NewOIDCService needs the AuthService created by NewAuthService")
+	}
+
+	return &oidcService{
+		authService:  finisher,
+		userRepo:     userRepo,
+		studentRepo:  studentRepo,
+		lecturerRepo: lecturerRepo,
+		roleRepo:     roleRepo,
+		stateRepo:    stateRepo,
+		provider:     provider,
+		cfg:          cfg,
+	}
+}
+
+// Login godoc
+// @Summary      Start single sign-on
+// @Description  Create an authorization request (authorization code + PKCE) at the university identity provider. Returns the URL to open in the browser, or redirects to it when redirect=true. Sets an HttpOnly cookie binding the login to this browser; the callback must be sent with it.
+// @Tags         Authentication
+// @Produce      json
+// @Param        redirect  query    bool  false  "Redirect to the identity provider instead of returning JSON"
+// @Success      200 {object} map[string]interface{} "Authorization URL created"
+// @Success      302 "Redirect to the identity provider"
+// @Failure      404 {object} map[string]interface{} "Single sign-on is not configured"
+// @Failure      502 {object} map[string]interface{} "Identity provider unavailable"
+// @Router       /auth/oidc/login [get]
+func (s *oidcService) Login(c *fiber.Ctx) error {
+	if s.provider == nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Single sign-on is not configured")
+	}
+
+	state, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to start login")
+	}
+	nonce, err := utils.GenerateSecureToken(16)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to start login")
+	}
+	codeVerifier, codeChallenge, err := utils.GeneratePKCE()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to start login")
+	}
+	binding, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to start login")
+	}
+
+	authURL, err := s.provider.AuthCodeURL(c.UserContext(), state, nonce, codeChallenge)
+	if err != nil {
+		utils.GlobalLogger.Error("OIDC discovery failed", err)
+		return utils.ErrorResponse(c, fiber.StatusBadGateway, "Identity provider unavailable")
+	}
+
+	now := time.Now()
+	if _, err := s.stateRepo.DeleteExpired(now); err != nil {
+		utils.GlobalLogger.Error("Failed to purge expired OIDC states", err)
+	}
+
+	if err := s.stateRepo.Create(&models.OIDCState{
+		StateHash:    utils.HashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		BindingHash:  utils.HashToken(binding),
+		ExpiresAt:    now.Add(oidcStateTTL),
+	}); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to start login")
+	}
+	s.setBindingCookie(c, binding, now.Add(oidcStateTTL))
+
+	if c.QueryBool("redirect") {
+		return c.Redirect(authURL, fiber.StatusFound)
+	}
+
+	return utils.SuccessResponse(c, "Authorization URL created", fiber.Map{
+		"authorization_url": authURL,
+		"state":             state,
+	})
+}
+
+// Callback godoc
+// @Summary      Complete single sign-on
+// @Description  Redeem the code and state returned by the identity provider, from the browser that started the login. The ID token is verified and mapped to an account by subject, NIM/NIP or email; the response is the same as /auth/login.
+// @Tags         Authentication
+// @Accept       json
+// @Produce      json
+// @Param        request body OIDCCallbackRequest true "Authorization response"
+// @Success      200 {object} map[string]interface{} "Login successful"
+// @Failure      400 {object} map[string]interface{} "Invalid or expired login state, or login started in another browser"
+// @Failure      401 {object} map[string]interface{} "Identity could not be verified"
+// @Failure      403 {object} map[string]interface{} "No account for this identity or account inactive"
+// @Failure      409 {object} map[string]interface{} "Account already linked to another identity"
+// @Router       /auth/oidc/callback [post]
+func (s *oidcService) Callback(c *fiber.Ctx) error {
+	if s.provider == nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Single sign-on is not configured")
+	}
+
+	var req OIDCCallbackRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.ValidationErrorResponse(c, err)
+	}
+
+	binding := c.Cookies(oidcBindingCookie)
+	s.setBindingCookie(c, "", time.Unix(0, 0))
+
+	state, err := s.stateRepo.Consume(utils.HashToken(req.State), time.Now())
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid or expired login state")
+	}
+	if !stateBoundTo(state, binding) {
+		utils.GlobalLogger.LogAuth("oidc_login", "", false, map[string]interface{}{
+			"reason": "state not bound to this browser",
+			"ip":     c.IP(),
+		})
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Login was not started in this browser")
+	}
+
+	token, err := s.provider.Exchange(c.UserContext(), req.Code, state.CodeVerifier)
+	if err != nil {
+		utils.GlobalLogger.Error("OIDC code exchange failed", err)
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Identity could not be verified")
+	}
+
+	claims, err := s.provider.VerifyIDToken(c.UserContext(), token.IDToken, state.Nonce)
+	if err != nil {
+		utils.GlobalLogger.Error("OIDC ID token rejected", err)
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Identity could not be verified")
+	}
+
+	user, err := s.resolveUser(claims)
+	if err != nil {
+		subject := utils.ClaimString(claims, "sub")
+		utils.GlobalLogger.LogAuth("oidc_login", "", false, map[string]interface{}{
+			"subject": subject,
+			"reason":  err.Error(),
+		})
+		if loginErr, ok := err.(*oidcLoginError); ok {
+			return utils.ErrorResponse(c, loginErr.status, loginErr.message)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to sign in")
+	}
+
+	if !user.IsActive {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "Account is inactive")
+	}
+
+	utils.GlobalLogger.LogAuth("oidc_login", user.ID.String(), true)
+	return s.authService.finishLogin(c, user)
+}
+
+// setBindingCookie sets the login binding cookie, or clears it when value is empty. It is
+// scoped to the SSO routes, which Login and Callback share the parent path of.
+func (s *oidcService) setBindingCookie(c *fiber.Ctx, value string, expires time.Time) {
+	path := c.Path()
+	if i := strings.LastIndex(path, "/"); i > 0 {
+		path = path[:i]
+	}
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcBindingCookie,
+		Value:    value,
+		Path:     path,
+		Expires:  expires,
+		HTTPOnly: true,
+		Secure:   s.cfg.AppEnv == "production",
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+// stateBoundTo reports whether the login state was started by the browser presenting binding
+func stateBoundTo(state *models.OIDCState, binding string) bool {
+	if binding == "" || state.BindingHash == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(utils.HashToken(binding)), []byte(state.BindingHash)) == 1
+}
+
+// resolveUser maps verified ID token claims to a local account: first by linked
+// subject, then by NIM/NIP, then by email. Unmatched identities are provisioned
+// when enabled.
+func (s *oidcService) resolveUser(claims jwt.MapClaims) (*models.User, error) {
+	subject := utils.ClaimString(claims, "sub")
+
+	user, err := s.userRepo.FindByExternalID(subject)
+	if err != nil {
+		return nil, err
+	}
+	if user.ID != uuid.Nil {
+		return user, nil
+	}
+
+	candidate, err := s.findMatchingUser(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if candidate != nil {
+		if candidate.ExternalID != nil && *candidate.ExternalID != subject {
+			return nil, &oidcLoginError{fiber.StatusConflict, "Account is already linked to another identity"}
+		}
+		if candidate.AuthProvider != models.AuthProviderOIDC && !s.cfg.OIDCLinkExisting {
+			return nil, &oidcLoginError{fiber.StatusForbidden, "Account is not enabled for single sign-on"}
+		}
+		// Matching an email or NIP is not enough to hand out an administrator account
+		if candidate.Role.Name == authz.RoleAdmin {
+			return nil, &oidcLoginError{fiber.StatusForbidden, "Administrator accounts cannot be linked on sign-in"}
+		}
+
+		if err := s.userRepo.SetAuthProvider(candidate.ID, models.AuthProviderOIDC, &subject); err != nil {
+			return nil, err
+		}
+		candidate.AuthProvider = models.AuthProviderOIDC
+		candidate.ExternalID = &subject
+
+		utils.GlobalLogger.LogAuth("oidc_account_linked", candidate.ID.String(), true, map[string]interface{}{
+			"subject": subject,
+		})
+		return candidate, nil
+	}
+
+	if !s.cfg.OIDCAutoProvision {
+		return nil, &oidcLoginError{fiber.StatusForbidden, "No account found for this identity"}
+	}
+	return s.provisionUser(claims)
+}
+
+// findMatchingUser looks up an existing account by NIM, NIP or verified email
+func (s *oidcService) findMatchingUser(claims jwt.MapClaims) (*models.User, error) {
+	if nim := utils.ClaimString(claims, s.cfg.OIDCStudentIDClaim); nim != "" {
+		student, err := s.studentRepo.FindByStudentID(nim)
+		if err == nil && student.ID != uuid.Nil {
+			return s.findUser(student.UserID)
+		}
+	}
+
+	if nip := utils.ClaimString(claims, s.cfg.OIDCLecturerIDClaim); nip != "" {
+		lecturer, err := s.lecturerRepo.FindByLecturerID(nip)
+		if err == nil && lecturer.ID != uuid.Nil {
+			return s.findUser(lecturer.UserID)
+		}
+	}
+
+	if email := s.verifiedEmail(claims); email != "" {
+		user, err := s.userRepo.FindByEmail(email)
+		if err != nil {
+			return nil, err
+		}
+		if user.ID != uuid.Nil {
+			return user, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (s *oidcService) findUser(id uuid.UUID) (*models.User, error) {
+	user, err := s.userRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if user.ID == uuid.Nil {
+		return nil, nil
+	}
+	return user, nil
+}
+
+// provisionUser creates an account for a first-time SSO user. The role follows
+// from the NIM or NIP claim; identities with neither are not provisioned.
+func (s *oidcService) provisionUser(claims jwt.MapClaims) (*models.User, error) {
+	subject := utils.ClaimString(claims, "sub")
+	nim := utils.ClaimString(claims, s.cfg.OIDCStudentIDClaim)
+	nip := utils.ClaimString(claims, s.cfg.OIDCLecturerIDClaim)
+
+	email := s.verifiedEmail(claims)
+	if email == "" {
+		return nil, &oidcLoginError{fiber.StatusForbidden, "A verified email is required to create an account"}
+	}
+
+	var roleName string
+	switch {
+	case nim != "":
+		roleName = "Mahasiswa"
+	case nip != "":
+		roleName = "Dosen Wali"
+	default:
+		return nil, &oidcLoginError{fiber.StatusForbidden, "Cannot determine a role for this identity"}
+	}
+
+	role, err := s.roleRepo.FindByName(roleName)
+	if err != nil || role.ID == uuid.Nil {
+		return nil, &oidcLoginError{fiber.StatusInternalServerError, "Failed to find role"}
+	}
+
+	username := utils.ClaimString(claims, "preferred_username")
+	if username == "" {
+		username = strings.SplitN(email, "@", 2)[0]
+	}
+	if existing, err := s.userRepo.FindByUsername(username); err == nil && existing.ID != uuid.Nil {
+		return nil, &oidcLoginError{fiber.StatusConflict, "Username is already taken, ask an administrator to link the account"}
+	}
+
+	fullName := utils.ClaimString(claims, "name")
+	if fullName == "" {
+		fullName = username
+	}
+
+	// Federated accounts get an unusable random password
+	secret, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, err
+	}
+	passwordHash, err := utils.HashPassword(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Username:     username,
+		Email:        email,
+		PasswordHash: passwordHash,
+		FullName:     fullName,
+		RoleID:       role.ID,
+		IsActive:     true,
+		AuthProvider: models.AuthProviderOIDC,
+		ExternalID:   &subject,
+	}
+
+	// The account and its profile are created together, so a failed profile leaves no
+	// half-provisioned account behind for the next login to pick up
+	var student *models.Student
+	var lecturer *models.Lecturer
+	if nim != "" {
+		student = &models.Student{StudentID: nim}
+	} else {
+		lecturer = &models.Lecturer{LecturerID: nip}
+	}
+	if err := s.userRepo.CreateFederated(user, student, lecturer); err != nil {
+		return nil, err
+	}
+
+	utils.GlobalLogger.LogAuth("oidc_account_provisioned", user.ID.String(), true, map[string]interface{}{
+		"subject": subject,
+		"role":    roleName,
+	})
+
+	return s.userRepo.FindByID(user.ID)
+}
+
+// verifiedEmail returns the email claim if it may be trusted for account matching
+func (s *oidcService) verifiedEmail(claims jwt.MapClaims) string {
+	email := strings.ToLower(utils.ClaimString(claims, "email"))
+	if email == "" {
+		return ""
+	}
+	if verified, _ := claims["email_verified"].(bool); !verified && s.cfg.OIDCRequireVerifiedEmail {
+		return ""
+	}
+	return email
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"student-achievement-system/config"
+	"student-achievement-system/models"
+	"student-achievement-system/repository"
+	"student-achievement-system/utils"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// fakeOIDCStateRepo holds pending login states by hash
+type fakeOIDCStateRepo struct {
+	repository.OIDCStateRepository
+	states map[string]*models.OIDCState
+}
+
+func (r *fakeOIDCStateRepo) Consume(stateHash string, now time.Time) (*models.OIDCState, error) {
+	state, ok := r.states[stateHash]
+	if !ok || !state.ExpiresAt.After(now) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	delete(r.states, stateHash)
+	return state, nil
+}
+
+func TestStateBoundTo(t *testing.T) {
+	state := &models.OIDCState{BindingHash: utils.HashToken("browser-a")}
+
+	if !stateBoundTo(state, "browser-a") {
+		t.Error("stateBoundTo() = false for the browser that started the login")
+	}
+	for _, binding := range []string{"browser-b", ""} {
+		if stateBoundTo(state, binding) {
+			t.Errorf("stateBoundTo(%q) = true, want false", binding)
+		}
+	}
+	if stateBoundTo(&models.OIDCState{}, "") {
+		t.Error("stateBoundTo() = true for a state without binding")
+	}
+}
+
+func TestCallbackRejectsStateFromAnotherBrowser(t *testing.T) {
+	// The attacker started the login and hands their state and code to the victim's browser
+	states := &fakeOIDCStateRepo{states: map[string]*models.OIDCState{
+		utils.HashToken("attacker-state"): {
+			BindingHash: utils.HashToken("attacker-binding"),
+			ExpiresAt:   time.Now().Add(oidcStateTTL),
+		},
+	}}
+	s := &oidcService{stateRepo: states, provider: &utils.OIDCProvider{}, cfg: &config.Config{}}
+
+	app := fiber.New()
+	app.Post("/auth/oidc/callback", s.Callback)
+	req := httptest.NewRequest(fiber.MethodPost, "/auth/oidc/callback",
+		strings.NewReader(`{"code":"attacker-code","state":"attacker-state"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	req.AddCookie(&http.Cookie{Name: oidcBindingCookie, Value: "victim-binding"})
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("callback with another browser's state got %d, want 400", resp.StatusCode)
+	}
+	// The state is spent either way, so it cannot be retried
+	if len(states.states) != 0 {
+		t.Error("state left pending after a rejected callback")
+	}
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"student-achievement-system/middleware"
+	"student-achievement-system/models"
+	"student-achievement-system/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=6"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=6"`
+}
+
+// ChangePassword godoc
+// @Summary      Change password
+// @Description  Change the authenticated user's password. Every access and refresh token issued so far is revoked, signing out all sessions; the response carries a new token pair for the caller.
+// @Tags         Authentication
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body ChangePasswordRequest true "Old and new password"
+// @Success      200 {object} map[string]interface{} "Password changed successfully, with a new token pair"
+// @Failure      400 {object} map[string]interface{} "Invalid request body or validation failed"
+// @Failure      401 {object} map[string]interface{} "Old password is incorrect"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /auth/password/change [post]
+func (s *authService) ChangePassword(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	var req ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.ValidationErrorResponse(c, err)
+	}
+
+	user, err := s.userRepo.FindByID(claims.UserID)
+	if err != nil || user.ID == uuid.Nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "User not found")
+	}
+
+	if user.AuthProvider == models.AuthProviderOIDC {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Password is managed by the identity provider")
+	}
+
+	if !utils.CheckPassword(req.OldPassword, user.PasswordHash) {
+		utils.GlobalLogger.LogAuth("password_change", user.ID.String(), false)
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Old password is incorrect")
+	}
+
+	if err := s.setPassword(user, req.NewPassword); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to change password")
+	}
+
+	// Every token issued so far is revoked; the caller continues with a fresh pair
+	if err := s.tokenService.RevokeUserTokens(user.ID); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Password changed but failed to revoke existing tokens")
+	}
+
+	utils.GlobalLogger.LogAuth("password_change", user.ID.String(), true)
+	return s.completeLogin(c, user, "Password changed successfully", nil)
+}
+
+// ForgotPassword godoc
+// @Summary      Request password reset
+// @Description  Email a single-use password reset link. The response is the same whether or not the email is registered.
+// @Tags         Authentication
+// @Accept       json
+// @Produce      json
+// @Param        request body ForgotPasswordRequest true "Account email"
+// @Success      200 {object} map[string]interface{} "Reset instructions sent if the account exists"
+// @Failure      400 {object} map[string]interface{} "Invalid request body or validation failed"
+// @Router       /auth/password/forgot [post]
+func (s *authService) ForgotPassword(c *fiber.Ctx) error {
+	var req ForgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.ValidationErrorResponse(c, err)
+	}
+
+	const message = "If the email is registered, password reset instructions have been sent"
+
+	user, err := s.userRepo.FindByEmail(req.Email)
+	if err != nil || user.ID == uuid.Nil || !user.IsActive || user.AuthProvider == models.AuthProviderOIDC {
+		return utils.SuccessResponse(c, message, nil)
+	}
+
+	if err := s.sendPasswordReset(user); err != nil {
+		utils.GlobalLogger.Error("Failed to send password reset email", err, map[string]interface{}{
+			"user_id": user.ID,
+		})
+	}
+
+	return utils.SuccessResponse(c, message, nil)
+}
+
+// ResetPassword godoc
+// @Summary      Reset password
+// @Description  Set a new password using a token from the reset email. Tokens are single-use and expire; all existing sessions are signed out.
+// @Tags         Authentication
+// @Accept       json
+// @Produce      json
+// @Param        request body ResetPasswordRequest true "Reset token and new password"
+// @Success      200 {object} map[string]interface{} "Password reset successfully"
+// @Failure      400 {object} map[string]interface{} "Invalid or expired reset token"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /auth/password/reset [post]
+func (s *authService) ResetPassword(c *fiber.Ctx) error {
+	var req ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.ValidationErrorResponse(c, err)
+	}
+
+	userID, err := s.passwordResetRepo.Consume(utils.HashToken(req.Token), time.Now())
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid or expired reset token")
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil || user.ID == uuid.Nil || !user.IsActive || user.AuthProvider == models.AuthProviderOIDC {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid or expired reset token")
+	}
+
+	if err := s.setPassword(user, req.NewPassword); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to reset password")
+	}
+
+	// Whoever knew the old password must not stay signed in
+	if err := s.tokenService.RevokeUserTokens(user.ID); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Password reset but failed to sign out sessions")
+	}
+
+	utils.GlobalLogger.LogAuth("password_reset", user.ID.String(), true)
+	return utils.SuccessResponse(c, "Password reset successfully", nil)
+}
+
+// setPassword stores a new password hash and invalidates any outstanding reset tokens of the user
+func (s *authService) setPassword(user *models.User, password string) error {
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	user.PasswordHash = hash
+	user.UpdatedAt = time.Now()
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+
+	return s.passwordResetRepo.InvalidateForUser(user.ID)
+}
+
+// sendPasswordReset creates a reset token for the user and emails the reset link
+func (s *authService) sendPasswordReset(user *models.User) error {
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return err
+	}
+
+	resetToken := &models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(s.cfg.PasswordResetTTL),
+	}
+	if err := s.passwordResetRepo.Create(resetToken); err != nil {
+		return err
+	}
+
+	link := s.cfg.PasswordResetURL + "?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf(
+		"Hello %s,\n\n"+
+			"We received a request to reset the password of your account (%s).\n"+
+			"Open the link below to choose a new password:\n\n%s\n\n"+
+			"The link expires in %s and can only be used once.\n"+
+			"If you did not request a password reset, you can ignore this email.\n",
+		user.FullName, user.Username, link, s.cfg.PasswordResetTTL,
+	)
+
+	if err := s.mailer.Send(user.Email, "Password reset request", body); err != nil {
+		return err
+	}
+
+	utils.GlobalLogger.LogAuth("password_reset_requested", user.ID.String(), true)
+	return nil
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"student-achievement-system/repository"
+	"student-achievement-system/utils"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PermissionCache resolves the current role and permissions of a user for every request.
+// Entries are cached for ttl and dropped immediately when a user's role or a role's
+// permissions change on this instance. A token whose permission version is newer than
+// the cached role (changed on another instance) forces a reload.
+type PermissionCache struct {
+	userRepo repository.UserRepository
+	roleRepo repository.RoleRepository
+	ttl      time.Duration
+
+	mu    sync.RWMutex
+	users map[uuid.UUID]cachedUser
+	roles map[uuid.UUID]cachedRole
+}
+
+type cachedUser struct {
+	roleID   uuid.UUID
+	active   bool
+	loadedAt time.Time
+}
+
+type cachedRole struct {
+	name        string
+	permissions []string
+	version     int64
+	loadedAt    time.Time
+}
+
+func NewPermissionCache(userRepo repository.UserRepository, roleRepo repository.RoleRepository, ttl time.Duration) *PermissionCache {
+	return &PermissionCache{
+		userRepo: userRepo,
+		roleRepo: roleRepo,
+		ttl:      ttl,
+		users:    make(map[uuid.UUID]cachedUser),
+		roles:    make(map[uuid.UUID]cachedRole),
+	}
+}
+
+// Resolve fills in the role and permissions of the claims from the user's current role
+func (pc *PermissionCache) Resolve(claims *utils.JWTClaims) error {
+	user, err := pc.user(claims.UserID)
+	if err != nil {
+		return err
+	}
+	if !user.active {
+		return utils.ErrAccountUnavailable
+	}
+
+	role, err := pc.role(user.roleID, claims.PermissionVersion)
+	if err != nil {
+		return err
+	}
+
+	claims.RoleID = user.roleID
+	claims.RoleName = role.name
+	claims.Permissions = role.permissions
+	return nil
+}
+
+// RolePermissions returns the current permissions and permission version of a role
+func (pc *PermissionCache) RolePermissions(roleID uuid.UUID) ([]string, int64, error) {
+	role, err := pc.role(roleID, 0)
+	if err != nil {
+		return nil, 0, err
+	}
+	return role.permissions, role.version, nil
+}
+
+// InvalidateUser drops the cached role of a user, e.g. after a role assignment or deactivation
+func (pc *PermissionCache) InvalidateUser(userID uuid.UUID) {
+	pc.mu.Lock()
+	delete(pc.users, userID)
+	pc.mu.Unlock()
+}
+
+// InvalidateRole drops the cached permissions of a role after its grants changed
+func (pc *PermissionCache) InvalidateRole(roleID uuid.UUID) {
+	pc.mu.Lock()
+	delete(pc.roles, roleID)
+	pc.mu.Unlock()
+}
+
+// InvalidateAll drops every cached role, e.g. after a permission was renamed or deleted
+func (pc *PermissionCache) InvalidateAll() {
+	pc.mu.Lock()
+	pc.roles = make(map[uuid.UUID]cachedRole)
+	pc.mu.Unlock()
+}
+
+func (pc *PermissionCache) user(userID uuid.UUID) (cachedUser, error) {
+	pc.mu.RLock()
+	entry, ok := pc.users[userID]
+	pc.mu.RUnlock()
+	if ok && time.Since(entry.loadedAt) < pc.ttl {
+		return entry, nil
+	}
+
+	user, err := pc.userRepo.FindByID(userID)
+	if err != nil {
+		return cachedUser{}, err
+	}
+	if user.ID == uuid.Nil {
+		return cachedUser{}, utils.ErrAccountUnavailable
+	}
+
+	entry = cachedUser{roleID: user.RoleID, active: user.IsActive, loadedAt: time.Now()}
+	pc.mu.Lock()
+	pc.users[userID] = entry
+	pc.mu.Unlock()
+	return entry, nil
+}
+
+func (pc *PermissionCache) role(roleID uuid.UUID, minVersion int64) (cachedRole, error) {
+	pc.mu.RLock()
+	entry, ok := pc.roles[roleID]
+	pc.mu.RUnlock()
+	if ok && time.Since(entry.loadedAt) < pc.ttl && entry.version >= minVersion {
+		return entry, nil
+	}
+
+	role, err := pc.roleRepo.FindByID(roleID)
+	if err != nil {
+		return cachedRole{}, err
+	}
+	if role.ID == uuid.Nil {
+		return cachedRole{}, utils.ErrAccountUnavailable
+	}
+
+	permissions, err := pc.userRepo.GetUserPermissions(roleID)
+	if err != nil {
+		return cachedRole{}, err
+	}
+	if permissions == nil {
+		permissions = []string{}
+	}
+
+	entry = cachedRole{
+		name:        role.Name,
+		permissions: permissions,
+		version:     role.PermissionVersion,
+		loadedAt:    time.Now(),
+	}
+	pc.mu.Lock()
+	pc.roles[roleID] = entry
+	pc.mu.Unlock()
+	return entry, nil
+}
@@ -0,0 +1,356 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"student-achievement-system/models"
+	"student-achievement-system/points"
+	"student-achievement-system/repository"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// rescoreBatchSize is the number of achievements read and updated together
+const rescoreBatchSize = 200
+
+// PointsRescorer recalculates the points of existing achievements with a points rule set,
+// e.g. once new rules took effect. It runs from the admin API and the command line.
+type PointsRescorer interface {
+	Rescore(options RescoreOptions) (*RescoreReport, error)
+}
+
+// RescoreOptions selects the achievements to rescore and how
+type RescoreOptions struct {
+	Status          models.AchievementStatus // every status but deleted when empty
+	AchievementType string
+	CreatedFrom     *time.Time
+	CreatedTo       *time.Time // exclusive
+	Version         int        // rule set to score with, the one in force when 0
+	Apply           bool       // save the new points; otherwise only report them
+	After           string     // continue after this achievement, as reported in Next
+	Limit           int        // read at most this many achievements, 0 for all of them
+	AuthorID        string     // recorded on the revisions of rescored achievements
+	AuthorName      string
+}
+
+// RescoreReport describes the points a rescoring changed, or would change on a dry run
+type RescoreReport struct {
+	RuleSetVersion int               `json:"rule_set_version"`
+	Applied        bool              `json:"applied"`
+	Scanned        int               `json:"scanned"`  // achievements matching the filters
+	Changed        int               `json:"changed"`  // of which scored differently
+	Updated        int               `json:"updated"`  // of which saved, 0 on a dry run
+	Repaired       int               `json:"repaired"` // already rescored, but missing the revision an earlier run failed to record
+	Delta          int               `json:"delta"`
+	Students       []RescoredStudent `json:"students"`
+	Next           string            `json:"next,omitempty"` // where to continue when the limit stopped the run
+}
+
+// RescoredStudent is the change to one student's points
+type RescoredStudent struct {
+	points.StudentDelta
+	StudentNumber string `json:"student_number,omitempty"`
+	FullName      string `json:"full_name,omitempty"`
+}
+
+// NewRescoreOptions checks the filters of a rescoring given as text, by the admin API or
+// on the command line. Dates are YYYY-MM-DD and to is inclusive.
+func NewRescoreOptions(status, achievementType, from, to string, version int, apply bool) (RescoreOptions, error) {
+	options := RescoreOptions{
+		Status:          models.AchievementStatus(status),
+		AchievementType: achievementType,
+		Version:         version,
+		Apply:           apply,
+	}
+
+	switch options.Status {
+	case "", models.StatusDraft, models.StatusSubmitted, models.StatusVerified, models.StatusRejected, models.StatusDeleted:
+	default:
+		return options, fmt.Errorf("invalid status '%s'", status)
+	}
+	switch models.AchievementType(achievementType) {
+	case "", models.TypeAcademic, models.TypeCompetition, models.TypeOrganization,
+		models.TypePublication, models.TypeCertification, models.TypeOther:
+	default:
+		return options, fmt.Errorf("invalid achievement type '%s'", achievementType)
+	}
+	if version < 0 {
+		return options, fmt.Errorf("invalid rule set version %d", version)
+	}
+
+	if from != "" {
+		createdFrom, err := time.ParseInLocation("2006-01-02", from, time.Local)
+		if err != nil {
+			return options, fmt.Errorf("invalid from date, expected YYYY-MM-DD")
+		}
+		options.CreatedFrom = &createdFrom
+	}
+	if to != "" {
+		toDate, err := time.ParseInLocation("2006-01-02", to, time.Local)
+		if err != nil {
+			return options, fmt.Errorf("invalid to date, expected YYYY-MM-DD")
+		}
+		// The to date is the last day included
+		createdTo := toDate.AddDate(0, 0, 1)
+		options.CreatedTo = &createdTo
+	}
+	if options.CreatedFrom != nil && options.CreatedTo != nil && !options.CreatedTo.After(*options.CreatedFrom) {
+		return options, fmt.Errorf("to must not be before from")
+	}
+	return options, nil
+}
+
+type pointsRescorer struct {
+	achievementRepo    repository.AchievementRepository
+	revisionRepo       repository.AchievementRevisionRepository
+	achievementRefRepo repository.AchievementReferenceRepository
+	studentRepo        repository.StudentRepository
+	pointsRuleRepo     repository.PointsRuleRepository
+}
+
+func NewPointsRescorer(
+	achievementRepo repository.AchievementRepository,
+	revisionRepo repository.AchievementRevisionRepository,
+	achievementRefRepo repository.AchievementReferenceRepository,
+	studentRepo repository.StudentRepository,
+	pointsRuleRepo repository.PointsRuleRepository,
+) PointsRescorer {
+	return &pointsRescorer{
+		achievementRepo:    achievementRepo,
+		revisionRepo:       revisionRepo,
+		achievementRefRepo: achievementRefRepo,
+		studentRepo:        studentRepo,
+		pointsRuleRepo:     pointsRuleRepo,
+	}
+}
+
+// Rescore scores every achievement matching the options with the chosen rule set. When
+// applying, achievements are updated in batches and each one changed gets a revision;
+// achievements already scored with the rule set are skipped, so an interrupted or
+// repeated rescoring can simply be run again, which also records the revisions an
+// earlier run failed to. A run stopped by the limit reports where to continue.
+func (r *pointsRescorer) Rescore(options RescoreOptions) (*RescoreReport, error) {
+	rules, version, err := r.ruleSet(options.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RescoreReport{RuleSetVersion: version, Applied: options.Apply}
+	filter := repository.AchievementFilter{
+		AchievementType: options.AchievementType,
+		CreatedFrom:     options.CreatedFrom,
+		CreatedTo:       options.CreatedTo,
+	}
+
+	ctx := context.Background()
+	changes := make([]points.Change, 0)
+	afterID := options.After
+	read := 0
+	for {
+		size := rescoreBatchSize
+		if options.Limit > 0 {
+			if read >= options.Limit {
+				report.Next = afterID
+				break
+			}
+			size = min(size, options.Limit-read)
+		}
+
+		batch, err := r.achievementRepo.FindBatch(ctx, filter, afterID, size)
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		afterID = batch[len(batch)-1].ID.Hex()
+		read += len(batch)
+
+		achievements, err := r.withStatus(batch, options.Status)
+		if err != nil {
+			return nil, err
+		}
+		report.Scanned += len(achievements)
+
+		batchChanges := make([]points.Change, 0)
+		rescored := make(map[string]*models.Achievement)
+		unchanged := make([]*models.Achievement, 0)
+		for _, achievement := range achievements {
+			if change, changed := points.Rescore(rules, version, achievement); changed {
+				batchChanges = append(batchChanges, change)
+				rescored[change.AchievementID] = achievement
+			} else {
+				unchanged = append(unchanged, achievement)
+			}
+		}
+		report.Changed += len(batchChanges)
+
+		if options.Apply {
+			repaired, err := r.repairRevisions(ctx, unchanged, options)
+			if err != nil {
+				return nil, err
+			}
+			report.Repaired += repaired
+		}
+		if options.Apply && len(batchChanges) > 0 {
+			updated, err := r.apply(ctx, batchChanges, rescored, options)
+			if err != nil {
+				return nil, err
+			}
+			batchChanges = updated
+			report.Updated += len(updated)
+		}
+		changes = append(changes, batchChanges...)
+	}
+
+	report.Students = make([]RescoredStudent, 0)
+	for _, delta := range points.ByStudent(changes) {
+		report.Delta += delta.Delta
+		report.Students = append(report.Students, r.describeStudent(delta))
+	}
+	return report, nil
+}
+
+// ruleSet returns the rules of a version, or of the one in force when version is 0
+func (r *pointsRescorer) ruleSet(version int) ([]models.PointsRule, int, error) {
+	var set *models.PointsRuleSet
+	var err error
+	if version > 0 {
+		set, err = r.pointsRuleRepo.FindByVersion(version)
+		if err == nil && set == nil {
+			err = fmt.Errorf("points rule set version %d not found", version)
+		}
+	} else {
+		set, err = r.pointsRuleRepo.FindInForce(time.Now())
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if set == nil {
+		return points.DefaultRules(), 0, nil
+	}
+	return set.Rules, set.Version, nil
+}
+
+// withStatus keeps the achievements of a batch in the status, leaving out deleted ones
+// unless those are asked for
+func (r *pointsRescorer) withStatus(batch []models.Achievement, status models.AchievementStatus) ([]*models.Achievement, error) {
+	ids := make([]string, 0, len(batch))
+	for _, achievement := range batch {
+		ids = append(ids, achievement.ID.Hex())
+	}
+	refs, err := r.achievementRefRepo.FindByMongoIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make(map[string]models.AchievementStatus, len(refs))
+	for _, ref := range refs {
+		statuses[ref.MongoAchievementID] = ref.Status
+	}
+
+	kept := make([]*models.Achievement, 0, len(batch))
+	for i := range batch {
+		current, ok := statuses[batch[i].ID.Hex()]
+		if !ok || (status == "" && current == models.StatusDeleted) || (status != "" && current != status) {
+			continue
+		}
+		kept = append(kept, &batch[i])
+	}
+	return kept, nil
+}
+
+// apply saves a batch of changes and records a revision of each achievement it changed,
+// returning the changes that were saved. A revision that cannot be recorded fails the
+// batch; running the rescoring again records it.
+func (r *pointsRescorer) apply(ctx context.Context, changes []points.Change, achievements map[string]*models.Achievement, options RescoreOptions) ([]points.Change, error) {
+	updates := make([]repository.PointsUpdate, 0, len(changes))
+	for _, change := range changes {
+		updates = append(updates, repository.PointsUpdate{
+			ID:              change.AchievementID,
+			Points:          change.PointsAfter,
+			Version:         change.VersionAfter,
+			PreviousPoints:  change.PointsBefore,
+			PreviousVersion: change.VersionBefore,
+		})
+	}
+
+	updatedIDs, err := r.achievementRepo.UpdatePoints(ctx, updates)
+	updated := make(map[string]bool, len(updatedIDs))
+	for _, id := range updatedIDs {
+		updated[id] = true
+	}
+
+	saved := make([]points.Change, 0, len(updatedIDs))
+	for _, change := range changes {
+		if !updated[change.AchievementID] {
+			continue
+		}
+		saved = append(saved, change)
+
+		achievement := achievements[change.AchievementID]
+		achievement.Points = change.PointsAfter
+		achievement.PointsVersion = change.VersionAfter
+		if revisionErr := r.recordRescore(ctx, achievement, options); revisionErr != nil {
+			return nil, revisionErr
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// repairRevisions records the rescore revisions an earlier run saved the points of but
+// failed to record, found as achievements whose latest revision has other points than
+// the achievement. It returns the number recorded.
+func (r *pointsRescorer) repairRevisions(ctx context.Context, achievements []*models.Achievement, options RescoreOptions) (int, error) {
+	ids := make([]string, 0, len(achievements))
+	for _, achievement := range achievements {
+		ids = append(ids, achievement.ID.Hex())
+	}
+	latest, err := r.revisionRepo.FindLatestByAchievementIDs(ctx, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	repaired := 0
+	for _, achievement := range achievements {
+		revision, ok := latest[achievement.ID.Hex()]
+		if !ok || (revision.Content.Points == achievement.Points && revision.Content.PointsVersion == achievement.PointsVersion) {
+			continue
+		}
+		if err := r.recordRescore(ctx, achievement, options); err != nil {
+			return repaired, err
+		}
+		repaired++
+	}
+	return repaired, nil
+}
+
+// recordRescore records the revision of a rescored achievement
+func (r *pointsRescorer) recordRescore(ctx context.Context, achievement *models.Achievement, options RescoreOptions) error {
+	revision := &models.AchievementRevision{
+		AchievementID: achievement.ID.Hex(),
+		Change:        "rescore",
+		Content:       *achievement,
+		AuthorID:      options.AuthorID,
+		AuthorName:    options.AuthorName,
+	}
+	if err := r.revisionRepo.Create(ctx, revision); err != nil {
+		return fmt.Errorf("points of achievement %s were saved but its revision was not recorded, run the rescoring again: %w", revision.AchievementID, err)
+	}
+	return nil
+}
+
+// describeStudent names the student a delta belongs to
+func (r *pointsRescorer) describeStudent(delta points.StudentDelta) RescoredStudent {
+	described := RescoredStudent{StudentDelta: delta}
+	if id, err := uuid.Parse(delta.StudentID); err == nil {
+		if student, err := r.studentRepo.FindByID(id); err == nil && student.ID != uuid.Nil {
+			described.StudentNumber = student.StudentID
+			described.FullName = student.User.FullName
+		}
+	}
+	return described
+}
@@ -0,0 +1,399 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"student-achievement-system/middleware"
+	"student-achievement-system/models"
+	"student-achievement-system/points"
+	"student-achievement-system/repository"
+	"student-achievement-system/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PointsRuleService interface {
+	ListRuleSets(c *fiber.Ctx) error
+	GetRuleSet(c *fiber.Ctx) error
+	CreateRuleSet(c *fiber.Ctx) error
+	UpdateRuleSet(c *fiber.Ctx) error
+	DeleteRuleSet(c *fiber.Ctx) error
+	RescoreAchievements(c *fiber.Ctx) error
+}
+
+type PointsRuleSetRequest struct {
+	Name          string              `json:"name" validate:"required,max=100"`
+	EffectiveFrom string              `json:"effective_from" validate:"required"`
+	Rules         []PointsRuleRequest `json:"rules" validate:"required,min=1,dive"`
+}
+
+type PointsRuleRequest struct {
+	Name             string            `json:"name" validate:"required,max=100"`
+	Points           int               `json:"points"`
+	AchievementType  string            `json:"achievement_type,omitempty" validate:"omitempty,oneof=academic competition organization publication certification other"`
+	CompetitionLevel string            `json:"competition_level,omitempty" validate:"max=50"`
+	Rank             *int              `json:"rank,omitempty" validate:"omitempty,min=1"`
+	MedalType        string            `json:"medal_type,omitempty" validate:"max=50"`
+	PublicationType  string            `json:"publication_type,omitempty" validate:"max=50"`
+	CustomFields     map[string]string `json:"custom_fields,omitempty"`
+}
+
+// RescoreRequest selects the achievements to rescore
+type RescoreRequest struct {
+	Status          string `json:"status,omitempty"`
+	AchievementType string `json:"achievement_type,omitempty"`
+	From            string `json:"from,omitempty"`
+	To              string `json:"to,omitempty"`
+	Version         int    `json:"version,omitempty"`
+	Apply           bool   `json:"apply"`
+	After           string `json:"after,omitempty"` // the next of the previous run, to continue it
+	Limit           int    `json:"limit,omitempty"` // achievements to read, at most rescoreRequestLimit
+}
+
+// rescoreRequestLimit is the most achievements one rescoring request reads; larger
+// rescorings continue over several requests or run from the command line
+const rescoreRequestLimit = 2000
+
+type pointsRuleService struct {
+	pointsRuleRepo repository.PointsRuleRepository
+	rescorer       PointsRescorer
+	auditRepo      repository.AuditRepository
+}
+
+func NewPointsRuleService(pointsRuleRepo repository.PointsRuleRepository, rescorer PointsRescorer, auditRepo repository.AuditRepository) PointsRuleService {
+	return &pointsRuleService{
+		pointsRuleRepo: pointsRuleRepo,
+		rescorer:       rescorer,
+		auditRepo:      auditRepo,
+	}
+}
+
+// ListRuleSets godoc
+// @Summary      List points rule sets
+// @Description  Get every version of the points rules, newest first, with the version achievements are scored with now. Scored achievements record the version that produced their points.
+// @Tags         Points Rules
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200 {object} map[string]interface{} "Points rule sets"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /points-rule-sets [get]
+func (s *pointsRuleService) ListRuleSets(c *fiber.Ctx) error {
+	sets, err := s.pointsRuleRepo.FindAll()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to get points rule sets")
+	}
+
+	inForce, err := s.pointsRuleRepo.FindInForce(time.Now())
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to get the points rule set in force")
+	}
+	inForceVersion := 0
+	if inForce != nil {
+		inForceVersion = inForce.Version
+	}
+
+	return utils.SuccessResponse(c, "Points rule sets retrieved successfully", fiber.Map{
+		"rule_sets":        sets,
+		"in_force_version": inForceVersion,
+	})
+}
+
+// GetRuleSet godoc
+// @Summary      Get points rule set
+// @Description  Get a version of the points rules
+// @Tags         Points Rules
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path     string  true  "Points rule set ID (UUID)"
+// @Success      200 {object} map[string]interface{} "Points rule set"
+// @Failure      400 {object} map[string]interface{} "Invalid points rule set ID"
+// @Failure      404 {object} map[string]interface{} "Points rule set not found"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /points-rule-sets/{id} [get]
+func (s *pointsRuleService) GetRuleSet(c *fiber.Ctx) error {
+	set, err := s.findRuleSet(c)
+	if set == nil {
+		return err
+	}
+	return utils.SuccessResponse(c, "Points rule set retrieved successfully", set)
+}
+
+// CreateRuleSet godoc
+// @Summary      Create points rule set
+// @Description  Add the next version of the points rules, in force from effective_from (YYYY-MM-DD, today or later). Every rule whose conditions an achievement meets adds its points; conditions left empty match any achievement and text is compared case-insensitively. custom_fields match the custom fields of academic and other achievements.
+// @Tags         Points Rules
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body PointsRuleSetRequest true "Rule set with its rules"
+// @Success      200 {object} map[string]interface{} "Points rule set created successfully"
+// @Failure      400 {object} map[string]interface{} "Invalid request body or validation failed"
+// @Failure      409 {object} map[string]interface{} "Another rule set was created at the same time"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /points-rule-sets [post]
+func (s *pointsRuleService) CreateRuleSet(c *fiber.Ctx) error {
+	set, err := s.buildRuleSet(c)
+	if set == nil {
+		return err
+	}
+	if claims := middleware.GetUserFromContext(c); claims != nil {
+		set.CreatedBy = &claims.UserID
+	}
+
+	if err := s.pointsRuleRepo.Create(set); err != nil {
+		if errors.Is(err, repository.ErrRuleSetVersionTaken) {
+			return utils.ErrorResponse(c, fiber.StatusConflict, "Another rule set was created at the same time, please try again")
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create points rule set")
+	}
+
+	RecordAudit(c, s.auditRepo, "points_rules.create", "points_rule_set", set.ID.String(), auditChanges(nil, set))
+
+	created, _ := s.pointsRuleRepo.FindByID(set.ID)
+	if created == nil {
+		created = set
+	}
+	return utils.SuccessResponse(c, "Points rule set created successfully", created)
+}
+
+// UpdateRuleSet godoc
+// @Summary      Update points rule set
+// @Description  Replace the name, effective date and rules of a version that is not in force yet. Versions already in force are kept as they are so that scored achievements can be traced to their rules; create a new version instead.
+// @Tags         Points Rules
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path     string                true  "Points rule set ID (UUID)"
+// @Param        request  body     PointsRuleSetRequest  true  "Rule set with its rules"
+// @Success      200 {object} map[string]interface{} "Points rule set updated successfully"
+// @Failure      400 {object} map[string]interface{} "Invalid request body or validation failed"
+// @Failure      404 {object} map[string]interface{} "Points rule set not found"
+// @Failure      409 {object} map[string]interface{} "Points rule set already in force"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /points-rule-sets/{id} [put]
+func (s *pointsRuleService) UpdateRuleSet(c *fiber.Ctx) error {
+	existing, err := s.findRuleSet(c)
+	if existing == nil {
+		return err
+	}
+	if ok, err := checkNotInForce(c, existing); !ok {
+		return err
+	}
+
+	set, err := s.buildRuleSet(c)
+	if set == nil {
+		return err
+	}
+	set.ID = existing.ID
+	set.Version = existing.Version
+	set.CreatedBy = existing.CreatedBy
+
+	if err := s.pointsRuleRepo.Update(set); err != nil {
+		if errors.Is(err, repository.ErrRuleSetInForce) {
+			return ruleSetInForce(c)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update points rule set")
+	}
+
+	RecordAudit(c, s.auditRepo, "points_rules.update", "points_rule_set", set.ID.String(), auditChanges(existing, set))
+
+	updated, _ := s.pointsRuleRepo.FindByID(set.ID)
+	if updated == nil {
+		updated = set
+	}
+	return utils.SuccessResponse(c, "Points rule set updated successfully", updated)
+}
+
+// DeleteRuleSet godoc
+// @Summary      Delete points rule set
+// @Description  Delete a version that is not in force yet
+// @Tags         Points Rules
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path     string  true  "Points rule set ID (UUID)"
+// @Success      200 {object} map[string]interface{} "Points rule set deleted successfully"
+// @Failure      400 {object} map[string]interface{} "Invalid points rule set ID"
+// @Failure      404 {object} map[string]interface{} "Points rule set not found"
+// @Failure      409 {object} map[string]interface{} "Points rule set already in force"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /points-rule-sets/{id} [delete]
+func (s *pointsRuleService) DeleteRuleSet(c *fiber.Ctx) error {
+	set, err := s.findRuleSet(c)
+	if set == nil {
+		return err
+	}
+	if ok, err := checkNotInForce(c, set); !ok {
+		return err
+	}
+
+	if err := s.pointsRuleRepo.Delete(set.ID); err != nil {
+		if errors.Is(err, repository.ErrRuleSetInForce) {
+			return ruleSetInForce(c)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete points rule set")
+	}
+
+	RecordAudit(c, s.auditRepo, "points_rules.delete", "points_rule_set", set.ID.String(), auditChanges(set, nil))
+
+	return utils.SuccessResponse(c, "Points rule set deleted successfully", nil)
+}
+
+// RescoreAchievements godoc
+// @Summary      Rescore achievements
+// @Description  Recalculate the points of existing achievements with a rule set version, the one in force by default. Achievements can be filtered by status (every status but deleted by default), achievement type and creation date from/to (YYYY-MM-DD, inclusive). Without apply the new points are only reported, with the change per student; with apply they are saved and every rescored achievement gets a revision. Achievements already scored with the version are skipped, so a rescoring can safely be repeated. A request reads at most 2000 achievements (fewer with limit); when more are left the report holds next, which is passed as after to continue.
+// @Tags         Points Rules
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body RescoreRequest true "Filters, rule set version and whether to save the new points"
+// @Success      200 {object} map[string]interface{} "Points changed per student"
+// @Failure      400 {object} map[string]interface{} "Invalid request body or filters"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /points-rule-sets/rescore [post]
+func (s *pointsRuleService) RescoreAchievements(c *fiber.Ctx) error {
+	var req RescoreRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	options, err := NewRescoreOptions(req.Status, req.AchievementType, req.From, req.To, req.Version, req.Apply)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+	if req.After != "" && !primitive.IsValidObjectID(req.After) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid after, expected an achievement ID")
+	}
+	if req.Limit < 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid limit")
+	}
+	options.After = req.After
+	options.Limit = rescoreRequestLimit
+	if req.Limit > 0 && req.Limit < rescoreRequestLimit {
+		options.Limit = req.Limit
+	}
+	if options.Version > 0 {
+		set, err := s.pointsRuleRepo.FindByVersion(options.Version)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to get points rule set")
+		}
+		if set == nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Points rule set version not found")
+		}
+	}
+	if claims := middleware.GetUserFromContext(c); claims != nil {
+		options.AuthorID = claims.UserID.String()
+		options.AuthorName = claims.Username
+	}
+
+	report, err := s.rescorer.Rescore(options)
+	if err != nil {
+		utils.GlobalLogger.Error("Failed to rescore achievements", err)
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to rescore achievements")
+	}
+
+	if !report.Applied {
+		return utils.SuccessResponse(c, "Rescoring previewed, no points were changed", report)
+	}
+
+	RecordAudit(c, s.auditRepo, "points_rules.rescore", "points_rule_set", strconv.Itoa(report.RuleSetVersion), fiber.Map{
+		"filters":  req,
+		"scanned":  report.Scanned,
+		"updated":  report.Updated,
+		"repaired": report.Repaired,
+		"delta":    report.Delta,
+		"next":     report.Next,
+	})
+	return utils.SuccessResponse(c, "Achievements rescored successfully", report)
+}
+
+// findRuleSet loads the rule set named by the :id route parameter.
+// On failure it writes the error response and returns a nil rule set.
+func (s *pointsRuleService) findRuleSet(c *fiber.Ctx) (*models.PointsRuleSet, error) {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid points rule set ID")
+	}
+
+	set, err := s.pointsRuleRepo.FindByID(id)
+	if err != nil {
+		return nil, utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to get points rule set")
+	}
+	if set == nil {
+		return nil, utils.ErrorResponse(c, fiber.StatusNotFound, "Points rule set not found")
+	}
+	return set, nil
+}
+
+// checkNotInForce refuses changes to a rule set that has taken effect, as achievements may
+// have been scored with it. When it returns false the error response has already been
+// written. The repository checks again when saving, the set may take effect meanwhile.
+func checkNotInForce(c *fiber.Ctx, set *models.PointsRuleSet) (bool, error) {
+	if !set.EffectiveFrom.After(time.Now()) {
+		return false, ruleSetInForce(c)
+	}
+	return true, nil
+}
+
+// ruleSetInForce writes the response for a change to a rule set that has taken effect
+func ruleSetInForce(c *fiber.Ctx) error {
+	return utils.ErrorResponse(c, fiber.StatusConflict,
+		"This rule set is already in force and cannot change; create a new version instead")
+}
+
+// buildRuleSet parses and validates the rule set in the request body. On failure it
+// writes the error response and returns a nil rule set.
+func (s *pointsRuleService) buildRuleSet(c *fiber.Ctx) (*models.PointsRuleSet, error) {
+	var req PointsRuleSetRequest
+	if err := c.BodyParser(&req); err != nil {
+		return nil, utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := utils.ValidateStruct(&req); err != nil {
+		return nil, utils.ValidationErrorResponse(c, err)
+	}
+
+	effectiveFrom, err := time.ParseInLocation("2006-01-02", req.EffectiveFrom, time.Local)
+	if err != nil {
+		return nil, utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid effective_from, expected YYYY-MM-DD")
+	}
+	// Rules may not reach back to achievements already scored
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if effectiveFrom.Before(today) {
+		return nil, utils.ErrorResponse(c, fiber.StatusBadRequest, "effective_from must not be in the past")
+	}
+
+	set := &models.PointsRuleSet{
+		Name:          strings.TrimSpace(req.Name),
+		EffectiveFrom: effectiveFrom,
+		Rules:         make([]models.PointsRule, 0, len(req.Rules)),
+	}
+	for _, ruleReq := range req.Rules {
+		rule := models.PointsRule{
+			Name:             strings.TrimSpace(ruleReq.Name),
+			Points:           ruleReq.Points,
+			AchievementType:  ruleReq.AchievementType,
+			CompetitionLevel: strings.ToLower(strings.TrimSpace(ruleReq.CompetitionLevel)),
+			Rank:             ruleReq.Rank,
+			MedalType:        strings.ToLower(strings.TrimSpace(ruleReq.MedalType)),
+			PublicationType:  strings.ToLower(strings.TrimSpace(ruleReq.PublicationType)),
+		}
+		if len(ruleReq.CustomFields) > 0 {
+			rule.CustomFields = make(map[string]string, len(ruleReq.CustomFields))
+			for field, value := range ruleReq.CustomFields {
+				rule.CustomFields[strings.TrimSpace(field)] = strings.TrimSpace(value)
+			}
+		}
+		set.Rules = append(set.Rules, rule)
+	}
+
+	if err := points.Validate(set.Rules); err != nil {
+		return nil, utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+	return set, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"student-achievement-system/models"
 	"student-achievement-system/repository"
+	"student-achievement-system/sla"
 	"student-achievement-system/utils"
 	"time"
 
@@ -17,6 +18,7 @@ type ReportService interface {
 	GetTopStudents(c *fiber.Ctx) error
 	GetStatisticsByPeriod(c *fiber.Ctx) error
 	GetCompetitionLevelDistribution(c *fiber.Ctx) error
+	GetVerificationSLA(c *fiber.Ctx) error
 }
 
 type reportService struct {
@@ -24,6 +26,8 @@ type reportService struct {
 	achievementRefRepo repository.AchievementReferenceRepository
 	studentRepo        repository.StudentRepository
 	lecturerRepo       repository.LecturerRepository
+	slaPolicy          sla.Policy
+	clock              sla.Clock
 }
 
 func NewReportService(
@@ -31,12 +35,16 @@ func NewReportService(
 	achievementRefRepo repository.AchievementReferenceRepository,
 	studentRepo repository.StudentRepository,
 	lecturerRepo repository.LecturerRepository,
+	slaPolicy sla.Policy,
+	clock sla.Clock,
 ) ReportService {
 	return &reportService{
 		achievementRepo:    achievementRepo,
 		achievementRefRepo: achievementRefRepo,
 		studentRepo:        studentRepo,
 		lecturerRepo:       lecturerRepo,
+		slaPolicy:          slaPolicy,
+		clock:              clock,
 	}
 }
 
@@ -254,3 +262,54 @@ func (s *reportService) GetCompetitionLevelDistribution(c *fiber.Ctx) error {
 		"note":         "Distribution of achievements by competition level - from competition type achievements",
 	})
 }
+
+// GetVerificationSLA godoc
+// @Summary      Get overdue verifications per lecturer
+// @Description  Count, per advisor, the submissions waiting for their decision longer than the verification SLA and those waiting past the escalation threshold
+// @Tags         Reports
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200 {object} map[string]interface{} "Verification SLA report retrieved successfully"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /reports/verification-sla [get]
+func (s *reportService) GetVerificationSLA(c *fiber.Ctx) error {
+	if !s.slaPolicy.Enabled() {
+		return utils.SuccessResponse(c, "Verification SLA is disabled", fiber.Map{
+			"enabled":   false,
+			"lecturers": []fiber.Map{},
+		})
+	}
+
+	now := s.clock.Now()
+	counts, err := s.achievementRefRepo.CountOverdueByAdvisor(s.slaPolicy.OverdueBefore(now), s.slaPolicy.EscalatedBefore(now))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to count overdue verifications")
+	}
+
+	lecturers := make([]fiber.Map, 0, len(counts))
+	var overdue, escalated int64
+	for _, count := range counts {
+		overdue += count.Overdue
+		escalated += count.Escalated
+		lecturers = append(lecturers, fiber.Map{
+			"lecturer_id":         count.LecturerID,
+			"lecturer_number":     count.LecturerNumber,
+			"full_name":           count.FullName,
+			"department":          count.Department,
+			"overdue":             count.Overdue,
+			"escalated":           count.Escalated,
+			"oldest_submitted_at": count.OldestSubmittedAt,
+		})
+	}
+
+	return utils.SuccessResponse(c, "Verification SLA report retrieved successfully", fiber.Map{
+		"enabled":         true,
+		"sla":             s.slaPolicy.RemindAfter.String(),
+		"escalate_after":  s.slaPolicy.EscalateAfter.String(),
+		"total_overdue":   overdue,
+		"total_escalated": escalated,
+		"lecturers":       lecturers,
+	})
+}
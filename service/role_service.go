@@ -0,0 +1,565 @@
+package service
+
+import (
+	"regexp"
+	"student-achievement-system/models"
+	"student-achievement-system/repository"
+	"student-achievement-system/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// permissionNamePattern enforces the "resource:action" naming used by the routes
+var permissionNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*:[a-z][a-z0-9_-]*$`)
+
+// rbacManagePermission guards the role and permission admin API; the Admin role
+// cannot lose it, or nobody could manage roles any more
+const rbacManagePermission = "role:manage"
+
+type RoleService interface {
+	GetRole(c *fiber.Ctx) error
+	CreateRole(c *fiber.Ctx) error
+	UpdateRole(c *fiber.Ctx) error
+	DeleteRole(c *fiber.Ctx) error
+	GrantRolePermissions(c *fiber.Ctx) error
+	RevokeRolePermission(c *fiber.Ctx) error
+	ListPermissions(c *fiber.Ctx) error
+	GetPermission(c *fiber.Ctx) error
+	CreatePermission(c *fiber.Ctx) error
+	UpdatePermission(c *fiber.Ctx) error
+	DeletePermission(c *fiber.Ctx) error
+}
+
+type RoleRequest struct {
+	Name        string `json:"name" validate:"required,max=50"`
+	Description string `json:"description,omitempty"`
+}
+
+type PermissionRequest struct {
+	Name        string `json:"name" validate:"required,max=100"`
+	Description string `json:"description,omitempty"`
+}
+
+type GrantPermissionsRequest struct {
+	Permissions []string `json:"permissions" validate:"required,min=1,dive,required"`
+}
+
+type roleService struct {
+	roleRepo       repository.RoleRepository
+	permissionRepo repository.PermissionRepository
+	auditRepo      repository.AuditRepository
+	permissions    *PermissionCache
+}
+
+func NewRoleService(
+	roleRepo repository.RoleRepository,
+	permissionRepo repository.PermissionRepository,
+	auditRepo repository.AuditRepository,
+	permissions *PermissionCache,
+) RoleService {
+	return &roleService{
+		roleRepo:       roleRepo,
+		permissionRepo: permissionRepo,
+		auditRepo:      auditRepo,
+		permissions:    permissions,
+	}
+}
+
+// GetRole godoc
+// @Summary      Get role
+// @Description  Get a role with its granted permissions and number of assigned users
+// @Tags         Roles & Permissions
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path     string  true  "Role ID (UUID)"
+// @Success      200 {object} map[string]interface{} "Role retrieved successfully"
+// @Failure      400 {object} map[string]interface{} "Invalid role ID"
+// @Failure      404 {object} map[string]interface{} "Role not found"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /roles/{id} [get]
+func (s *roleService) GetRole(c *fiber.Ctx) error {
+	role, err := s.findRole(c)
+	if role == nil {
+		return err
+	}
+
+	permissions, err := s.roleRepo.FindPermissions(role.ID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to load role permissions")
+	}
+
+	userCount, err := s.roleRepo.CountUsers(role.ID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to count role users")
+	}
+
+	return utils.SuccessResponse(c, "Role retrieved successfully", fiber.Map{
+		"role":        role,
+		"permissions": permissions,
+		"user_count":  userCount,
+	})
+}
+
+// CreateRole godoc
+// @Summary      Create role
+// @Description  Create a new role, e.g. "Kaprodi". Grant permissions to it with POST /roles/{id}/permissions.
+// @Tags         Roles & Permissions
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body RoleRequest true "Role name and description"
+// @Success      200 {object} map[string]interface{} "Role created successfully"
+// @Failure      400 {object} map[string]interface{} "Invalid request body or validation failed"
+// @Failure      409 {object} map[string]interface{} "Role name already exists"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /roles [post]
+func (s *roleService) CreateRole(c *fiber.Ctx) error {
+	var req RoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.ValidationErrorResponse(c, err)
+	}
+
+	if existing, err := s.roleRepo.FindByName(req.Name); err == nil && existing.ID != uuid.Nil {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Role name already exists")
+	}
+
+	role := &models.Role{
+		ID:          uuid.New(),
+		Name:        req.Name,
+		Description: req.Description,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.roleRepo.Create(role); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create role")
+	}
+
+	RecordAudit(c, s.auditRepo, "role.create", "role", role.ID.String(), auditChanges(nil, fiber.Map{
+		"name":        role.Name,
+		"description": role.Description,
+	}))
+
+	role, _ = s.roleRepo.FindByID(role.ID)
+	return utils.SuccessResponse(c, "Role created successfully", role)
+}
+
+// UpdateRole godoc
+// @Summary      Update role
+// @Description  Rename a role or change its description. Built-in roles can only change their description.
+// @Tags         Roles & Permissions
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path     string       true  "Role ID (UUID)"
+// @Param        request  body     RoleRequest  true  "Role name and description"
+// @Success      200 {object} map[string]interface{} "Role updated successfully"
+// @Failure      400 {object} map[string]interface{} "Invalid request body or validation failed"
+// @Failure      403 {object} map[string]interface{} "Built-in roles cannot be renamed"
+// @Failure      404 {object} map[string]interface{} "Role not found"
+// @Failure      409 {object} map[string]interface{} "Role name already exists"
+// @Router       /roles/{id} [put]
+func (s *roleService) UpdateRole(c *fiber.Ctx) error {
+	role, err := s.findRole(c)
+	if role == nil {
+		return err
+	}
+
+	var req RoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.ValidationErrorResponse(c, err)
+	}
+
+	if req.Name != role.Name {
+		if role.IsBuiltIn {
+			return utils.ErrorResponse(c, fiber.StatusForbidden, "Built-in roles cannot be renamed")
+		}
+		if existing, err := s.roleRepo.FindByName(req.Name); err == nil && existing.ID != uuid.Nil {
+			return utils.ErrorResponse(c, fiber.StatusConflict, "Role name already exists")
+		}
+	}
+
+	before := fiber.Map{"name": role.Name, "description": role.Description}
+
+	role.Name = req.Name
+	role.Description = req.Description
+	if err := s.roleRepo.Update(role); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update role")
+	}
+	s.permissions.InvalidateRole(role.ID)
+
+	RecordAudit(c, s.auditRepo, "role.update", "role", role.ID.String(),
+		auditChanges(before, fiber.Map{"name": role.Name, "description": role.Description}))
+
+	role, _ = s.roleRepo.FindByID(role.ID)
+	return utils.SuccessResponse(c, "Role updated successfully", role)
+}
+
+// DeleteRole godoc
+// @Summary      Delete role
+// @Description  Delete a custom role and its permission grants. Built-in roles and roles still assigned to users cannot be deleted.
+// @Tags         Roles & Permissions
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path     string  true  "Role ID (UUID)"
+// @Success      200 {object} map[string]interface{} "Role deleted successfully"
+// @Failure      400 {object} map[string]interface{} "Invalid role ID"
+// @Failure      403 {object} map[string]interface{} "Built-in roles cannot be deleted"
+// @Failure      404 {object} map[string]interface{} "Role not found"
+// @Failure      409 {object} map[string]interface{} "Role is still assigned to users"
+// @Router       /roles/{id} [delete]
+func (s *roleService) DeleteRole(c *fiber.Ctx) error {
+	role, err := s.findRole(c)
+	if role == nil {
+		return err
+	}
+
+	if role.IsBuiltIn {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "Built-in roles cannot be deleted")
+	}
+
+	userCount, err := s.roleRepo.CountUsers(role.ID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to count role users")
+	}
+	if userCount > 0 {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Role is still assigned to users, reassign them first")
+	}
+
+	if err := s.roleRepo.Delete(role.ID); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete role")
+	}
+	s.permissions.InvalidateRole(role.ID)
+
+	RecordAudit(c, s.auditRepo, "role.delete", "role", role.ID.String(), auditChanges(fiber.Map{
+		"name":        role.Name,
+		"description": role.Description,
+	}, nil))
+
+	return utils.SuccessResponse(c, "Role deleted successfully", nil)
+}
+
+// GrantRolePermissions godoc
+// @Summary      Grant permissions to role
+// @Description  Grant one or more permissions, by name, to a role. Already granted permissions are ignored. Takes effect on the next request of every user with the role.
+// @Tags         Roles & Permissions
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path     string                   true  "Role ID (UUID)"
+// @Param        request  body     GrantPermissionsRequest  true  "Permission names"
+// @Success      200 {object} map[string]interface{} "Permissions granted"
+// @Failure      400 {object} map[string]interface{} "Invalid request or unknown permission"
+// @Failure      404 {object} map[string]interface{} "Role not found"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /roles/{id}/permissions [post]
+func (s *roleService) GrantRolePermissions(c *fiber.Ctx) error {
+	role, err := s.findRole(c)
+	if role == nil {
+		return err
+	}
+
+	var req GrantPermissionsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.ValidationErrorResponse(c, err)
+	}
+
+	names := uniqueStrings(req.Permissions)
+	permissionIDs := make([]uuid.UUID, 0, len(names))
+	for _, name := range names {
+		permission, err := s.permissionRepo.FindByName(name)
+		if err != nil || permission.ID == uuid.Nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Unknown permission: "+name)
+		}
+		permissionIDs = append(permissionIDs, permission.ID)
+	}
+
+	granted, err := s.roleRepo.GrantPermissions(role.ID, permissionIDs)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to grant permissions")
+	}
+
+	if granted > 0 {
+		s.permissions.InvalidateRole(role.ID)
+		RecordAudit(c, s.auditRepo, "role.permission_grant", "role", role.ID.String(), fiber.Map{
+			"role":        role.Name,
+			"permissions": names,
+		})
+	}
+
+	permissions, _ := s.roleRepo.FindPermissions(role.ID)
+	return utils.SuccessResponse(c, "Permissions granted", fiber.Map{
+		"granted":     granted,
+		"permissions": permissions,
+	})
+}
+
+// RevokeRolePermission godoc
+// @Summary      Revoke permission from role
+// @Description  Remove a permission from a role. Takes effect on the next request of every user with the role. The Admin role cannot lose role:manage.
+// @Tags         Roles & Permissions
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id            path     string  true  "Role ID (UUID)"
+// @Param        permissionId  path     string  true  "Permission ID (UUID)"
+// @Success      200 {object} map[string]interface{} "Permission revoked"
+// @Failure      400 {object} map[string]interface{} "Invalid ID"
+// @Failure      403 {object} map[string]interface{} "Permission cannot be revoked from this role"
+// @Failure      404 {object} map[string]interface{} "Role not found or permission not granted"
+// @Router       /roles/{id}/permissions/{permissionId} [delete]
+func (s *roleService) RevokeRolePermission(c *fiber.Ctx) error {
+	role, err := s.findRole(c)
+	if role == nil {
+		return err
+	}
+
+	permissionID, err := uuid.Parse(c.Params("permissionId"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid permission ID")
+	}
+
+	permission, err := s.permissionRepo.FindByID(permissionID)
+	if err != nil || permission.ID == uuid.Nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Permission not found")
+	}
+
+	if role.IsBuiltIn && role.Name == "Admin" && permission.Name == rbacManagePermission {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "The Admin role cannot lose "+rbacManagePermission)
+	}
+
+	revoked, err := s.roleRepo.RevokePermission(role.ID, permission.ID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to revoke permission")
+	}
+	if !revoked {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Role does not have this permission")
+	}
+	s.permissions.InvalidateRole(role.ID)
+
+	RecordAudit(c, s.auditRepo, "role.permission_revoke", "role", role.ID.String(), fiber.Map{
+		"role":       role.Name,
+		"permission": permission.Name,
+	})
+
+	return utils.SuccessResponse(c, "Permission revoked", nil)
+}
+
+// ListPermissions godoc
+// @Summary      List permissions
+// @Description  List all permissions
+// @Tags         Roles & Permissions
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200 {object} map[string]interface{} "Permissions retrieved successfully"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /permissions [get]
+func (s *roleService) ListPermissions(c *fiber.Ctx) error {
+	permissions, err := s.permissionRepo.FindAll()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to retrieve permissions")
+	}
+
+	return utils.SuccessResponse(c, "Permissions retrieved successfully", permissions)
+}
+
+// GetPermission godoc
+// @Summary      Get permission
+// @Description  Get a permission by ID
+// @Tags         Roles & Permissions
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path     string  true  "Permission ID (UUID)"
+// @Success      200 {object} map[string]interface{} "Permission retrieved successfully"
+// @Failure      400 {object} map[string]interface{} "Invalid permission ID"
+// @Failure      404 {object} map[string]interface{} "Permission not found"
+// @Router       /permissions/{id} [get]
+func (s *roleService) GetPermission(c *fiber.Ctx) error {
+	permission, err := s.findPermission(c)
+	if permission == nil {
+		return err
+	}
+
+	return utils.SuccessResponse(c, "Permission retrieved successfully", permission)
+}
+
+// CreatePermission godoc
+// @Summary      Create permission
+// @Description  Create a permission named "resource:action", e.g. "report:export"
+// @Tags         Roles & Permissions
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body PermissionRequest true "Permission name and description"
+// @Success      200 {object} map[string]interface{} "Permission created successfully"
+// @Failure      400 {object} map[string]interface{} "Invalid request body or permission name"
+// @Failure      409 {object} map[string]interface{} "Permission already exists"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /permissions [post]
+func (s *roleService) CreatePermission(c *fiber.Ctx) error {
+	var req PermissionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.ValidationErrorResponse(c, err)
+	}
+
+	if !permissionNamePattern.MatchString(req.Name) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Permission name must look like resource:action")
+	}
+
+	if existing, err := s.permissionRepo.FindByName(req.Name); err == nil && existing.ID != uuid.Nil {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Permission already exists")
+	}
+
+	permission := &models.Permission{
+		Name:        req.Name,
+		Description: req.Description,
+	}
+	if err := s.permissionRepo.Create(permission); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create permission")
+	}
+
+	RecordAudit(c, s.auditRepo, "permission.create", "permission", permission.ID.String(), auditChanges(nil, fiber.Map{
+		"name":        permission.Name,
+		"description": permission.Description,
+	}))
+
+	permission, _ = s.permissionRepo.FindByID(permission.ID)
+	return utils.SuccessResponse(c, "Permission created successfully", permission)
+}
+
+// UpdatePermission godoc
+// @Summary      Update permission
+// @Description  Rename a permission or change its description. Built-in permissions can only change their description.
+// @Tags         Roles & Permissions
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path     string             true  "Permission ID (UUID)"
+// @Param        request  body     PermissionRequest  true  "Permission name and description"
+// @Success      200 {object} map[string]interface{} "Permission updated successfully"
+// @Failure      400 {object} map[string]interface{} "Invalid request body or permission name"
+// @Failure      403 {object} map[string]interface{} "Built-in permissions cannot be renamed"
+// @Failure      404 {object} map[string]interface{} "Permission not found"
+// @Failure      409 {object} map[string]interface{} "Permission already exists"
+// @Router       /permissions/{id} [put]
+func (s *roleService) UpdatePermission(c *fiber.Ctx) error {
+	permission, err := s.findPermission(c)
+	if permission == nil {
+		return err
+	}
+
+	var req PermissionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.ValidationErrorResponse(c, err)
+	}
+
+	if req.Name != permission.Name {
+		if permission.IsBuiltIn {
+			return utils.ErrorResponse(c, fiber.StatusForbidden, "Built-in permissions cannot be renamed")
+		}
+		if !permissionNamePattern.MatchString(req.Name) {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Permission name must look like resource:action")
+		}
+		if existing, err := s.permissionRepo.FindByName(req.Name); err == nil && existing.ID != uuid.Nil {
+			return utils.ErrorResponse(c, fiber.StatusConflict, "Permission already exists")
+		}
+	}
+
+	before := fiber.Map{"name": permission.Name, "description": permission.Description}
+
+	permission.Name = req.Name
+	permission.Description = req.Description
+	if err := s.permissionRepo.Update(permission); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update permission")
+	}
+	s.permissions.InvalidateAll()
+
+	RecordAudit(c, s.auditRepo, "permission.update", "permission", permission.ID.String(),
+		auditChanges(before, fiber.Map{"name": permission.Name, "description": permission.Description}))
+
+	return utils.SuccessResponse(c, "Permission updated successfully", permission)
+}
+
+// DeletePermission godoc
+// @Summary      Delete permission
+// @Description  Delete a custom permission and remove it from every role and API key. Built-in permissions cannot be deleted.
+// @Tags         Roles & Permissions
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path     string  true  "Permission ID (UUID)"
+// @Success      200 {object} map[string]interface{} "Permission deleted successfully"
+// @Failure      400 {object} map[string]interface{} "Invalid permission ID"
+// @Failure      403 {object} map[string]interface{} "Built-in permissions cannot be deleted"
+// @Failure      404 {object} map[string]interface{} "Permission not found"
+// @Router       /permissions/{id} [delete]
+func (s *roleService) DeletePermission(c *fiber.Ctx) error {
+	permission, err := s.findPermission(c)
+	if permission == nil {
+		return err
+	}
+
+	if permission.IsBuiltIn {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "Built-in permissions cannot be deleted")
+	}
+
+	if err := s.permissionRepo.Delete(permission.ID); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete permission")
+	}
+	s.permissions.InvalidateAll()
+
+	RecordAudit(c, s.auditRepo, "permission.delete", "permission", permission.ID.String(), auditChanges(fiber.Map{
+		"name":        permission.Name,
+		"description": permission.Description,
+	}, nil))
+
+	return utils.SuccessResponse(c, "Permission deleted successfully", nil)
+}
+
+// findRole loads the role named by the :id route parameter.
+// On failure it writes the error response and returns a nil role.
+func (s *roleService) findRole(c *fiber.Ctx) (*models.Role, error) {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid role ID")
+	}
+
+	role, err := s.roleRepo.FindByID(id)
+	if err != nil || role.ID == uuid.Nil {
+		return nil, utils.ErrorResponse(c, fiber.StatusNotFound, "Role not found")
+	}
+	return role, nil
+}
+
+// findPermission loads the permission named by the :id route parameter.
+// On failure it writes the error response and returns a nil permission.
+func (s *roleService) findPermission(c *fiber.Ctx) (*models.Permission, error) {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid permission ID")
+	}
+
+	permission, err := s.permissionRepo.FindByID(id)
+	if err != nil || permission.ID == uuid.Nil {
+		return nil, utils.ErrorResponse(c, fiber.StatusNotFound, "Permission not found")
+	}
+	return permission, nil
+}
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"student-achievement-system/middleware"
+	"student-achievement-system/models"
+	"student-achievement-system/repository"
+	"student-achievement-system/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type SessionService interface {
+	ListMySessions(c *fiber.Ctx) error
+	RevokeMySession(c *fiber.Ctx) error
+	RevokeMyOtherSessions(c *fiber.Ctx) error
+	ListUserSessions(c *fiber.Ctx) error
+	RevokeUserSession(c *fiber.Ctx) error
+	RevokeUserSessions(c *fiber.Ctx) error
+}
+
+// SessionResponse describes a signed-in device
+type SessionResponse struct {
+	ID            uuid.UUID `json:"id"`
+	UserAgent     string    `json:"user_agent"`
+	IPAddress     string    `json:"ip_address"`
+	RotationCount int       `json:"rotation_count"`
+	CreatedAt     time.Time `json:"created_at"`
+	LastSeenAt    time.Time `json:"last_seen_at"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	Current       bool      `json:"current"`
+}
+
+type sessionService struct {
+	sessionRepo  repository.SessionRepository
+	userRepo     repository.UserRepository
+	auditRepo    repository.AuditRepository
+	tokenService TokenService
+}
+
+func NewSessionService(
+	sessionRepo repository.SessionRepository,
+	userRepo repository.UserRepository,
+	auditRepo repository.AuditRepository,
+	tokenService TokenService,
+) SessionService {
+	return &sessionService{
+		sessionRepo:  sessionRepo,
+		userRepo:     userRepo,
+		auditRepo:    auditRepo,
+		tokenService: tokenService,
+	}
+}
+
+// ListMySessions godoc
+// @Summary      List my sessions
+// @Description  List the active sessions (signed-in devices) of the authenticated user
+// @Tags         Authentication
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200 {object} map[string]interface{} "Sessions retrieved successfully"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      500 {object} map[string]interface{} "Failed to retrieve sessions"
+// @Router       /auth/sessions [get]
+func (s *sessionService) ListMySessions(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	sessions, err := s.sessionRepo.FindActiveByUserID(claims.UserID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to retrieve sessions")
+	}
+
+	return utils.SuccessResponse(c, "Sessions retrieved successfully", toSessionResponses(sessions, claims.SessionID))
+}
+
+// RevokeMySession godoc
+// @Summary      Sign out a session
+// @Description  Revoke one of the authenticated user's sessions, e.g. a lost or unrecognized device
+// @Tags         Authentication
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path     string  true  "Session ID (UUID)"
+// @Success      200 {object} map[string]interface{} "Session revoked successfully"
+// @Failure      400 {object} map[string]interface{} "Invalid session ID"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      404 {object} map[string]interface{} "Session not found"
+// @Failure      500 {object} map[string]interface{} "Failed to revoke session"
+// @Router       /auth/sessions/{id} [delete]
+func (s *sessionService) RevokeMySession(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	sessionID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid session ID")
+	}
+
+	if ok, err := s.revokeOwnedSession(c, claims.UserID, sessionID, "user_signed_out"); !ok {
+		return err
+	}
+
+	return utils.SuccessResponse(c, "Session revoked successfully", nil)
+}
+
+// RevokeMyOtherSessions godoc
+// @Summary      Sign out other sessions
+// @Description  Revoke every session of the authenticated user except the current one
+// @Tags         Authentication
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200 {object} map[string]interface{} "Other sessions revoked successfully"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      500 {object} map[string]interface{} "Failed to revoke sessions"
+// @Router       /auth/sessions [delete]
+func (s *sessionService) RevokeMyOtherSessions(c *fiber.Ctx) error {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	revoked, err := s.tokenService.RevokeOtherSessions(claims.UserID, claims.SessionID, "user_signed_out")
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to revoke sessions")
+	}
+
+	return utils.SuccessResponse(c, "Other sessions revoked successfully", fiber.Map{
+		"revoked": revoked,
+	})
+}
+
+// ListUserSessions godoc
+// @Summary      List user sessions
+// @Description  List the active sessions of a user (Admin only)
+// @Tags         User Management
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path     string  true  "User ID (UUID)"
+// @Success      200 {object} map[string]interface{} "Sessions retrieved successfully"
+// @Failure      400 {object} map[string]interface{} "Invalid user ID"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      404 {object} map[string]interface{} "User not found"
+// @Failure      500 {object} map[string]interface{} "Failed to retrieve sessions"
+// @Router       /users/{id}/sessions [get]
+func (s *sessionService) ListUserSessions(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil || user.ID == uuid.Nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "User not found")
+	}
+
+	sessions, err := s.sessionRepo.FindActiveByUserID(userID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to retrieve sessions")
+	}
+
+	currentSessionID := ""
+	if claims := middleware.GetUserFromContext(c); claims != nil {
+		currentSessionID = claims.SessionID
+	}
+
+	return utils.SuccessResponse(c, "Sessions retrieved successfully", toSessionResponses(sessions, currentSessionID))
+}
+
+// RevokeUserSession godoc
+// @Summary      Revoke user session
+// @Description  Revoke a single session of a user (Admin only)
+// @Tags         User Management
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id         path     string  true  "User ID (UUID)"
+// @Param        sessionId  path     string  true  "Session ID (UUID)"
+// @Success      200 {object} map[string]interface{} "Session revoked successfully"
+// @Failure      400 {object} map[string]interface{} "Invalid ID"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      404 {object} map[string]interface{} "Session not found"
+// @Failure      500 {object} map[string]interface{} "Failed to revoke session"
+// @Router       /users/{id}/sessions/{sessionId} [delete]
+func (s *sessionService) RevokeUserSession(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	sessionID, err := uuid.Parse(c.Params("sessionId"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid session ID")
+	}
+
+	if ok, err := s.revokeOwnedSession(c, userID, sessionID, "revoked_by_admin"); !ok {
+		return err
+	}
+
+	RecordAudit(c, s.auditRepo, "user.revoke_session", "user", userID.String(), fiber.Map{
+		"session_id": sessionID,
+	})
+
+	return utils.SuccessResponse(c, "Session revoked successfully", nil)
+}
+
+// RevokeUserSessions godoc
+// @Summary      Revoke all user sessions
+// @Description  Revoke every session and outstanding token of a user (Admin only)
+// @Tags         User Management
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path     string  true  "User ID (UUID)"
+// @Success      200 {object} map[string]interface{} "All sessions revoked successfully"
+// @Failure      400 {object} map[string]interface{} "Invalid user ID"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      404 {object} map[string]interface{} "User not found"
+// @Failure      500 {object} map[string]interface{} "Failed to revoke sessions"
+// @Router       /users/{id}/sessions [delete]
+func (s *sessionService) RevokeUserSessions(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil || user.ID == uuid.Nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "User not found")
+	}
+
+	if err := s.tokenService.RevokeUserTokens(userID); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to revoke sessions")
+	}
+
+	RecordAudit(c, s.auditRepo, "user.revoke_sessions", "user", userID.String(), nil)
+
+	return utils.SuccessResponse(c, "All sessions revoked successfully", nil)
+}
+
+// revokeOwnedSession revokes a session after checking that it belongs to userID.
+// Sessions of other users are reported as not found so their IDs are not disclosed.
+// When it returns false the error response has already been written.
+func (s *sessionService) revokeOwnedSession(c *fiber.Ctx, userID, sessionID uuid.UUID, reason string) (bool, error) {
+	session, err := s.sessionRepo.FindByID(sessionID)
+	if err != nil || session.UserID != userID || session.RevokedAt != nil {
+		return false, utils.ErrorResponse(c, fiber.StatusNotFound, "Session not found")
+	}
+
+	if err := s.tokenService.RevokeSession(session.ID, session.UserID, reason); err != nil {
+		return false, utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to revoke session")
+	}
+
+	return true, nil
+}
+
+func toSessionResponses(sessions []models.Session, currentSessionID string) []SessionResponse {
+	responses := make([]SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		responses = append(responses, SessionResponse{
+			ID:            session.ID,
+			UserAgent:     session.UserAgent,
+			IPAddress:     session.IPAddress,
+			RotationCount: session.RotationCount,
+			CreatedAt:     session.CreatedAt,
+			LastSeenAt:    session.LastSeenAt,
+			ExpiresAt:     session.ExpiresAt,
+			Current:       session.ID.String() == currentSessionID,
+		})
+	}
+	return responses
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"student-achievement-system/authz"
+	"student-achievement-system/models"
+	"student-achievement-system/repository"
+	"student-achievement-system/sla"
+	"student-achievement-system/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// SLAMonitor reminds approvers of submissions waiting past the verification SLA and
+// escalates those waiting past the escalation threshold to the admins
+type SLAMonitor interface {
+	Start(interval time.Duration)
+	CheckOverdue() (reminded, escalated int, err error)
+}
+
+type slaMonitor struct {
+	achievementRefRepo repository.AchievementReferenceRepository
+	achievementRepo    repository.AchievementRepository
+	studentRepo        repository.StudentRepository
+	lecturerRepo       repository.LecturerRepository
+	delegationRepo     repository.AdvisorDelegationRepository
+	userRepo           repository.UserRepository
+	roleRepo           repository.RoleRepository
+	notificationRepo   repository.NotificationRepository
+	policy             sla.Policy
+	clock              sla.Clock
+}
+
+func NewSLAMonitor(
+	achievementRefRepo repository.AchievementReferenceRepository,
+	achievementRepo repository.AchievementRepository,
+	studentRepo repository.StudentRepository,
+	lecturerRepo repository.LecturerRepository,
+	delegationRepo repository.AdvisorDelegationRepository,
+	userRepo repository.UserRepository,
+	roleRepo repository.RoleRepository,
+	notificationRepo repository.NotificationRepository,
+	policy sla.Policy,
+	clock sla.Clock,
+) SLAMonitor {
+	return &slaMonitor{
+		achievementRefRepo: achievementRefRepo,
+		achievementRepo:    achievementRepo,
+		studentRepo:        studentRepo,
+		lecturerRepo:       lecturerRepo,
+		delegationRepo:     delegationRepo,
+		userRepo:           userRepo,
+		roleRepo:           roleRepo,
+		notificationRepo:   notificationRepo,
+		policy:             policy,
+		clock:              clock,
+	}
+}
+
+// Start checks for overdue submissions in the background
+func (m *slaMonitor) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			reminded, escalated, err := m.CheckOverdue()
+			if err != nil {
+				utils.GlobalLogger.Error("Failed to check overdue submissions", err)
+				continue
+			}
+			if reminded > 0 || escalated > 0 {
+				utils.GlobalLogger.Info("Followed up overdue submissions", map[string]interface{}{
+					"reminded":  reminded,
+					"escalated": escalated,
+				})
+			}
+		}
+	}()
+}
+
+// CheckOverdue reminds and escalates the submissions that are due for it. Each submission
+// is reminded and escalated at most once, even with several instances checking at once.
+func (m *slaMonitor) CheckOverdue() (reminded, escalated int, err error) {
+	if !m.policy.Enabled() {
+		return 0, 0, nil
+	}
+
+	now := m.clock.Now()
+	refs, err := m.achievementRefRepo.FindOverdue(m.policy.OverdueBefore(now))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for i := range refs {
+		ref := &refs[i]
+		if ref.SubmittedAt == nil {
+			continue
+		}
+		due := m.policy.Due(sla.Submission{
+			SubmittedAt: *ref.SubmittedAt,
+			RemindedAt:  ref.SLARemindedAt,
+			EscalatedAt: ref.SLAEscalatedAt,
+		}, now)
+		if !due.Remind && !due.Escalate {
+			continue
+		}
+
+		student, err := m.studentRepo.FindByID(ref.StudentID)
+		if err != nil || student.ID == uuid.Nil {
+			utils.GlobalLogger.Error("Failed to load student of overdue submission", err, map[string]interface{}{
+				"achievement_id": ref.MongoAchievementID,
+			})
+			continue
+		}
+		title := ref.MongoAchievementID
+		if achievement, err := m.achievementRepo.FindByID(context.Background(), ref.MongoAchievementID); err == nil {
+			title = achievement.Title
+		}
+
+		if due.Remind && m.mark(m.achievementRefRepo.MarkReminded, ref, now) {
+			m.remind(ref, student, title)
+			reminded++
+		}
+		if due.Escalate && m.mark(m.achievementRefRepo.MarkEscalated, ref, now) {
+			m.escalate(ref, student, title, now)
+			escalated++
+		}
+	}
+	return reminded, escalated, nil
+}
+
+// mark records a reminder or escalation of ref, reporting whether this check is the one
+// to send it
+func (m *slaMonitor) mark(record func(refID uuid.UUID, at time.Time) (bool, error), ref *models.AchievementReference, now time.Time) bool {
+	won, err := record(ref.ID, now)
+	if err != nil {
+		utils.GlobalLogger.Error("Failed to record follow-up of overdue submission", err, map[string]interface{}{
+			"achievement_id": ref.MongoAchievementID,
+		})
+		return false
+	}
+	return won
+}
+
+// remind tells whoever decides the stage the submission waits in that it is overdue
+func (m *slaMonitor) remind(ref *models.AchievementReference, student *models.Student, title string) {
+	for _, userID := range stageApprovers(ref.CurrentStage, student, m.userRepo, m.lecturerRepo, m.delegationRepo) {
+		CreateNotification(
+			m.notificationRepo,
+			userID,
+			models.NotificationTypeVerificationOverdue,
+			"Verification Overdue",
+			fmt.Sprintf("'%s' by %s has been waiting for your verification since %s",
+				title, student.User.FullName, ref.SubmittedAt.Format("2006-01-02 15:04")),
+			fiber.Map{
+				"achievement_id": ref.MongoAchievementID,
+				"student_id":     student.ID,
+				"submitted_at":   ref.SubmittedAt,
+			},
+		)
+	}
+}
+
+// escalate alerts the admins of the student's department, or every admin when none
+// belongs to it, that the submission is still not verified
+func (m *slaMonitor) escalate(ref *models.AchievementReference, student *models.Student, title string, now time.Time) {
+	waited := now.Sub(*ref.SubmittedAt).Round(time.Hour)
+	for _, userID := range m.departmentAdmins(student) {
+		CreateNotification(
+			m.notificationRepo,
+			userID,
+			models.NotificationTypeVerificationEscalated,
+			"Verification Escalated",
+			fmt.Sprintf("'%s' by %s has been waiting for verification for %s without a decision",
+				title, student.User.FullName, waited),
+			fiber.Map{
+				"achievement_id": ref.MongoAchievementID,
+				"student_id":     student.ID,
+				"advisor_id":     student.AdvisorID,
+				"submitted_at":   ref.SubmittedAt,
+			},
+		)
+	}
+}
+
+// departmentAdmins returns the admins whose lecturer profile is in the department the
+// student studies in, the department authorization goes by, falling back to every admin
+func (m *slaMonitor) departmentAdmins(student *models.Student) []uuid.UUID {
+	role, err := m.roleRepo.FindByName(authz.RoleAdmin)
+	if err != nil || role.ID == uuid.Nil {
+		utils.GlobalLogger.Error("Failed to load the admin role", err)
+		return nil
+	}
+	admins, err := m.userRepo.FindByRoleID(role.ID)
+	if err != nil {
+		utils.GlobalLogger.Error("Failed to load admins", err)
+		return nil
+	}
+
+	resource := authz.Resource{OwnerID: student.ID, Department: student.ProgramStudy}
+	all := make([]uuid.UUID, 0, len(admins))
+	inDepartment := make([]uuid.UUID, 0)
+	for _, admin := range admins {
+		all = append(all, admin.ID)
+		lecturer, err := m.lecturerRepo.FindByUserID(admin.ID)
+		if err != nil || lecturer.ID == uuid.Nil {
+			continue
+		}
+		if authz.SameDepartment(authz.Subject{LecturerID: lecturer.ID, Department: lecturer.Department}, resource) {
+			inDepartment = append(inDepartment, admin.ID)
+		}
+	}
+	if len(inDepartment) > 0 {
+		return inDepartment
+	}
+	return all
+}
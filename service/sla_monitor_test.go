@@ -0,0 +1,313 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"student-achievement-system/models"
+	"student-achievement-system/repository"
+	"student-achievement-system/sla"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The fakes embed the repository interfaces and implement only what the monitor uses;
+// anything else panics
+
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+type fakeRefRepo struct {
+	repository.AchievementReferenceRepository
+	refs []models.AchievementReference
+}
+
+func (r *fakeRefRepo) FindOverdue(submittedBefore time.Time) ([]models.AchievementReference, error) {
+	overdue := []models.AchievementReference{}
+	for _, ref := range r.refs {
+		if ref.SubmittedAt.Before(submittedBefore) && (ref.SLARemindedAt == nil || ref.SLAEscalatedAt == nil) {
+			overdue = append(overdue, ref)
+		}
+	}
+	return overdue, nil
+}
+
+func (r *fakeRefRepo) MarkReminded(refID uuid.UUID, at time.Time) (bool, error) {
+	return r.mark(refID, func(ref *models.AchievementReference) **time.Time { return &ref.SLARemindedAt }, at)
+}
+
+func (r *fakeRefRepo) MarkEscalated(refID uuid.UUID, at time.Time) (bool, error) {
+	return r.mark(refID, func(ref *models.AchievementReference) **time.Time { return &ref.SLAEscalatedAt }, at)
+}
+
+func (r *fakeRefRepo) mark(refID uuid.UUID, field func(*models.AchievementReference) **time.Time, at time.Time) (bool, error) {
+	for i := range r.refs {
+		if r.refs[i].ID != refID {
+			continue
+		}
+		marked := field(&r.refs[i])
+		if *marked != nil {
+			return false, nil
+		}
+		*marked = &at
+		return true, nil
+	}
+	return false, nil
+}
+
+type fakeAchievementRepo struct {
+	repository.AchievementRepository
+}
+
+func (fakeAchievementRepo) FindByID(_ context.Context, id string) (*models.Achievement, error) {
+	return &models.Achievement{Title: "Achievement " + id}, nil
+}
+
+type fakeStudentRepo struct {
+	repository.StudentRepository
+	students map[uuid.UUID]*models.Student
+}
+
+func (r *fakeStudentRepo) FindByID(id uuid.UUID) (*models.Student, error) {
+	if student, ok := r.students[id]; ok {
+		return student, nil
+	}
+	return &models.Student{}, nil
+}
+
+type fakeLecturerRepo struct {
+	repository.LecturerRepository
+	lecturers []models.Lecturer
+}
+
+func (r *fakeLecturerRepo) FindByID(id uuid.UUID) (*models.Lecturer, error) {
+	for i := range r.lecturers {
+		if r.lecturers[i].ID == id {
+			return &r.lecturers[i], nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *fakeLecturerRepo) FindByUserID(userID uuid.UUID) (*models.Lecturer, error) {
+	for i := range r.lecturers {
+		if r.lecturers[i].UserID == userID {
+			return &r.lecturers[i], nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+type fakeDelegationRepo struct {
+	repository.AdvisorDelegationRepository
+}
+
+func (fakeDelegationRepo) FindActiveByDelegator(uuid.UUID, time.Time) ([]models.AdvisorDelegation, error) {
+	return nil, nil
+}
+
+type fakeUserRepo struct {
+	repository.UserRepository
+	byRole map[uuid.UUID][]models.User
+}
+
+func (r *fakeUserRepo) FindByRoleID(roleID uuid.UUID) ([]models.User, error) {
+	return r.byRole[roleID], nil
+}
+
+type fakeRoleRepo struct {
+	repository.RoleRepository
+	roles []models.Role
+}
+
+func (r *fakeRoleRepo) FindByName(name string) (*models.Role, error) {
+	for i := range r.roles {
+		if r.roles[i].Name == name {
+			return &r.roles[i], nil
+		}
+	}
+	return &models.Role{}, nil
+}
+
+type fakeNotificationRepo struct {
+	repository.NotificationRepository
+	sent []models.Notification
+}
+
+func (r *fakeNotificationRepo) Create(notification *models.Notification) error {
+	r.sent = append(r.sent, *notification)
+	return nil
+}
+
+// take returns the notifications sent since the last call
+func (r *fakeNotificationRepo) take() []models.Notification {
+	sent := r.sent
+	r.sent = nil
+	return sent
+}
+
+// slaFixture is a student in Informatika whose advisor belongs to another department,
+// with an admin in each of the two
+type slaFixture struct {
+	clock         *fakeClock
+	refs          *fakeRefRepo
+	notifications *fakeNotificationRepo
+	monitor       SLAMonitor
+	student       *models.Student
+	advisorUser   uuid.UUID
+	studentsAdmin uuid.UUID
+	otherAdmin    uuid.UUID
+}
+
+func newSLAFixture(t *testing.T) *slaFixture {
+	t.Helper()
+	f := &slaFixture{
+		clock:         &fakeClock{now: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)},
+		refs:          &fakeRefRepo{},
+		notifications: &fakeNotificationRepo{},
+		advisorUser:   uuid.New(),
+		studentsAdmin: uuid.New(),
+		otherAdmin:    uuid.New(),
+	}
+
+	advisor := models.Lecturer{ID: uuid.New(), UserID: f.advisorUser, Department: "Sistem Informasi"}
+	lecturers := &fakeLecturerRepo{lecturers: []models.Lecturer{
+		advisor,
+		{ID: uuid.New(), UserID: f.studentsAdmin, Department: " informatika "},
+		{ID: uuid.New(), UserID: f.otherAdmin, Department: "Sistem Informasi"},
+	}}
+	f.student = &models.Student{
+		ID:           uuid.New(),
+		UserID:       uuid.New(),
+		ProgramStudy: "Informatika",
+		AdvisorID:    &advisor.ID,
+		User:         models.User{FullName: "Student"},
+	}
+
+	adminRole := models.Role{ID: uuid.New(), Name: "Admin"}
+	users := &fakeUserRepo{byRole: map[uuid.UUID][]models.User{
+		adminRole.ID: {{ID: f.studentsAdmin}, {ID: f.otherAdmin}},
+	}}
+
+	f.monitor = NewSLAMonitor(
+		f.refs,
+		fakeAchievementRepo{},
+		&fakeStudentRepo{students: map[uuid.UUID]*models.Student{f.student.ID: f.student}},
+		lecturers,
+		fakeDelegationRepo{},
+		users,
+		&fakeRoleRepo{roles: []models.Role{adminRole}},
+		f.notifications,
+		sla.Policy{RemindAfter: 48 * time.Hour, EscalateAfter: 96 * time.Hour},
+		f.clock,
+	)
+	return f
+}
+
+// submit adds a submission made ago before the current time
+func (f *slaFixture) submit(ago time.Duration) {
+	submittedAt := f.clock.now.Add(-ago)
+	f.refs.refs = append(f.refs.refs, models.AchievementReference{
+		ID:                 uuid.New(),
+		StudentID:          f.student.ID,
+		MongoAchievementID: "achievement-" + submittedAt.Format(time.RFC3339),
+		Status:             models.StatusSubmitted,
+		SubmittedAt:        &submittedAt,
+	})
+}
+
+func (f *slaFixture) check(t *testing.T, wantReminded, wantEscalated int) []models.Notification {
+	t.Helper()
+	reminded, escalated, err := f.monitor.CheckOverdue()
+	if err != nil {
+		t.Fatalf("CheckOverdue() error = %v", err)
+	}
+	if reminded != wantReminded || escalated != wantEscalated {
+		t.Fatalf("CheckOverdue() = %d reminded, %d escalated, want %d and %d", reminded, escalated, wantReminded, wantEscalated)
+	}
+	return f.notifications.take()
+}
+
+func recipients(notifications []models.Notification, notifType models.NotificationType) []uuid.UUID {
+	users := []uuid.UUID{}
+	for _, notification := range notifications {
+		if notification.Type == notifType {
+			users = append(users, notification.UserID)
+		}
+	}
+	return users
+}
+
+func TestCheckOverdueRemindsOnce(t *testing.T) {
+	f := newSLAFixture(t)
+	f.submit(24 * time.Hour)
+
+	// Within the SLA nothing happens
+	f.check(t, 0, 0)
+
+	f.clock.now = f.clock.now.Add(25 * time.Hour)
+	sent := f.check(t, 1, 0)
+	if got := recipients(sent, models.NotificationTypeVerificationOverdue); len(got) != 1 || got[0] != f.advisorUser {
+		t.Errorf("reminded %v, want the advisor %v", got, f.advisorUser)
+	}
+	if len(sent) != 1 {
+		t.Errorf("sent %d notifications, want only the reminder", len(sent))
+	}
+
+	f.clock.now = f.clock.now.Add(time.Hour)
+	if sent := f.check(t, 0, 0); len(sent) != 0 {
+		t.Errorf("sent %d notifications on the next check, want none", len(sent))
+	}
+}
+
+func TestCheckOverdueEscalatesOnce(t *testing.T) {
+	f := newSLAFixture(t)
+	f.submit(50 * time.Hour)
+	f.check(t, 1, 0)
+
+	f.clock.now = f.clock.now.Add(50 * time.Hour)
+	sent := f.check(t, 0, 1)
+	// The admin of the student's program is alerted, not the one of the advisor's department
+	if got := recipients(sent, models.NotificationTypeVerificationEscalated); len(got) != 1 || got[0] != f.studentsAdmin {
+		t.Errorf("escalated to %v, want the admin of the student's department %v", got, f.studentsAdmin)
+	}
+	if got := recipients(sent, models.NotificationTypeVerificationOverdue); len(got) != 0 {
+		t.Errorf("reminded %v again, want no second reminder", got)
+	}
+
+	f.clock.now = f.clock.now.Add(24 * time.Hour)
+	if sent := f.check(t, 0, 0); len(sent) != 0 {
+		t.Errorf("sent %d notifications on the next check, want none", len(sent))
+	}
+}
+
+func TestCheckOverdueSkippedBothThresholds(t *testing.T) {
+	// The monitor was down while the submission passed both thresholds
+	f := newSLAFixture(t)
+	f.submit(120 * time.Hour)
+
+	sent := f.check(t, 1, 1)
+	if got := recipients(sent, models.NotificationTypeVerificationOverdue); len(got) != 1 || got[0] != f.advisorUser {
+		t.Errorf("reminded %v, want the advisor %v", got, f.advisorUser)
+	}
+	if got := recipients(sent, models.NotificationTypeVerificationEscalated); len(got) != 1 || got[0] != f.studentsAdmin {
+		t.Errorf("escalated to %v, want %v", got, f.studentsAdmin)
+	}
+
+	f.clock.now = f.clock.now.Add(time.Hour)
+	f.check(t, 0, 0)
+}
+
+func TestCheckOverdueEscalatesToEveryAdminOutsideDepartments(t *testing.T) {
+	f := newSLAFixture(t)
+	f.student.ProgramStudy = "Teknik Sipil"
+	f.submit(100 * time.Hour)
+
+	sent := f.check(t, 1, 1)
+	got := recipients(sent, models.NotificationTypeVerificationEscalated)
+	if len(got) != 2 {
+		t.Errorf("escalated to %v, want both admins", got)
+	}
+}
@@ -1,7 +1,9 @@
 package service
 
 import (
+	"student-achievement-system/authz"
 	"student-achievement-system/middleware"
+	"student-achievement-system/models"
 	"student-achievement-system/repository"
 	"student-achievement-system/utils"
 
@@ -29,7 +31,9 @@ type AssignAdvisorRequest struct {
 type studentService struct {
 	studentRepo        repository.StudentRepository
 	lecturerRepo       repository.LecturerRepository
+	delegationRepo     repository.AdvisorDelegationRepository
 	achievementRefRepo repository.AchievementReferenceRepository
+	auditRepo          repository.AuditRepository
 }
 
 type lecturerService struct {
@@ -40,12 +44,16 @@ type lecturerService struct {
 func NewStudentService(
 	studentRepo repository.StudentRepository,
 	lecturerRepo repository.LecturerRepository,
+	delegationRepo repository.AdvisorDelegationRepository,
 	achievementRefRepo repository.AchievementReferenceRepository,
+	auditRepo repository.AuditRepository,
 ) StudentService {
 	return &studentService{
 		studentRepo:        studentRepo,
 		lecturerRepo:       lecturerRepo,
+		delegationRepo:     delegationRepo,
 		achievementRefRepo: achievementRefRepo,
+		auditRepo:          auditRepo,
 	}
 }
 
@@ -99,17 +107,13 @@ func (s *studentService) ListStudents(c *fiber.Ctx) error {
 // @Success      200 {object} map[string]interface{} "Student details"
 // @Failure      400 {object} map[string]interface{} "Invalid student ID"
 // @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
 // @Failure      404 {object} map[string]interface{} "Student not found"
 // @Router       /students/{id} [get]
 func (s *studentService) GetStudent(c *fiber.Ctx) error {
-	id, err := uuid.Parse(c.Params("id"))
-	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid student ID")
-	}
-
-	student, err := s.studentRepo.FindByUserID(id)
-	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusNotFound, "Student not found")
+	student, err := s.authorizeStudent(c, authz.ActionRead)
+	if student == nil {
+		return err
 	}
 
 	return utils.SuccessResponse(c, "Student retrieved successfully", student)
@@ -126,17 +130,13 @@ func (s *studentService) GetStudent(c *fiber.Ctx) error {
 // @Success      200 {object} map[string]interface{} "Student achievements"
 // @Failure      400 {object} map[string]interface{} "Invalid student ID"
 // @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
 // @Failure      404 {object} map[string]interface{} "Student not found"
 // @Router       /students/{id}/achievements [get]
 func (s *studentService) GetStudentAchievements(c *fiber.Ctx) error {
-	id, err := uuid.Parse(c.Params("id"))
-	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid student ID")
-	}
-
-	student, err := s.studentRepo.FindByUserID(id)
-	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusNotFound, "Student not found")
+	student, err := s.authorizeStudent(c, authz.ActionRead)
+	if student == nil {
+		return err
 	}
 
 	// Get achievements from MongoDB would be done via achievement repository
@@ -164,9 +164,9 @@ func (s *studentService) GetStudentAchievements(c *fiber.Ctx) error {
 // @Failure      500 {object} map[string]interface{} "Internal server error"
 // @Router       /students/{id}/assign-advisor [post]
 func (s *studentService) AssignAdvisor(c *fiber.Ctx) error {
-	id, err := uuid.Parse(c.Params("id"))
-	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid student ID")
+	student, err := s.authorizeStudent(c, authz.ActionManage)
+	if student == nil {
+		return err
 	}
 
 	var req AssignAdvisorRequest
@@ -180,25 +180,52 @@ func (s *studentService) AssignAdvisor(c *fiber.Ctx) error {
 	}
 
 	// Verify lecturer exists using lecturer ID (not user_id)
-	_, err = s.lecturerRepo.FindByID(advisorID)
-	if err != nil {
+	advisor, err := s.lecturerRepo.FindByID(advisorID)
+	if err != nil || advisor.ID == uuid.Nil {
 		return utils.ErrorResponse(c, fiber.StatusNotFound, "Advisor not found")
 	}
 
-	student, err := s.studentRepo.FindByUserID(id)
-	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusNotFound, "Student not found")
-	}
+	before := fiber.Map{"advisor_id": student.AdvisorID}
 
 	student.AdvisorID = &advisorID
 	if err := s.studentRepo.Update(student); err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to assign advisor")
 	}
 
-	student, _ = s.studentRepo.FindByUserID(id)
+	RecordAudit(c, s.auditRepo, "student.assign_advisor", "student", student.ID.String(),
+		auditChanges(before, fiber.Map{"advisor_id": advisorID}))
+
+	student, _ = s.studentRepo.FindByUserID(student.UserID)
 	return utils.SuccessResponse(c, "Advisor assigned successfully", student)
 }
 
+// authorizeStudent loads the student named by the id route parameter (their user ID) and
+// checks that the caller may perform action on them. When it returns nil the error
+// response has already been written.
+func (s *studentService) authorizeStudent(c *fiber.Ctx, action authz.Action) (*models.Student, error) {
+	claims := middleware.GetUserFromContext(c)
+	if claims == nil {
+		return nil, utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not authenticated")
+	}
+
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid student ID")
+	}
+
+	student, err := s.studentRepo.FindByUserID(id)
+	if err != nil || student.ID == uuid.Nil {
+		return nil, utils.ErrorResponse(c, fiber.StatusNotFound, "Student not found")
+	}
+
+	subject := authzSubject(claims, s.studentRepo, s.lecturerRepo, s.delegationRepo)
+	if !authz.Can(subject, action, ownedBy(authz.KindStudent, student)) {
+		return nil, forbidden(c, action, authz.KindStudent)
+	}
+
+	return student, nil
+}
+
 // Lecturer Service Methods
 // ListLecturers godoc
 // @Summary      List all lecturers
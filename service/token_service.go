@@ -0,0 +1,389 @@
+package service
+
+import (
+	"student-achievement-system/config"
+	"student-achievement-system/repository"
+	"student-achievement-system/utils"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// TokenService issues and validates tokens and manages server-side revocation
+type TokenService interface {
+	GenerateAccessToken(claims *utils.JWTClaims) (string, error)
+	GenerateRefreshToken(claims *utils.JWTClaims) (string, error)
+	GenerateScopedToken(claims *utils.JWTClaims, scope string, expiresIn time.Duration) (string, error)
+	GenerateImpersonationToken(claims *utils.JWTClaims) (string, time.Time, error)
+	VerifyAccessToken(tokenString string, allowedScopes ...string) (*utils.JWTClaims, error)
+	VerifyRefreshToken(tokenString string) (*utils.JWTClaims, error)
+	VerifyAPIKey(key string, ipAddress string) (*utils.JWTClaims, error)
+	TouchSession(claims *utils.JWTClaims, ipAddress, userAgent string)
+	RevokeSession(sessionID uuid.UUID, userID uuid.UUID, reason string) error
+	RevokeOtherSessions(userID uuid.UUID, keepSessionID string, reason string) (int, error)
+	RevokeUserTokens(userID uuid.UUID) error
+	RevokeToken(claims *utils.JWTClaims) error
+	StartCleanup(interval time.Duration)
+	JWKS(c *fiber.Ctx) error
+}
+
+// sessionTouchInterval limits how often last-seen is written for a session
+const sessionTouchInterval = time.Minute
+
+// impersonatePermission lets an admin obtain a token acting as another user.
+// The admin must still hold it for the token to remain valid.
+const impersonatePermission = "user:impersonate"
+
+type tokenService struct {
+	revokedTokenRepo repository.RevokedTokenRepository
+	sessionRepo      repository.SessionRepository
+	apiKeyRepo       repository.APIKeyRepository
+	permissions      *PermissionCache
+	keys             *utils.KeySet
+	cfg              *config.Config
+
+	touchMu   sync.Mutex
+	lastTouch map[uuid.UUID]time.Time
+}
+
+// NewTokenService creates the token service. When keys is nil, access tokens
+// are signed with HS256 and cfg.JWTSecret.
+func NewTokenService(
+	revokedTokenRepo repository.RevokedTokenRepository,
+	sessionRepo repository.SessionRepository,
+	apiKeyRepo repository.APIKeyRepository,
+	permissions *PermissionCache,
+	keys *utils.KeySet,
+	cfg *config.Config,
+) TokenService {
+	return &tokenService{
+		revokedTokenRepo: revokedTokenRepo,
+		sessionRepo:      sessionRepo,
+		apiKeyRepo:       apiKeyRepo,
+		permissions:      permissions,
+		keys:             keys,
+		cfg:              cfg,
+		lastTouch:        make(map[uuid.UUID]time.Time),
+	}
+}
+
+// GenerateAccessToken signs a short-lived access token
+func (s *tokenService) GenerateAccessToken(claims *utils.JWTClaims) (string, error) {
+	return s.signAccess(claims, s.cfg.JWTExpiresIn)
+}
+
+// GenerateRefreshToken signs a refresh token.
+// Refresh tokens are only ever read by this service, so they stay HMAC-signed.
+func (s *tokenService) GenerateRefreshToken(claims *utils.JWTClaims) (string, error) {
+	return utils.SignToken(claims, s.cfg.JWTRefreshSecret, s.cfg.JWTRefreshExpiresIn)
+}
+
+// GenerateScopedToken signs a short-lived token that is only accepted by endpoints allowing its scope
+func (s *tokenService) GenerateScopedToken(claims *utils.JWTClaims, scope string, expiresIn time.Duration) (string, error) {
+	claims.Scope = scope
+	return s.signAccess(claims, expiresIn)
+}
+
+// GenerateImpersonationToken signs a short-lived access token for claims carrying an Actor.
+// It belongs to no session, so it cannot be refreshed, and returns its expiry.
+func (s *tokenService) GenerateImpersonationToken(claims *utils.JWTClaims) (string, time.Time, error) {
+	token, err := s.signAccess(claims, s.cfg.ImpersonationTTL)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, claims.ExpiresAt.Time, nil
+}
+
+// VerifyAccessToken validates the signature of an access token and checks it against the revocation store.
+// Scoped tokens are rejected unless their scope is listed in allowedScopes.
+// The returned claims carry the user's current role and permissions.
+func (s *tokenService) VerifyAccessToken(tokenString string, allowedScopes ...string) (*utils.JWTClaims, error) {
+	var claims *utils.JWTClaims
+	var err error
+	if s.keys != nil {
+		claims, err = s.keys.Validate(tokenString)
+	} else {
+		claims, err = utils.ValidateToken(tokenString, s.cfg.JWTSecret)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !scopeAllowed(claims.Scope, allowedScopes) {
+		return nil, utils.ErrTokenScope
+	}
+	if err := s.checkRevoked(claims); err != nil {
+		return nil, err
+	}
+	if err := s.permissions.Resolve(claims); err != nil {
+		return nil, err
+	}
+	if claims.Impersonating() {
+		if err := s.checkActor(claims); err != nil {
+			return nil, err
+		}
+	}
+	return claims, nil
+}
+
+// checkActor ends an impersonation as soon as the admin behind it logs out, is deactivated
+// or loses the permission to impersonate
+func (s *tokenService) checkActor(claims *utils.JWTClaims) error {
+	actor := &utils.JWTClaims{UserID: claims.Actor.UserID}
+	actor.IssuedAt = claims.IssuedAt
+	actor.IssuedAtMillis = claims.IssuedAtMillis
+	if err := s.checkRevoked(actor); err != nil {
+		return err
+	}
+	if err := s.permissions.Resolve(actor); err != nil {
+		return utils.ErrTokenRevoked
+	}
+	if !hasPermission(actor.Permissions, impersonatePermission) {
+		return utils.ErrTokenRevoked
+	}
+	return nil
+}
+
+// VerifyRefreshToken validates the signature of a refresh token and checks it against the revocation store
+func (s *tokenService) VerifyRefreshToken(tokenString string) (*utils.JWTClaims, error) {
+	claims, err := utils.ValidateToken(tokenString, s.cfg.JWTRefreshSecret)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkRevoked(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// VerifyAPIKey authenticates an API key and returns claims carrying exactly the key's permissions.
+// The claims have no user or role, so role-based shortcuts in handlers never apply to keys.
+func (s *tokenService) VerifyAPIKey(key string, ipAddress string) (*utils.JWTClaims, error) {
+	apiKey, err := s.apiKeyRepo.FindByHash(utils.HashToken(key))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if apiKey.RevokedAt != nil {
+		return nil, utils.ErrTokenRevoked
+	}
+	if !now.Before(apiKey.ExpiresAt) {
+		return nil, jwt.ErrTokenExpired
+	}
+
+	s.touchMu.Lock()
+	last, seen := s.lastTouch[apiKey.ID]
+	touch := !seen || now.Sub(last) >= sessionTouchInterval
+	if touch {
+		s.lastTouch[apiKey.ID] = now
+	}
+	s.touchMu.Unlock()
+
+	if touch {
+		if err := s.apiKeyRepo.TouchLastUsed(apiKey.ID, ipAddress, now); err != nil {
+			utils.GlobalLogger.Error("Failed to update API key last-used", err, map[string]interface{}{
+				"api_key_id": apiKey.ID,
+			})
+		}
+	}
+
+	return &utils.JWTClaims{
+		Username:    "api-key:" + apiKey.Name,
+		Permissions: apiKey.Scopes,
+		APIKeyID:    apiKey.ID.String(),
+	}, nil
+}
+
+// TouchSession records the session's last-seen time, IP and user agent.
+// Writes are throttled so that busy clients do not update the row on every request.
+func (s *tokenService) TouchSession(claims *utils.JWTClaims, ipAddress, userAgent string) {
+	sessionID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	s.touchMu.Lock()
+	if last, ok := s.lastTouch[sessionID]; ok && now.Sub(last) < sessionTouchInterval {
+		s.touchMu.Unlock()
+		return
+	}
+	s.lastTouch[sessionID] = now
+	s.touchMu.Unlock()
+
+	if err := s.sessionRepo.Touch(sessionID, ipAddress, truncate(userAgent, 255), now); err != nil {
+		utils.GlobalLogger.Error("Failed to update session last-seen", err, map[string]interface{}{
+			"session_id": sessionID,
+		})
+	}
+}
+
+func (s *tokenService) checkRevoked(claims *utils.JWTClaims) error {
+	revoked, err := s.revokedTokenRepo.IsRevoked(claims.ID, claims.SessionID, claims.UserID, claims.IssuedTime())
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return utils.ErrTokenRevoked
+	}
+	return nil
+}
+
+// RevokeSession ends a refresh token family and every access token issued from it
+func (s *tokenService) RevokeSession(sessionID uuid.UUID, userID uuid.UUID, reason string) error {
+	if err := s.sessionRepo.Revoke(sessionID, reason); err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(s.maxTokenLifetime())
+	if err := s.revokedTokenRepo.Revoke(repository.SessionRevocationKey(sessionID.String()), userID, expiresAt); err != nil {
+		return err
+	}
+
+	utils.GlobalLogger.LogAuth("session_revoked", userID.String(), true, map[string]interface{}{
+		"session_id": sessionID,
+		"reason":     reason,
+	})
+	return nil
+}
+
+// RevokeOtherSessions revokes every active session of the user except keepSessionID
+// and returns how many sessions were revoked
+func (s *tokenService) RevokeOtherSessions(userID uuid.UUID, keepSessionID string, reason string) (int, error) {
+	sessions, err := s.sessionRepo.FindActiveByUserID(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	revoked := 0
+	for _, session := range sessions {
+		if session.ID.String() == keepSessionID {
+			continue
+		}
+		if err := s.RevokeSession(session.ID, userID, reason); err != nil {
+			return revoked, err
+		}
+		revoked++
+	}
+	return revoked, nil
+}
+
+// RevokeUserTokens revokes every access and refresh token issued to the user so far.
+// The entry is kept until the longest-lived token issued before now has expired.
+func (s *tokenService) RevokeUserTokens(userID uuid.UUID) error {
+	now := time.Now()
+	expiresAt := now.Add(s.maxTokenLifetime())
+
+	if err := s.revokedTokenRepo.RevokeAllForUser(userID, now, expiresAt); err != nil {
+		utils.GlobalLogger.Error("Failed to revoke user tokens", err, map[string]interface{}{
+			"user_id": userID,
+		})
+		return err
+	}
+
+	if err := s.sessionRepo.RevokeAllForUser(userID, "revoked"); err != nil {
+		utils.GlobalLogger.Error("Failed to revoke user sessions", err, map[string]interface{}{
+			"user_id": userID,
+		})
+		return err
+	}
+
+	utils.GlobalLogger.LogAuth("tokens_revoked", userID.String(), true)
+	return nil
+}
+
+// RevokeToken revokes a single access token until it expires
+func (s *tokenService) RevokeToken(claims *utils.JWTClaims) error {
+	expiresAt := time.Now().Add(s.maxTokenLifetime())
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+	return s.revokedTokenRepo.Revoke(claims.ID, claims.UserID, expiresAt)
+}
+
+// StartCleanup purges expired revocation entries and sessions in the background
+// and forgets throttling state of idle sessions and API keys
+func (s *tokenService) StartCleanup(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			now := time.Now()
+
+			deleted, err := s.revokedTokenRepo.DeleteExpired(now)
+			if err != nil {
+				utils.GlobalLogger.Error("Failed to purge expired revoked tokens", err)
+			} else if deleted > 0 {
+				utils.GlobalLogger.Info("Purged expired revoked tokens", map[string]interface{}{
+					"deleted": deleted,
+				})
+			}
+
+			s.touchMu.Lock()
+			for id, last := range s.lastTouch {
+				if now.Sub(last) > sessionTouchInterval {
+					delete(s.lastTouch, id)
+				}
+			}
+			s.touchMu.Unlock()
+
+			deleted, err = s.sessionRepo.DeleteExpired(now)
+			if err != nil {
+				utils.GlobalLogger.Error("Failed to purge expired sessions", err)
+			} else if deleted > 0 {
+				utils.GlobalLogger.Info("Purged expired sessions", map[string]interface{}{
+					"deleted": deleted,
+				})
+			}
+		}
+	}()
+}
+
+// JWKS godoc
+// @Summary      JSON Web Key Set
+// @Description  Public keys for verifying access tokens, selected by the token's kid header. Empty when tokens are signed with a shared secret.
+// @Tags         Authentication
+// @Produce      json
+// @Success      200 {object} map[string]interface{} "JWK Set"
+// @Router       /.well-known/jwks.json [get]
+func (s *tokenService) JWKS(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	if s.keys == nil {
+		return c.JSON(fiber.Map{"keys": []interface{}{}})
+	}
+	return c.JSON(s.keys.JWKS())
+}
+
+// signAccess signs access and scoped tokens with the asymmetric key when configured
+func (s *tokenService) signAccess(claims *utils.JWTClaims, expiresIn time.Duration) (string, error) {
+	if s.keys != nil {
+		return s.keys.Sign(claims, expiresIn)
+	}
+	return utils.SignToken(claims, s.cfg.JWTSecret, expiresIn)
+}
+
+func scopeAllowed(scope string, allowedScopes []string) bool {
+	if scope == "" {
+		return true
+	}
+	for _, allowed := range allowedScopes {
+		if scope == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *tokenService) maxTokenLifetime() time.Duration {
+	lifetime := s.cfg.JWTExpiresIn
+	for _, ttl := range []time.Duration{s.cfg.JWTRefreshExpiresIn, s.cfg.ImpersonationTTL} {
+		if ttl > lifetime {
+			lifetime = ttl
+		}
+	}
+	return lifetime
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"student-achievement-system/middleware"
 	"student-achievement-system/models"
 	"student-achievement-system/repository"
 	"student-achievement-system/utils"
@@ -19,6 +20,8 @@ type UserService interface {
 	ListDeletedUsers(c *fiber.Ctx) error
 	RestoreUser(c *fiber.Ctx) error
 	HardDeleteUser(c *fiber.Ctx) error
+	UnlockUser(c *fiber.Ctx) error
+	ImpersonateUser(c *fiber.Ctx) error
 	ListRoles(c *fiber.Ctx) error
 }
 
@@ -40,6 +43,8 @@ type UpdateUserRequest struct {
 	FullName string `json:"full_name,omitempty"`
 	Email    string `json:"email,omitempty"`
 	IsActive *bool  `json:"is_active,omitempty"`
+	// AuthProvider switches between "local" password login and "oidc" single sign-on
+	AuthProvider *string `json:"auth_provider,omitempty"`
 }
 
 type AssignRoleRequest struct {
@@ -47,10 +52,14 @@ type AssignRoleRequest struct {
 }
 
 type userService struct {
-	userRepo     repository.UserRepository
-	studentRepo  repository.StudentRepository
-	lecturerRepo repository.LecturerRepository
-	roleRepo     repository.RoleRepository
+	userRepo         repository.UserRepository
+	studentRepo      repository.StudentRepository
+	lecturerRepo     repository.LecturerRepository
+	roleRepo         repository.RoleRepository
+	loginAttemptRepo repository.LoginAttemptRepository
+	tokenService     TokenService
+	permissions      *PermissionCache
+	auditRepo        repository.AuditRepository
 }
 
 func NewUserService(
@@ -58,12 +67,20 @@ func NewUserService(
 	studentRepo repository.StudentRepository,
 	lecturerRepo repository.LecturerRepository,
 	roleRepo repository.RoleRepository,
+	loginAttemptRepo repository.LoginAttemptRepository,
+	tokenService TokenService,
+	permissions *PermissionCache,
+	auditRepo repository.AuditRepository,
 ) UserService {
 	return &userService{
-		userRepo:     userRepo,
-		studentRepo:  studentRepo,
-		lecturerRepo: lecturerRepo,
-		roleRepo:     roleRepo,
+		userRepo:         userRepo,
+		studentRepo:      studentRepo,
+		lecturerRepo:     lecturerRepo,
+		roleRepo:         roleRepo,
+		loginAttemptRepo: loginAttemptRepo,
+		tokenService:     tokenService,
+		permissions:      permissions,
+		auditRepo:        auditRepo,
 	}
 }
 
@@ -230,12 +247,14 @@ func (s *userService) CreateUser(c *fiber.Ctx) error {
 	// Reload user with role
 	user, _ = s.userRepo.FindByID(user.ID)
 
+	RecordAudit(c, s.auditRepo, "user.create", "user", user.ID.String(), auditChanges(nil, userAuditState(user)))
+
 	return utils.SuccessResponse(c, "User created successfully", user)
 }
 
 // UpdateUser godoc
 // @Summary      Update user
-// @Description  Update user information by ID
+// @Description  Update user information by ID. auth_provider switches the account between local password login and single sign-on
 // @Tags         User Management
 // @Accept       json
 // @Produce      json
@@ -260,11 +279,18 @@ func (s *userService) UpdateUser(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
 	}
 
+	if req.AuthProvider != nil && *req.AuthProvider != models.AuthProviderLocal && *req.AuthProvider != models.AuthProviderOIDC {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "auth_provider must be 'local' or 'oidc'")
+	}
+
 	user, err := s.userRepo.FindByID(id)
-	if err != nil {
+	if err != nil || user.ID == uuid.Nil {
 		return utils.ErrorResponse(c, fiber.StatusNotFound, "User not found")
 	}
 
+	before := userAuditState(user)
+	wasActive := user.IsActive
+
 	// Update allowed fields
 	if req.FullName != "" {
 		user.FullName = req.FullName
@@ -279,8 +305,29 @@ func (s *userService) UpdateUser(c *fiber.Ctx) error {
 	if err := s.userRepo.Update(user); err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update user")
 	}
+	s.permissions.InvalidateUser(user.ID)
+
+	if req.AuthProvider != nil && *req.AuthProvider != user.AuthProvider {
+		// Switching back to local login unlinks the external identity
+		externalID := user.ExternalID
+		if *req.AuthProvider == models.AuthProviderLocal {
+			externalID = nil
+		}
+		if err := s.userRepo.SetAuthProvider(user.ID, *req.AuthProvider, externalID); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update login method")
+		}
+	}
+
+	// Deactivated accounts must not keep using previously issued tokens
+	if wasActive && !user.IsActive {
+		if err := s.tokenService.RevokeUserTokens(user.ID); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "User deactivated but failed to revoke tokens")
+		}
+	}
 
 	user, _ = s.userRepo.FindByID(user.ID)
+	RecordAudit(c, s.auditRepo, "user.update", "user", user.ID.String(), auditChanges(before, userAuditState(user)))
+
 	return utils.SuccessResponse(c, "User updated successfully", user)
 }
 
@@ -305,9 +352,17 @@ func (s *userService) DeleteUser(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid user ID")
 	}
 
+	user, err := s.userRepo.FindByID(id)
+	if err != nil || user.ID == uuid.Nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "User not found")
+	}
+
 	if err := s.userRepo.Delete(id); err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete user")
 	}
+	s.permissions.InvalidateUser(id)
+
+	RecordAudit(c, s.auditRepo, "user.delete", "user", id.String(), auditChanges(userAuditState(user), nil))
 
 	return utils.SuccessResponse(c, "User deleted successfully", nil)
 }
@@ -344,17 +399,28 @@ func (s *userService) AssignRole(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid role ID")
 	}
 
+	role, err := s.roleRepo.FindByID(roleID)
+	if err != nil || role.ID == uuid.Nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Role not found")
+	}
+
 	user, err := s.userRepo.FindByID(id)
-	if err != nil {
+	if err != nil || user.ID == uuid.Nil {
 		return utils.ErrorResponse(c, fiber.StatusNotFound, "User not found")
 	}
 
+	before := userAuditState(user)
 	user.RoleID = roleID
 	if err := s.userRepo.Update(user); err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to assign role")
 	}
 
+	// The new role's permissions apply from the user's next request
+	s.permissions.InvalidateUser(user.ID)
+
 	user, _ = s.userRepo.FindByID(user.ID)
+	RecordAudit(c, s.auditRepo, "user.assign_role", "user", user.ID.String(), auditChanges(before, userAuditState(user)))
+
 	return utils.SuccessResponse(c, "Role assigned successfully", user)
 }
 
@@ -406,11 +472,19 @@ func (s *userService) RestoreUser(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid user ID")
 	}
 
+	deleted, err := s.userRepo.FindByIDWithDeleted(id)
+	if err != nil || deleted.ID == uuid.Nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "User not found")
+	}
+
 	if err := s.userRepo.Restore(id); err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to restore user")
 	}
+	s.permissions.InvalidateUser(id)
 
 	user, _ := s.userRepo.FindByID(id)
+	RecordAudit(c, s.auditRepo, "user.restore", "user", id.String(), auditChanges(nil, userAuditState(user)))
+
 	return utils.SuccessResponse(c, "User restored successfully", user)
 }
 
@@ -435,6 +509,11 @@ func (s *userService) HardDeleteUser(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid user ID")
 	}
 
+	user, err := s.userRepo.FindByIDWithDeleted(id)
+	if err != nil || user.ID == uuid.Nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "User not found")
+	}
+
 	// Delete related student data first
 	if err := s.studentRepo.DeleteByUserID(id); err != nil {
 		// Ignore error if student not found
@@ -449,10 +528,55 @@ func (s *userService) HardDeleteUser(c *fiber.Ctx) error {
 	if err := s.userRepo.HardDelete(id); err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete user permanently")
 	}
+	s.permissions.InvalidateUser(id)
+
+	RecordAudit(c, s.auditRepo, "user.hard_delete", "user", id.String(), auditChanges(userAuditState(user), nil))
 
 	return utils.SuccessResponse(c, "User permanently deleted", nil)
 }
 
+// UnlockUser godoc
+// @Summary      Unlock user
+// @Description  Clear failed login attempts and any lockout or backoff of a user
+// @Tags         User Management
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path     string  true  "User ID (UUID)"
+// @Success      200 {object} map[string]interface{} "User unlocked successfully"
+// @Failure      400 {object} map[string]interface{} "Invalid user ID"
+// @Failure      401 {object} map[string]interface{} "Unauthorized"
+// @Failure      403 {object} map[string]interface{} "Forbidden"
+// @Failure      404 {object} map[string]interface{} "User not found"
+// @Failure      500 {object} map[string]interface{} "Internal server error"
+// @Router       /users/{id}/unlock [post]
+func (s *userService) UnlockUser(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	user, err := s.userRepo.FindByID(id)
+	if err != nil || user.ID == uuid.Nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "User not found")
+	}
+
+	if err := s.loginAttemptRepo.Reset(repository.UserLoginKey(user.ID)); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to unlock user")
+	}
+
+	actorID := ""
+	if claims := middleware.GetUserFromContext(c); claims != nil {
+		actorID = claims.UserID.String()
+	}
+	utils.GlobalLogger.LogAuth("account_unlocked", user.ID.String(), true, map[string]interface{}{
+		"unlocked_by": actorID,
+	})
+	RecordAudit(c, s.auditRepo, "user.unlock", "user", user.ID.String(), nil)
+
+	return utils.SuccessResponse(c, "User unlocked successfully", nil)
+}
+
 // ListRoles godoc
 // @Summary      List all roles
 // @Description  Get list of all available roles with their IDs
@@ -472,3 +596,16 @@ func (s *userService) ListRoles(c *fiber.Ctx) error {
 
 	return utils.SuccessResponse(c, "Roles retrieved successfully", roles)
 }
+
+// userAuditState is the part of a user recorded in the audit trail; credentials are left out
+func userAuditState(user *models.User) fiber.Map {
+	return fiber.Map{
+		"username":      user.Username,
+		"email":         user.Email,
+		"full_name":     user.FullName,
+		"role_id":       user.RoleID,
+		"role":          user.Role.Name,
+		"is_active":     user.IsActive,
+		"auth_provider": user.AuthProvider,
+	}
+}
@@ -0,0 +1,75 @@
+// Package sla decides when a submitted achievement has waited too long for verification.
+//
+// A submission is overdue once it has waited longer than the verification SLA, counted
+// from when it was submitted; whoever decides it is then reminded, once. Past a second
+// threshold it is escalated to the admins, also once. The rules take the current time
+// as an argument and the monitor applying them reads it from a Clock, so both can be
+// tested at any point in time.
+package sla
+
+import "time"
+
+// Clock tells the current time
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SystemClock reads the time of the operating system
+var SystemClock Clock = systemClock{}
+
+// Policy holds the thresholds of the verification SLA
+type Policy struct {
+	RemindAfter   time.Duration // the SLA; 0 disables reminders and escalations
+	EscalateAfter time.Duration // 0 disables escalations
+}
+
+// Enabled reports whether submissions are tracked at all
+func (p Policy) Enabled() bool {
+	return p.RemindAfter > 0
+}
+
+// Submission is a submitted achievement as far as the SLA is concerned
+type Submission struct {
+	SubmittedAt time.Time
+	RemindedAt  *time.Time // when its approvers were reminded, nil if not yet
+	EscalatedAt *time.Time // when it was escalated, nil if not yet
+}
+
+// Due is what a submission is due for
+type Due struct {
+	Remind   bool
+	Escalate bool
+}
+
+// Due works out what s is due for at now. A submission that skipped past both
+// thresholds, e.g. while the monitor was down, is due for both.
+func (p Policy) Due(s Submission, now time.Time) Due {
+	if !p.Enabled() {
+		return Due{}
+	}
+	waited := now.Sub(s.SubmittedAt)
+	return Due{
+		Remind:   s.RemindedAt == nil && waited >= p.RemindAfter,
+		Escalate: s.EscalatedAt == nil && p.EscalateAfter > 0 && waited >= p.EscalateAfter,
+	}
+}
+
+// OverdueBefore returns the submission time before which submissions are overdue at now
+func (p Policy) OverdueBefore(now time.Time) time.Time {
+	return now.Add(-p.RemindAfter)
+}
+
+// EscalatedBefore returns the submission time before which submissions are escalated at
+// now, the zero time if escalations are disabled
+func (p Policy) EscalatedBefore(now time.Time) time.Time {
+	if p.EscalateAfter <= 0 {
+		return time.Time{}
+	}
+	return now.Add(-p.EscalateAfter)
+}
@@ -0,0 +1,61 @@
+package sla
+
+import (
+	"testing"
+	"time"
+)
+
+// fixedClock is a Clock stopped at one point in time
+type fixedClock struct {
+	now time.Time
+}
+
+func (c fixedClock) Now() time.Time {
+	return c.now
+}
+
+func TestDue(t *testing.T) {
+	clock := fixedClock{now: time.Date(2025, 6, 30, 9, 0, 0, 0, time.UTC)}
+	policy := Policy{RemindAfter: 72 * time.Hour, EscalateAfter: 168 * time.Hour}
+	ago := func(d time.Duration) time.Time { return clock.Now().Add(-d) }
+	reminded := ago(24 * time.Hour)
+
+	tests := []struct {
+		name       string
+		policy     Policy
+		submission Submission
+		want       Due
+	}{
+		{"within the SLA", policy, Submission{SubmittedAt: ago(71 * time.Hour)}, Due{}},
+		{"past the SLA", policy, Submission{SubmittedAt: ago(72 * time.Hour)}, Due{Remind: true}},
+		{"already reminded", policy, Submission{SubmittedAt: ago(100 * time.Hour), RemindedAt: &reminded}, Due{}},
+		{"past the escalation threshold", policy, Submission{SubmittedAt: ago(170 * time.Hour), RemindedAt: &reminded}, Due{Escalate: true}},
+		{"skipped past both thresholds", policy, Submission{SubmittedAt: ago(200 * time.Hour)}, Due{Remind: true, Escalate: true}},
+		{"already escalated", policy, Submission{SubmittedAt: ago(200 * time.Hour), RemindedAt: &reminded, EscalatedAt: &reminded}, Due{}},
+		{"escalations disabled", Policy{RemindAfter: 72 * time.Hour}, Submission{SubmittedAt: ago(500 * time.Hour)}, Due{Remind: true}},
+		{"SLA disabled", Policy{}, Submission{SubmittedAt: ago(500 * time.Hour)}, Due{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Due(tt.submission, clock.Now()); got != tt.want {
+				t.Errorf("Due() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThresholds(t *testing.T) {
+	now := time.Date(2025, 6, 30, 9, 0, 0, 0, time.UTC)
+	policy := Policy{RemindAfter: 72 * time.Hour, EscalateAfter: 168 * time.Hour}
+
+	if got, want := policy.OverdueBefore(now), now.Add(-72*time.Hour); !got.Equal(want) {
+		t.Errorf("OverdueBefore() = %v, want %v", got, want)
+	}
+	if got, want := policy.EscalatedBefore(now), now.Add(-168*time.Hour); !got.Equal(want) {
+		t.Errorf("EscalatedBefore() = %v, want %v", got, want)
+	}
+	if got := (Policy{RemindAfter: time.Hour}).EscalatedBefore(now); !got.IsZero() {
+		t.Errorf("EscalatedBefore() without escalations = %v, want the zero time", got)
+	}
+}
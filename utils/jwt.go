@@ -1,56 +1,159 @@
 package utils
 
 import (
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
-// JWTClaims represents JWT claims
+// ErrTokenRevoked is returned when a token has been revoked server-side
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// ErrTokenScope is returned when a restricted token is used outside its scope
+var ErrTokenScope = errors.New("token is not valid for this endpoint")
+
+// ErrAccountUnavailable is returned when the user of a valid token is inactive or no longer exists
+var ErrAccountUnavailable = errors.New("account is inactive or no longer exists")
+
+// ScopeMFAPending marks a token issued after the password step of a login
+// that still has to complete two-factor verification or enrollment
+const ScopeMFAPending = "mfa_pending"
+
+// ScopedTokenType is the typ header of scoped tokens. Together with an audience naming
+// the scope it keeps consumers of the published keys from taking a scoped token for a
+// session, even when they ignore the scope claim.
+const ScopedTokenType = "scoped+jwt"
+
+// ErrTokenType is returned when the typ header, audience and scope of a token disagree
+var ErrTokenType = errors.New("token type does not match its scope")
+
+// JWTClaims represents JWT claims.
+// Tokens carry only the identity and the permission version of the role at issue time;
+// RoleID, RoleName and Permissions are resolved from the current role on every request.
 type JWTClaims struct {
-	UserID      uuid.UUID `json:"user_id"`
-	Username    string    `json:"username"`
-	Email       string    `json:"email"`
-	RoleID      uuid.UUID `json:"role_id"`
-	RoleName    string    `json:"role_name"`
-	Permissions []string  `json:"permissions"`
+	UserID            uuid.UUID `json:"user_id"`
+	Username          string    `json:"username"`
+	Email             string    `json:"email"`
+	RoleID            uuid.UUID `json:"-"`
+	RoleName          string    `json:"-"`
+	Permissions       []string  `json:"-"`
+	PermissionVersion int64     `json:"pv"`
+	SessionID         string    `json:"sid,omitempty"`
+	Scope             string    `json:"scope,omitempty"`
+	// IssuedAtMillis is the issue time in milliseconds; iat only has whole seconds, too
+	// coarse to tell a token issued right after a user-wide revocation from one before it
+	IssuedAtMillis int64 `json:"iat_ms,omitempty"`
+	// APIKeyID is set when the request was authenticated with an API key instead of a token
+	APIKeyID string `json:"-"`
+	// Actor is set on impersonation tokens and identifies the admin acting as the user
+	Actor *Actor `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Actor is the "act" claim (RFC 8693) of an impersonation token
+type Actor struct {
+	UserID   uuid.UUID `json:"sub"`
+	Username string    `json:"username"`
+}
+
+// IssuedTime returns when the token was issued, to the millisecond when the token
+// carries iat_ms
+func (c *JWTClaims) IssuedTime() time.Time {
+	if c.IssuedAtMillis > 0 {
+		return time.UnixMilli(c.IssuedAtMillis)
+	}
+	if c.IssuedAt != nil {
+		return c.IssuedAt.Time
+	}
+	return time.Time{}
+}
+
+// Impersonating reports whether the token was issued to an admin acting as the user
+func (c *JWTClaims) Impersonating() bool {
+	return c.Actor != nil
+}
+
 // GenerateToken generates a new JWT token
 func GenerateToken(userID uuid.UUID, username, email string, roleID uuid.UUID, roleName string, permissions []string, secret string, expiresIn time.Duration) (string, error) {
-	claims := JWTClaims{
+	claims := &JWTClaims{
 		UserID:      userID,
 		Username:    username,
 		Email:       email,
 		RoleID:      roleID,
 		RoleName:    roleName,
 		Permissions: permissions,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return SignToken(claims, secret, expiresIn)
+}
+
+// SignToken fills in the registered claims (jti, iat, nbf, exp) and signs the token with HS256
+func SignToken(claims *JWTClaims, secret string, expiresIn time.Duration) (string, error) {
+	setRegisteredClaims(claims, expiresIn)
+
+	token := newToken(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(secret))
 }
 
-// ValidateToken validates and parses a JWT token
+// ValidateToken validates and parses an HS256 JWT token
 func ValidateToken(tokenString, secret string) (*JWTClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
 		return []byte(secret), nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
 	if err != nil {
 		return nil, err
 	}
 
 	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
+		if err := checkTokenType(token, claims); err != nil {
+			return nil, err
+		}
 		return claims, nil
 	}
 
 	return nil, jwt.ErrSignatureInvalid
 }
+
+// newToken creates an unsigned token, marking scoped ones with their own type
+func newToken(method jwt.SigningMethod, claims *JWTClaims) *jwt.Token {
+	token := jwt.NewWithClaims(method, claims)
+	if claims.Scope != "" {
+		token.Header["typ"] = ScopedTokenType
+	}
+	return token
+}
+
+// checkTokenType accepts a scoped token only with the scoped type and its scope as
+// audience, and any other token only without them
+func checkTokenType(token *jwt.Token, claims *JWTClaims) error {
+	typ, _ := token.Header["typ"].(string)
+	if claims.Scope == "" {
+		if typ == ScopedTokenType || len(claims.Audience) > 0 {
+			return ErrTokenType
+		}
+		return nil
+	}
+	if typ != ScopedTokenType || len(claims.Audience) != 1 || claims.Audience[0] != claims.Scope {
+		return ErrTokenType
+	}
+	return nil
+}
+
+// setRegisteredClaims sets a fresh jti (unless already set), iat, nbf and exp, and the
+// audience of scoped tokens
+func setRegisteredClaims(claims *JWTClaims, expiresIn time.Duration) {
+	now := time.Now()
+	if claims.ID == "" {
+		claims.ID = uuid.New().String()
+	}
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(expiresIn))
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.IssuedAtMillis = now.UnixMilli()
+	claims.NotBefore = jwt.NewNumericDate(now)
+	if claims.Scope != "" {
+		claims.Audience = jwt.ClaimStrings{claims.Scope}
+	}
+}
@@ -0,0 +1,267 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// VerificationKey is a public key accepted for token verification
+type VerificationKey struct {
+	KID       string
+	Method    jwt.SigningMethod
+	PublicKey crypto.PublicKey
+}
+
+// KeySet holds the asymmetric key used to sign access tokens and every key
+// that is still accepted for verification. During rotation the previous
+// keys stay in the set until all tokens signed with them have expired.
+type KeySet struct {
+	signingKID    string
+	signingMethod jwt.SigningMethod
+	signingKey    crypto.PrivateKey
+	keys          map[string]*VerificationKey
+	order         []string
+}
+
+// LoadKeySet loads the PEM-encoded private signing key and any additional
+// public or private keys that remain valid for verification.
+// RSA keys sign with RS256 and Ed25519 keys with EdDSA.
+func LoadKeySet(signingKeyFile string, verificationKeyFiles []string) (*KeySet, error) {
+	privateKey, err := readPrivateKey(signingKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("signing key %s: %w", signingKeyFile, err)
+	}
+
+	signingKey, err := newVerificationKey(publicKeyOf(privateKey))
+	if err != nil {
+		return nil, fmt.Errorf("signing key %s: %w", signingKeyFile, err)
+	}
+
+	ks := &KeySet{
+		signingKID:    signingKey.KID,
+		signingMethod: signingKey.Method,
+		signingKey:    privateKey,
+		keys:          map[string]*VerificationKey{},
+	}
+	ks.add(signingKey)
+
+	for _, file := range verificationKeyFiles {
+		publicKey, err := readPublicKey(file)
+		if err != nil {
+			return nil, fmt.Errorf("verification key %s: %w", file, err)
+		}
+		key, err := newVerificationKey(publicKey)
+		if err != nil {
+			return nil, fmt.Errorf("verification key %s: %w", file, err)
+		}
+		ks.add(key)
+	}
+
+	return ks, nil
+}
+
+// Sign fills in the registered claims like SignToken and signs with the current key
+func (ks *KeySet) Sign(claims *JWTClaims, expiresIn time.Duration) (string, error) {
+	setRegisteredClaims(claims, expiresIn)
+
+	token := newToken(ks.signingMethod, claims)
+	token.Header["kid"] = ks.signingKID
+	return token.SignedString(ks.signingKey)
+}
+
+// Validate parses a token signed by any key in the set, selected by its kid header
+func (ks *KeySet) Validate(tokenString string) (*JWTClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := ks.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		// The algorithm is bound to the key, never taken from the token
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, jwt.ErrTokenSignatureInvalid
+		}
+		return key.PublicKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
+		if err := checkTokenType(token, claims); err != nil {
+			return nil, err
+		}
+		return claims, nil
+	}
+
+	return nil, jwt.ErrSignatureInvalid
+}
+
+// JWKS returns the verification keys as a JSON Web Key Set (RFC 7517)
+func (ks *KeySet) JWKS() map[string]interface{} {
+	keys := make([]map[string]string, 0, len(ks.order))
+	for _, kid := range ks.order {
+		jwk := publicJWK(ks.keys[kid].PublicKey)
+		jwk["kid"] = kid
+		jwk["alg"] = ks.keys[kid].Method.Alg()
+		jwk["use"] = "sig"
+		keys = append(keys, jwk)
+	}
+	return map[string]interface{}{"keys": keys}
+}
+
+func (ks *KeySet) add(key *VerificationKey) {
+	if _, exists := ks.keys[key.KID]; exists {
+		return
+	}
+	ks.keys[key.KID] = key
+	ks.order = append(ks.order, key.KID)
+}
+
+// newVerificationKey picks the signing method for the key type and derives its kid
+func newVerificationKey(publicKey crypto.PublicKey) (*VerificationKey, error) {
+	var method jwt.SigningMethod
+	switch k := publicKey.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must be at least 2048 bits")
+		}
+		method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", publicKey)
+	}
+
+	return &VerificationKey{
+		KID:       jwkThumbprint(publicKey),
+		Method:    method,
+		PublicKey: publicKey,
+	}, nil
+}
+
+// jwkThumbprint computes the RFC 7638 thumbprint used as the key ID
+func jwkThumbprint(publicKey crypto.PublicKey) string {
+	jwk := publicJWK(publicKey)
+
+	// Required members in lexicographic order
+	var members []string
+	switch jwk["kty"] {
+	case "RSA":
+		members = []string{"e", "kty", "n"}
+	case "OKP":
+		members = []string{"crv", "kty", "x"}
+	}
+
+	canonical := "{"
+	for i, member := range members {
+		if i > 0 {
+			canonical += ","
+		}
+		name, _ := json.Marshal(member)
+		value, _ := json.Marshal(jwk[member])
+		canonical += string(name) + ":" + string(value)
+	}
+	canonical += "}"
+
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// publicJWK returns the public members of a JWK for the key
+func publicJWK(publicKey crypto.PublicKey) map[string]string {
+	switch k := publicKey.(type) {
+	case *rsa.PublicKey:
+		return map[string]string{
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		}
+	case ed25519.PublicKey:
+		return map[string]string{
+			"kty": "OKP",
+			"crv": "Ed25519",
+			"x":   base64.RawURLEncoding.EncodeToString(k),
+		}
+	}
+	return map[string]string{}
+}
+
+func publicKeyOf(privateKey crypto.PrivateKey) crypto.PublicKey {
+	if signer, ok := privateKey.(crypto.Signer); ok {
+		return signer.Public()
+	}
+	return nil
+}
+
+func readPEM(file string) (*pem.Block, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	return block, nil
+}
+
+func readPrivateKey(file string) (crypto.PrivateKey, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		switch k := key.(type) {
+		case *rsa.PrivateKey:
+			return k, nil
+		case ed25519.PrivateKey:
+			return k, nil
+		}
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+}
+
+// readPublicKey accepts a public key or a private key, from which the public key is derived
+func readPublicKey(file string) (crypto.PublicKey, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+
+	privateKey, err := readPrivateKey(file)
+	if err != nil {
+		return nil, err
+	}
+	return publicKeyOf(privateKey), nil
+}
@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// writeKey stores a private key as PKCS#8 PEM and returns the file
+func writeKey(t *testing.T, name string, key crypto.PrivateKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func newEd25519Key(t *testing.T, name string) string {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writeKey(t, name, key)
+}
+
+func newRSAKey(t *testing.T, name string) string {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writeKey(t, name, key)
+}
+
+func TestKeySetRotation(t *testing.T) {
+	oldFile, newFile := newEd25519Key(t, "old.pem"), newRSAKey(t, "new.pem")
+
+	oldSet, err := LoadKeySet(oldFile, nil)
+	if err != nil {
+		t.Fatalf("LoadKeySet(old) error = %v", err)
+	}
+	// After rotation the old key is only kept for verification
+	rotated, err := LoadKeySet(newFile, []string{oldFile})
+	if err != nil {
+		t.Fatalf("LoadKeySet(rotated) error = %v", err)
+	}
+
+	oldToken, err := oldSet.Sign(testClaims(), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newToken, err := rotated.Sign(testClaims(), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := rotated.Validate(oldToken); err != nil {
+		t.Errorf("token signed before the rotation rejected: %v", err)
+	}
+	if _, err := rotated.Validate(newToken); err != nil {
+		t.Errorf("token signed after the rotation rejected: %v", err)
+	}
+	if _, err := oldSet.Validate(newToken); err == nil {
+		t.Error("token signed with an unknown key accepted")
+	}
+
+	if keys := rotated.JWKS()["keys"].([]map[string]string); len(keys) != 2 {
+		t.Errorf("JWKS() published %d keys, want both the signing and the previous key", len(keys))
+	}
+}
+
+func TestKeySetRejectsExpiredTokens(t *testing.T) {
+	set, err := LoadKeySet(newEd25519Key(t, "key.pem"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := set.Sign(testClaims(), -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := set.Validate(token); err == nil {
+		t.Error("expired token accepted")
+	}
+}
+
+// TestJWKSRoundTrip checks that a consumer of the published key set can verify tokens
+func TestJWKSRoundTrip(t *testing.T) {
+	for _, keyFile := range []string{newRSAKey(t, "rsa.pem"), newEd25519Key(t, "ed25519.pem")} {
+		set, err := LoadKeySet(keyFile, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, err := set.Sign(testClaims(), time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Read the key set back the way the single sign-on client reads a provider's
+		body, err := json.Marshal(set.JWKS())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var jwks struct {
+			Keys []map[string]interface{} `json:"keys"`
+		}
+		if err := json.Unmarshal(body, &jwks); err != nil {
+			t.Fatal(err)
+		}
+		published := map[string]crypto.PublicKey{}
+		for _, jwk := range jwks.Keys {
+			key, err := parseJWK(jwk)
+			if err != nil {
+				t.Fatalf("published JWK unreadable: %v", err)
+			}
+			kid, _ := jwk["kid"].(string)
+			if jwkThumbprint(key) != kid {
+				t.Errorf("kid %s is not the thumbprint of the published key", kid)
+			}
+			published[kid] = key
+		}
+
+		_, err = jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+			return published[token.Header["kid"].(string)], nil
+		})
+		if err != nil {
+			t.Errorf("token not verifiable with the published %s key: %v", filepath.Base(keyFile), err)
+		}
+	}
+}
+
+// TestKeySetScopedTokens checks that scoped tokens signed with the published key cannot
+// pass for session tokens with consumers of the key set
+func TestKeySetScopedTokens(t *testing.T) {
+	set, err := LoadKeySet(newEd25519Key(t, "key.pem"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scoped := testClaims()
+	scoped.Scope = ScopeMFAPending
+	token, err := set.Sign(scoped, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := set.Validate(token); err != nil {
+		t.Fatalf("scoped token rejected: %v", err)
+	}
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &JWTClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	audience, _ := parsed.Claims.GetAudience()
+	if parsed.Header["typ"] != ScopedTokenType || len(audience) != 1 || audience[0] != ScopeMFAPending {
+		t.Errorf("scoped token typ = %v, aud = %v, want %s and %s", parsed.Header["typ"], audience, ScopedTokenType, ScopeMFAPending)
+	}
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func testClaims() *JWTClaims {
+	return &JWTClaims{UserID: uuid.New(), Username: "student", Email: "student@example.com"}
+}
+
+func TestScopedTokenType(t *testing.T) {
+	const secret = "test-secret"
+
+	scoped := testClaims()
+	scoped.Scope = ScopeMFAPending
+	token, err := SignToken(scoped, secret, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := ValidateToken(token, secret)
+	if err != nil {
+		t.Fatalf("scoped token rejected: %v", err)
+	}
+	if claims.Scope != ScopeMFAPending || len(claims.Audience) != 1 || claims.Audience[0] != ScopeMFAPending {
+		t.Errorf("scoped token claims = %+v, want its scope as audience", claims)
+	}
+	parsed, _, _ := jwt.NewParser().ParseUnverified(token, &JWTClaims{})
+	if parsed.Header["typ"] != ScopedTokenType {
+		t.Errorf("scoped token typ = %v, want %s", parsed.Header["typ"], ScopedTokenType)
+	}
+
+	// A scope claim without the scoped type, or the scoped type without a scope, is refused
+	untyped := testClaims()
+	untyped.Scope = ScopeMFAPending
+	setRegisteredClaims(untyped, time.Minute)
+	signed, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, untyped).SignedString([]byte(secret))
+	if _, err := ValidateToken(signed, secret); err != ErrTokenType {
+		t.Errorf("scope claim without the scoped type: error = %v, want ErrTokenType", err)
+	}
+
+	unscoped := testClaims()
+	setRegisteredClaims(unscoped, time.Minute)
+	typed := jwt.NewWithClaims(jwt.SigningMethodHS256, unscoped)
+	typed.Header["typ"] = ScopedTokenType
+	signed, _ = typed.SignedString([]byte(secret))
+	if _, err := ValidateToken(signed, secret); err != ErrTokenType {
+		t.Errorf("scoped type without a scope: error = %v, want ErrTokenType", err)
+	}
+
+	// Tokens of a full session carry neither
+	session, err := SignToken(testClaims(), secret, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims, err := ValidateToken(session, secret); err != nil || len(claims.Audience) != 0 {
+		t.Errorf("session token: claims = %+v, error = %v", claims, err)
+	}
+}
@@ -89,6 +89,9 @@ func (l *Logger) Debug(message string, context ...map[string]interface{}) {
 	l.log(LogLevelDebug, message, ctx)
 }
 
+// RequestIDKey is the fiber.Ctx Locals key holding the ID of the current request
+const RequestIDKey = "request_id"
+
 // LogRequest logs HTTP request details
 func (l *Logger) LogRequest(c *fiber.Ctx, duration time.Duration) {
 	context := map[string]interface{}{
@@ -99,6 +102,9 @@ func (l *Logger) LogRequest(c *fiber.Ctx, duration time.Duration) {
 		"ip":         c.IP(),
 		"user_agent": c.Get("User-Agent"),
 	}
+	if requestID, ok := c.Locals(RequestIDKey).(string); ok {
+		context["request_id"] = requestID
+	}
 	
 	message := fmt.Sprintf("%s %s - %d", c.Method(), c.Path(), c.Response().StatusCode())
 	l.log(LogLevelInfo, message, context)
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 key of the RFC 6238 test vectors, "12345678901234567890"
+var rfc6238Secret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	// RFC 6238 appendix B, SHA-1, truncated from 8 to 6 digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		now := time.Unix(tt.unix, 0)
+		if got := totpCode([]byte("12345678901234567890"), tt.unix/totpPeriod); got != tt.want {
+			t.Errorf("totpCode(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+		step, ok := ValidateTOTP(rfc6238Secret, tt.want, now)
+		if !ok || step != tt.unix/totpPeriod {
+			t.Errorf("ValidateTOTP(%s at %d) = %d, %v, want step %d", tt.want, tt.unix, step, ok, tt.unix/totpPeriod)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	issued := time.Unix(1111111111, 0) // code 050471
+
+	tests := []struct {
+		name string
+		code string
+		now  time.Time
+		want bool
+	}{
+		{"same step", "050471", issued, true},
+		{"one step late", "050471", issued.Add(totpPeriod * time.Second), true},
+		{"one step early", "050471", issued.Add(-totpPeriod * time.Second), true},
+		{"two steps late", "050471", issued.Add(2 * totpPeriod * time.Second), false},
+		{"surrounding spaces", " 050471 ", issued, true},
+		{"wrong code", "050472", issued, false},
+		{"too short", "05047", issued, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := ValidateTOTP(rfc6238Secret, tt.code, tt.now); ok != tt.want {
+				t.Errorf("ValidateTOTP() = %v, want %v", ok, tt.want)
+			}
+		})
+	}
+
+	if _, ok := ValidateTOTP("not base32!", "050471", issued); ok {
+		t.Error("ValidateTOTP() accepted an invalid secret")
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret() error = %v", err)
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(key) != 20 {
+		t.Errorf("GenerateTOTPSecret() = %q, want 160 bits of base32", secret)
+	}
+}
@@ -0,0 +1,212 @@
+// Package workflow defines the lifecycle of an achievement as a state machine.
+//
+// Every status change is a transition declared in one table: the event that triggers
+// it, the status it leaves and enters, who may trigger it and the guards that must
+// hold. Handlers ask the machine for a transition instead of assigning statuses, so
+// e.g. a draft can never be verified nor a verified achievement deleted.
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"student-achievement-system/authz"
+	"student-achievement-system/models"
+)
+
+type Event string
+
+const (
+	EventSubmit   Event = "submit"
+	EventApprove  Event = "approve" // approval at a stage that is not the last one
+	EventVerify   Event = "verify"
+	EventReject   Event = "reject"
+	EventWithdraw Event = "withdraw" // recalls a submission back to draft
+	EventDelete   Event = "delete"
+
+	// EventEdit changes the content of an achievement; it is not a transition, the
+	// status stays the same
+	EventEdit Event = "edit"
+)
+
+// Request is an attempt by a subject to fire an event on an achievement
+type Request struct {
+	Subject  authz.Subject
+	Resource authz.Resource
+	Note     string // verification comments or rejection reason
+
+	// PendingStages is the number of approval stages still incomplete once this
+	// request's approval has been counted
+	PendingStages int
+}
+
+// Guard checks a condition a transition depends on, returning why it does not hold
+type Guard func(ref *models.AchievementReference, req Request) error
+
+// Transition moves an achievement from one status to another when Event fires
+type Transition struct {
+	Event  Event
+	From   models.AchievementStatus
+	To     models.AchievementStatus
+	By     []authz.Rule // any one of these allows the subject to fire the event
+	Guards []Guard
+
+	// Unopened allows the transition only while no verifier has opened the submission.
+	// Fire checks the achievement as loaded; callers saving the transition must check
+	// again that nobody opened it in the meantime.
+	Unopened bool
+}
+
+// ErrNotPermitted is returned when the transition exists but the subject may not trigger it
+var ErrNotPermitted = errors.New("not permitted to trigger this transition")
+
+// TransitionError explains why an event cannot fire in the achievement's current status
+type TransitionError struct {
+	Event   Event
+	From    models.AchievementStatus
+	Allowed []Event // events that can fire in From
+	Reason  string
+}
+
+func (e *TransitionError) Error() string {
+	return e.Reason
+}
+
+// RequireNote rejects requests without a note, e.g. a rejection without a reason
+func RequireNote(_ *models.AchievementReference, req Request) error {
+	if strings.TrimSpace(req.Note) == "" {
+		return errors.New("a reason is required")
+	}
+	return nil
+}
+
+// FinalStage holds once every approval stage is complete, so that only the decision
+// completing the last stage verifies an achievement
+func FinalStage(_ *models.AchievementReference, req Request) error {
+	if req.PendingStages > 0 {
+		return fmt.Errorf("%d approval stage(s) are still pending", req.PendingStages)
+	}
+	return nil
+}
+
+// StagesPending holds while approval stages remain after this approval
+func StagesPending(_ *models.AchievementReference, req Request) error {
+	if req.PendingStages == 0 {
+		return errors.New("this approval completes the final stage")
+	}
+	return nil
+}
+
+// unopened holds until a verifier opens the submission or decides on it
+func unopened(ref *models.AchievementReference, _ Request) error {
+	if ref.FirstViewedAt != nil {
+		return errors.New("a verifier has already opened it")
+	}
+	return nil
+}
+
+// transitions is the lifecycle of an achievement. Pairs of status and event that are
+// not listed are invalid.
+var transitions = []Transition{
+	{Event: EventSubmit, From: models.StatusDraft, To: models.StatusSubmitted, By: []authz.Rule{authz.Admin, authz.Owner}},
+	{Event: EventSubmit, From: models.StatusRejected, To: models.StatusSubmitted, By: []authz.Rule{authz.Admin, authz.Owner}},
+	{Event: EventApprove, From: models.StatusSubmitted, To: models.StatusSubmitted, By: []authz.Rule{authz.Admin, authz.StageApprover}, Guards: []Guard{StagesPending}},
+	{Event: EventVerify, From: models.StatusSubmitted, To: models.StatusVerified, By: []authz.Rule{authz.Admin, authz.StageApprover}, Guards: []Guard{FinalStage}},
+	{Event: EventReject, From: models.StatusSubmitted, To: models.StatusRejected, By: []authz.Rule{authz.Admin, authz.StageApprover}, Guards: []Guard{RequireNote}},
+	{Event: EventWithdraw, From: models.StatusSubmitted, To: models.StatusDraft, By: []authz.Rule{authz.Admin, authz.Owner}, Unopened: true},
+	{Event: EventDelete, From: models.StatusDraft, To: models.StatusDeleted, By: []authz.Rule{authz.Admin, authz.Owner}},
+	{Event: EventDelete, From: models.StatusRejected, To: models.StatusDeleted, By: []authz.Rule{authz.Admin, authz.Owner}},
+}
+
+// editable lists the statuses in which the content of an achievement may change. A
+// rejected achievement is revised and then resubmitted.
+var editable = map[models.AchievementStatus]bool{
+	models.StatusDraft:    true,
+	models.StatusRejected: true,
+}
+
+// CanEdit reports, as a TransitionError, why the content of ref may not change
+func CanEdit(ref *models.AchievementReference) error {
+	if editable[ref.Status] {
+		return nil
+	}
+	return &TransitionError{
+		Event:   EventEdit,
+		From:    ref.Status,
+		Allowed: Allowed(ref.Status),
+		Reason:  fmt.Sprintf("Cannot edit an achievement that is %s", ref.Status),
+	}
+}
+
+// Check reports, as a TransitionError, whether event can fire at all in the current
+// status of ref, before it is known who fires it and whether its guards hold
+func Check(ref *models.AchievementReference, event Event) error {
+	for _, t := range transitions {
+		if t.Event == event && t.From == ref.Status {
+			return nil
+		}
+	}
+	return &TransitionError{
+		Event:   event,
+		From:    ref.Status,
+		Allowed: Allowed(ref.Status),
+		Reason:  fmt.Sprintf("Cannot %s an achievement that is %s", event, ref.Status),
+	}
+}
+
+// Fire looks up the transition event triggers from the achievement's current status and
+// checks that the subject may trigger it and its guards hold. It does not change ref.
+func Fire(ref *models.AchievementReference, event Event, req Request) (*Transition, error) {
+	for i := range transitions {
+		t := &transitions[i]
+		if t.Event != event || t.From != ref.Status {
+			continue
+		}
+
+		if !permitted(t, req) {
+			return nil, ErrNotPermitted
+		}
+		guards := t.Guards
+		if t.Unopened {
+			guards = append([]Guard{unopened}, guards...)
+		}
+		for _, guard := range guards {
+			if err := guard(ref, req); err != nil {
+				return nil, &TransitionError{
+					Event:   event,
+					From:    ref.Status,
+					Allowed: Allowed(ref.Status),
+					Reason:  fmt.Sprintf("Cannot %s this achievement: %s", event, err),
+				}
+			}
+		}
+		return t, nil
+	}
+
+	return nil, &TransitionError{
+		Event:   event,
+		From:    ref.Status,
+		Allowed: Allowed(ref.Status),
+		Reason:  fmt.Sprintf("Cannot %s an achievement that is %s", event, ref.Status),
+	}
+}
+
+// Allowed lists the events that can fire in status, whoever triggers them
+func Allowed(status models.AchievementStatus) []Event {
+	events := make([]Event, 0)
+	for _, t := range transitions {
+		if t.From == status {
+			events = append(events, t.Event)
+		}
+	}
+	return events
+}
+
+func permitted(t *Transition, req Request) bool {
+	for _, rule := range t.By {
+		if rule(req.Subject, req.Resource) {
+			return true
+		}
+	}
+	return false
+}
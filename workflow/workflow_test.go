@@ -0,0 +1,207 @@
+package workflow
+
+import (
+	"errors"
+	"student-achievement-system/authz"
+	"student-achievement-system/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestFire(t *testing.T) {
+	ownerID := uuid.New()
+	advisorID := uuid.New()
+
+	admin := authz.Subject{UserID: uuid.New(), RoleName: authz.RoleAdmin}
+	owner := authz.Subject{UserID: uuid.New(), StudentID: ownerID}
+	advisor := authz.Subject{UserID: uuid.New(), LecturerID: advisorID}
+	otherLecturer := authz.Subject{UserID: uuid.New(), LecturerID: uuid.New()}
+
+	resource := authz.Resource{Kind: authz.KindAchievement, OwnerID: ownerID, AdvisorID: &advisorID}
+
+	tests := []struct {
+		name    string
+		status  models.AchievementStatus
+		event   Event
+		subject authz.Subject
+		note    string
+		want    models.AchievementStatus
+		wantErr error // nil, ErrNotPermitted or a *TransitionError
+	}{
+		{"owner submits draft", models.StatusDraft, EventSubmit, owner, "", models.StatusSubmitted, nil},
+		{"admin submits draft", models.StatusDraft, EventSubmit, admin, "", models.StatusSubmitted, nil},
+		{"advisor cannot submit draft", models.StatusDraft, EventSubmit, advisor, "", "", ErrNotPermitted},
+		{"submitted cannot be submitted again", models.StatusSubmitted, EventSubmit, owner, "", "", &TransitionError{}},
+		{"owner resubmits rejected", models.StatusRejected, EventSubmit, owner, "", models.StatusSubmitted, nil},
+		{"advisor cannot resubmit rejected", models.StatusRejected, EventSubmit, advisor, "", "", ErrNotPermitted},
+		{"verified cannot be resubmitted", models.StatusVerified, EventSubmit, owner, "", "", &TransitionError{}},
+
+		{"advisor verifies submitted", models.StatusSubmitted, EventVerify, advisor, "", models.StatusVerified, nil},
+		{"admin verifies submitted", models.StatusSubmitted, EventVerify, admin, "", models.StatusVerified, nil},
+		{"owner cannot verify own achievement", models.StatusSubmitted, EventVerify, owner, "", "", ErrNotPermitted},
+		{"other lecturer cannot verify", models.StatusSubmitted, EventVerify, otherLecturer, "", "", ErrNotPermitted},
+		{"draft cannot be verified", models.StatusDraft, EventVerify, admin, "", "", &TransitionError{}},
+		{"verified cannot be verified again", models.StatusVerified, EventVerify, advisor, "", "", &TransitionError{}},
+
+		{"advisor rejects with reason", models.StatusSubmitted, EventReject, advisor, "Certificate is unreadable", models.StatusRejected, nil},
+		{"rejection needs a reason", models.StatusSubmitted, EventReject, advisor, "  ", "", &TransitionError{}},
+		{"verified cannot be rejected", models.StatusVerified, EventReject, admin, "Wrong", "", &TransitionError{}},
+
+		{"owner withdraws submitted", models.StatusSubmitted, EventWithdraw, owner, "", models.StatusDraft, nil},
+		{"advisor cannot withdraw submitted", models.StatusSubmitted, EventWithdraw, advisor, "", "", ErrNotPermitted},
+		{"draft cannot be withdrawn", models.StatusDraft, EventWithdraw, owner, "", "", &TransitionError{}},
+		{"verified cannot be withdrawn", models.StatusVerified, EventWithdraw, owner, "", "", &TransitionError{}},
+
+		{"owner deletes draft", models.StatusDraft, EventDelete, owner, "", models.StatusDeleted, nil},
+		{"owner deletes rejected", models.StatusRejected, EventDelete, owner, "", models.StatusDeleted, nil},
+		{"advisor cannot delete draft", models.StatusDraft, EventDelete, advisor, "", "", ErrNotPermitted},
+		{"submitted cannot be deleted", models.StatusSubmitted, EventDelete, owner, "", "", &TransitionError{}},
+		{"verified cannot be deleted", models.StatusVerified, EventDelete, admin, "", "", &TransitionError{}},
+		{"deleted is final", models.StatusDeleted, EventDelete, admin, "", "", &TransitionError{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref := &models.AchievementReference{Status: tt.status}
+			got, err := Fire(ref, tt.event, Request{Subject: tt.subject, Resource: resource, Note: tt.note})
+
+			var transitionErr *TransitionError
+			switch {
+			case tt.wantErr == nil:
+				if err != nil {
+					t.Fatalf("Fire(%s from %s) returned error %v", tt.event, tt.status, err)
+				}
+				if got.To != tt.want {
+					t.Errorf("Fire(%s from %s) moves to %s, want %s", tt.event, tt.status, got.To, tt.want)
+				}
+			case errors.Is(tt.wantErr, ErrNotPermitted):
+				if !errors.Is(err, ErrNotPermitted) {
+					t.Errorf("Fire(%s from %s) error = %v, want ErrNotPermitted", tt.event, tt.status, err)
+				}
+			default:
+				if !errors.As(err, &transitionErr) {
+					t.Fatalf("Fire(%s from %s) error = %v, want a TransitionError", tt.event, tt.status, err)
+				}
+				if transitionErr.From != tt.status || transitionErr.Event != tt.event {
+					t.Errorf("TransitionError describes %s from %s", transitionErr.Event, transitionErr.From)
+				}
+			}
+			if ref.Status != tt.status {
+				t.Errorf("Fire changed the status to %s", ref.Status)
+			}
+		})
+	}
+}
+
+func TestFireApprovalStages(t *testing.T) {
+	ownerID := uuid.New()
+	advisorID := uuid.New()
+
+	advisor := authz.Subject{UserID: uuid.New(), LecturerID: advisorID}
+	office := authz.Subject{UserID: uuid.New(), RoleName: "Kemahasiswaan"}
+
+	advisorStage := authz.Resource{Kind: authz.KindAchievement, OwnerID: ownerID, AdvisorID: &advisorID, Stage: &authz.Stage{Advisor: true}}
+	officeStage := authz.Resource{Kind: authz.KindAchievement, OwnerID: ownerID, AdvisorID: &advisorID, Stage: &authz.Stage{Role: "Kemahasiswaan"}}
+
+	tests := []struct {
+		name     string
+		event    Event
+		subject  authz.Subject
+		resource authz.Resource
+		pending  int
+		wantErr  bool
+	}{
+		{"advisor approves first stage", EventApprove, advisor, advisorStage, 1, false},
+		{"advisor cannot verify while stages are pending", EventVerify, advisor, advisorStage, 1, true},
+		{"office verifies at the final stage", EventVerify, office, officeStage, 0, false},
+		{"final approval cannot leave the achievement submitted", EventApprove, office, officeStage, 0, true},
+		{"office cannot approve the advisor stage", EventApprove, office, advisorStage, 1, true},
+		{"advisor cannot verify the office stage", EventVerify, advisor, officeStage, 0, true},
+		{"office rejects at its stage", EventReject, office, officeStage, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref := &models.AchievementReference{Status: models.StatusSubmitted}
+			_, err := Fire(ref, tt.event, Request{Subject: tt.subject, Resource: tt.resource, Note: "Checked", PendingStages: tt.pending})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Fire(%s) error = %v, want error %v", tt.event, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFireWithdrawOpened(t *testing.T) {
+	ownerID := uuid.New()
+	owner := authz.Subject{UserID: uuid.New(), StudentID: ownerID}
+	resource := authz.Resource{Kind: authz.KindAchievement, OwnerID: ownerID}
+
+	openedAt := time.Now()
+	ref := &models.AchievementReference{Status: models.StatusSubmitted, FirstViewedAt: &openedAt}
+
+	var transitionErr *TransitionError
+	if _, err := Fire(ref, EventWithdraw, Request{Subject: owner, Resource: resource}); !errors.As(err, &transitionErr) {
+		t.Errorf("Fire(withdraw) after a verifier opened it = %v, want a TransitionError", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if err := Check(&models.AchievementReference{Status: models.StatusSubmitted}, EventVerify); err != nil {
+		t.Errorf("Check(verify from submitted) = %v, want nil", err)
+	}
+	var transitionErr *TransitionError
+	if err := Check(&models.AchievementReference{Status: models.StatusDraft}, EventVerify); !errors.As(err, &transitionErr) {
+		t.Errorf("Check(verify from draft) = %v, want a TransitionError", err)
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		status models.AchievementStatus
+		want   []Event
+	}{
+		{models.StatusDraft, []Event{EventSubmit, EventDelete}},
+		{models.StatusSubmitted, []Event{EventApprove, EventVerify, EventReject, EventWithdraw}},
+		{models.StatusRejected, []Event{EventSubmit, EventDelete}},
+		{models.StatusVerified, []Event{}},
+		{models.StatusDeleted, []Event{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			got := Allowed(tt.status)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Allowed(%s) = %v, want %v", tt.status, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Allowed(%s) = %v, want %v", tt.status, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCanEdit(t *testing.T) {
+	tests := []struct {
+		status models.AchievementStatus
+		want   bool
+	}{
+		{models.StatusDraft, true},
+		{models.StatusRejected, true},
+		{models.StatusSubmitted, false},
+		{models.StatusVerified, false},
+		{models.StatusDeleted, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			err := CanEdit(&models.AchievementReference{Status: tt.status})
+			if got := err == nil; got != tt.want {
+				t.Errorf("CanEdit(%s) = %v, want editable %v", tt.status, err, tt.want)
+			}
+		})
+	}
+}